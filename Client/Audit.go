@@ -0,0 +1,262 @@
+package Client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/Audit"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+	"os"
+	"sort"
+	"time"
+)
+
+// 每次校验的结果追加到审计日志（默认为 <license路径>.audit），日志以签发公钥加密的锚点开始，
+// 链式摘要串联并逐条更换密钥，审计链状态保存在 <审计日志>.chain 中。通过 ExportAudit 导出后
+// 由签发端 Server.VerifyAudit 使用签发私钥校验，用于确认授权失效的时间与原因。
+// 未配置签发公钥（TrustStore或PublicKey）时不记录审计日志。
+
+// CheckExport 导出审计日志时追加的记录
+const CheckExport = Audit.CheckExport
+
+// auditPath 审计日志路径，为空表示不记录
+func (c *Client) auditPath() string {
+	if c.AuditPath != "" {
+		return c.AuditPath
+	}
+	if licPath, ok := c.writablePath(); ok {
+		return licPath + ".audit"
+	}
+	return ""
+}
+
+// takeFailed 取出最近一次未通过的校验项
+func (c *Client) takeFailed() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var failed = c.lastFailed
+	c.lastFailed = ""
+	return failed
+}
+
+// audit 追加一条审计记录，写入失败只记录日志，不影响校验结果
+func (c *Client) audit(result bool, check string, reason error) {
+	var rec = &Entity.AuditRecord{Time: time.Now(), Result: result, Check: check}
+	if reason != nil {
+		rec.Error = reason.Error()
+	}
+	err := c.appendAudit(rec)
+	if err != nil {
+		c.logger().Warn(c.text(I18n.MsgAuditWriteFailed), Logger.Err(err))
+	}
+}
+
+func (c *Client) appendAudit(rec *Entity.AuditRecord) error {
+	var path = c.auditPath()
+	if path == "" {
+		return nil
+	}
+	c.mu.RLock()
+	rec.Serial = Logger.Serial(c.license)
+	c.mu.RUnlock()
+
+	c.auditMu.Lock()
+	defer c.auditMu.Unlock()
+	if c.auditHardware == "" {
+		c.auditHardware = hardwareDigest(Utils.GetMotherBoardID())
+	}
+	rec.HardwareDigest = c.auditHardware
+	var lines = make([][]byte, 0, 2)
+	if c.auditChain == nil {
+		chain, err := readAuditChain(path + ".chain")
+		if err != nil {
+			c.logger().Warn(c.text(I18n.MsgAuditChainReset), Logger.Err(err), Logger.F(Logger.FieldPath, path))
+		}
+		if chain == nil {
+			key, err := c.vendorKey()
+			if err != nil {
+				// 没有签发公钥时无法生成签发方可验证的锚点
+				return nil
+			}
+			anchor, newChain, err := newAuditChain(key)
+			if err != nil {
+				return err
+			}
+			line, err := json.Marshal(anchor)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+			chain = newChain
+		}
+		c.auditChain = chain
+	}
+	var chain = *c.auditChain
+	entry, err := chain.Append(rec)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, line)
+	if rec.Result && rec.Check == "" {
+		chain.Checked = rec.Time
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	_, err = file.Write(append(bytes.Join(lines, []byte{'\n'}), '\n'))
+	if err != nil {
+		return err
+	}
+	c.auditChain = &chain
+	return writeAuditChain(path+".chain", &chain)
+}
+
+// newAuditChain 使用签发公钥生成审计日志锚点
+func newAuditChain(key *Trust.Key) (*Entity.AuditEntry, *Audit.Chain, error) {
+	suite, err := Suite.Get(key.Suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, err := key.Public()
+	if err != nil {
+		return nil, nil, err
+	}
+	return Audit.NewAnchor(suite, key.KeyID, pub)
+}
+
+// readAuditChain 读取审计链状态，文件不存在时返回空
+func readAuditChain(path string) (*Audit.Chain, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var chain = new(Audit.Chain)
+	err = json.Unmarshal(data, chain)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// writeAuditChain 先写临时文件再改名，保存审计链状态
+func writeAuditChain(path string, chain *Audit.Chain) error {
+	data, err := json.Marshal(chain)
+	if err != nil {
+		return err
+	}
+	var tmp = path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkedBefore 审计链中是否记录过校验通过。审计日志以签发公钥锚定，
+// 删除审计日志与审计链状态后重新开始会在签发端校验时被发现。
+func (c *Client) checkedBefore() (bool, error) {
+	var path = c.auditPath()
+	if path == "" {
+		return false, nil
+	}
+	c.auditMu.Lock()
+	defer c.auditMu.Unlock()
+	if c.auditChain != nil {
+		return !c.auditChain.Checked.IsZero(), nil
+	}
+	chain, err := readAuditChain(path + ".chain")
+	if err != nil || chain == nil {
+		return false, err
+	}
+	return !chain.Checked.IsZero(), nil
+}
+
+// readAuditEntries 读取审计日志全部记录
+func readAuditEntries(path string) ([]*Entity.AuditEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries = make([]*Entity.AuditEntry, 0)
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry = new(Entity.AuditEntry)
+		err = json.Unmarshal(scanner.Bytes(), entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ExportAudit 导出审计日志包，发送给签发方使用 Server.VerifyAudit 校验。
+// 导出前追加一条导出记录，标记日志在导出时的末尾。
+func (c *Client) ExportAudit() ([]byte, error) {
+	var path = c.auditPath()
+	if path == "" {
+		return nil, c.err(I18n.CodeNoAuditPath)
+	}
+	_, err := c.vendorKey()
+	if err != nil {
+		return nil, err
+	}
+	err = c.appendAudit(&Entity.AuditRecord{Time: time.Now(), Result: c.Status(), Check: CheckExport})
+	if err != nil {
+		return nil, err
+	}
+	c.auditMu.Lock()
+	entries, err := readAuditEntries(path)
+	c.auditMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var bundle = &Entity.AuditBundle{
+		Version:       Audit.BundleVersion,
+		CreatedAt:     time.Now(),
+		MotherBoardID: Utils.GetMotherBoardID(),
+		Entries:       entries,
+	}
+	return json.MarshalIndent(bundle, "", "    ")
+}
+
+// hardwareDigest 主板ID与全部网卡MAC的摘要
+func hardwareDigest(motherBoardID string) string {
+	var macs = make([]string, 0)
+	for _, card := range Utils.GetAllNetCardInfo() {
+		macs = append(macs, card.MAC)
+	}
+	sort.Strings(macs)
+	var data = motherBoardID
+	for _, mac := range macs {
+		data += "|" + mac
+	}
+	var sum = sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:16])
+}
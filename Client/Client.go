@@ -1,39 +1,75 @@
 package Client
 
 import (
-	"encoding/json"
-	"errors"
+	"crypto"
 	"fmt"
 	"github.com/lizazacn/ElstLic/Entity"
 	"github.com/lizazacn/ElstLic/Utils"
-	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/Audit"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
 	"github.com/manifoldco/promptui"
-	"log"
 	"math/rand"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 )
 
+// Client 授权客户端。导出字段为配置项，应在开始校验前设置；
+// 运行期间的License与校验状态由内部锁保护，通过 Status、Snapshot、Subscribe 读取。
 type Client struct {
-	Offset      int
-	Step        int
-	DevInfo     string          // 开发信息
-	licPath     string          // lic证书路径
-	CheckStatus bool            // 校验状态
-	License     *Entity.License // 证书文件
+	Offset     int
+	Step       int
+	DevInfo    string           // 开发信息
+	Suite      string           // 生成node.info使用的算法套件，为空时使用默认套件
+	PublicKey  crypto.PublicKey // 签发公钥，设置后只接受签名有效的License
+	TrustStore *Trust.Store     // 受信任的签发公钥列表，支持密钥轮换，优先于PublicKey
+	Source     LicenseSource    // License来源
+	StatePath  string           // 运行状态文件路径，为空时使用可写文件来源旁的.state文件
+	Logger     Logger.Logger    // 日志，为空时不输出
+	Locale     I18n.Locale      // 提示与错误信息语言，为空时按LANG检测
+	AuditPath  string           // 审计日志路径，为空时使用可写文件来源旁的.audit文件
+	ProductID  string           // 产品ID，写入node.info；设置后拒绝其它产品的License
+
+	// OnCheckFailed 定时校验未通过或检测到时间回拨时调用，参数为失败原因；
+	// 为空时记录日志后退出进程
+	OnCheckFailed func(err error)
+
+	mu          sync.RWMutex
+	signed      bool                    // 当前License是否带签名
+	checkStatus bool                    // 校验状态
+	statusErr   error                   // 最近一次校验未通过的原因
+	license     *Entity.License         // 当前License，替换而不原地修改
+	handlers    []func(Event)           // 事件处理函数
+	subscribers map[chan Event]struct{} // 事件订阅通道
+	lastDigest  [32]byte                // 最近一次读取的License内容摘要
+	lastStamp   string                  // 最近一次读取的文件修改时间与大小
+	loadErr     error                   // 最近一次读取或校验新License失败的原因
+
+	checks         map[string]bool // 各校验项最近一次结果
+	lastSuccess    time.Time       // 最近一次校验通过时间
+	clockRollbacks int             // 检测到系统时间回拨的次数
+	lastFailed     string          // 最近一次未通过的校验项
+	checkSeq       uint64          // 校验结果计数
+
+	auditMu       sync.Mutex
+	auditChain    *Audit.Chain // 审计链状态，首次写入时从状态文件读取
+	auditHardware string       // 审计记录的硬件摘要，首次写入时采集
+
+	// fileMu 串行化License与状态文件的读取-修改-写回，避免节点注册与定时校验互相覆盖；
+	// 持有fileMu时可以获取mu，反之不行
+	fileMu sync.Mutex
 }
 
 type Lic func() bool
 
 // CreateNodeInfoFile 创建节点信息文件
 func (c *Client) CreateNodeInfoFile() error {
-	if c.Offset == 0 {
-		c.Offset = 1
-	}
-	if c.Step == 0 {
-		c.Step = 1
-	}
 	licData, err := c.createLicData()
 	if err != nil {
 		return err
@@ -44,7 +80,7 @@ func (c *Client) CreateNodeInfoFile() error {
 // createLicData 初始化Lic证书数据
 func (c *Client) createLicData() (*Entity.License, error) {
 	var lic = new(Entity.License)
-	lic.StartTime = time.Now().Format("2006-01-02T15:04:05")
+	lic.StartTime = Timestamp.Format(time.Now())
 
 	// 根据系统类型生成主板ID
 	switch runtime.GOOS {
@@ -56,10 +92,11 @@ func (c *Client) createLicData() (*Entity.License, error) {
 		lic.MotherBoardID = ""
 	}
 
-	lic.ClientTimeZone = time.Local.String()
+	lic.ClientTimeZone = Timestamp.LocalZone()
+	lic.ProductID = c.ProductID
 
 	if lic.MotherBoardID == "" {
-		return nil, errors.New("未获取到主板ID")
+		return nil, c.err(I18n.CodeNoMotherBoardID)
 	}
 	var netCards = Utils.GetAllNetCardInfo()
 	var template = &promptui.SelectTemplates{
@@ -69,7 +106,7 @@ func (c *Client) createLicData() (*Entity.License, error) {
 		Selected: "{{ .Name | cyan }} ({{ .MAC | red }})",
 	}
 	prompt := promptui.Select{
-		Label:     "请选择需要授权的网卡：",
+		Label:     c.text(I18n.PromptSelectNetCard),
 		Items:     netCards,
 		Templates: template,
 		Size:      5,
@@ -87,7 +124,7 @@ func (c *Client) createLicData() (*Entity.License, error) {
 func (c *Client) encryptDataToFile(lic *Entity.License) error {
 	var path = "./"
 	prompt := promptui.Prompt{
-		Label:   "请输入node.info文件保存路径",
+		Label:   c.text(I18n.PromptNodeInfoSavePath),
 		Default: path,
 	}
 
@@ -112,22 +149,16 @@ func (c *Client) encryptDataToFile(lic *Entity.License) error {
 		_ = file.Close()
 	}()
 
-	licByte, err := json.Marshal(lic)
-	if err != nil {
-		return err
-	}
-	lic.CheckCode = GM.SM3SUM(string(licByte))
-
-	licByte, err = json.Marshal(lic)
+	suite, err := Suite.Get(c.Suite)
 	if err != nil {
 		return err
 	}
-	var key = lic.CheckCode[:16]
-	encrypt, err := GM.SM4Encrypt(licByte, []byte(key), []byte(key))
+	offset, step := c.params()
+	var sealer = &Utils.Sealer{Offset: offset, Step: step, Suite: suite}
+	encrypt, err := sealer.Seal(lic)
 	if err != nil {
 		return err
 	}
-	encrypt = Utils.AddKeyToGMCipher(encrypt, []byte(key), c.Offset, c.Step)
 	_, err = file.Write(encrypt)
 	if err != nil {
 		return err
@@ -135,99 +166,205 @@ func (c *Client) encryptDataToFile(lic *Entity.License) error {
 	return nil
 }
 
-// encryptDataToLicFile 加密数据到认证文件
-func (c *Client) encryptDataToLicFile(lic *Entity.License, licPath string) error {
-	file, err := os.OpenFile(licPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
-	if err != nil {
-		return err
+// params 加密参数，未设置时为1
+func (c *Client) params() (offset, step int) {
+	offset, step = c.Offset, c.Step
+	if offset == 0 {
+		offset = 1
 	}
-	defer func() {
-		_ = file.Close()
-	}()
+	if step == 0 {
+		step = 1
+	}
+	return offset, step
+}
 
-	licByte, err := json.Marshal(lic)
+// encryptDataToLicFile 加密数据到认证文件。
+// 签名License或非文件来源的License不能改写，运行状态写入旁路状态文件。
+func (c *Client) encryptDataToLicFile(lic *Entity.License) error {
+	licPath, ok := c.writablePath()
+	c.mu.RLock()
+	var signed = c.signed
+	c.mu.RUnlock()
+	if signed || !ok {
+		return c.saveState(lic)
+	}
+	suite, err := Suite.Get(lic.CryptoSuite)
 	if err != nil {
 		return err
 	}
-	lic.CheckCode = GM.SM3SUM(string(licByte))
-
-	licByte, err = json.Marshal(lic)
+	file, err := os.OpenFile(licPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
 		return err
 	}
-	var key = lic.CheckCode[:16]
-	encrypt, err := GM.SM4Encrypt(licByte, []byte(key), []byte(key))
+	defer func() {
+		_ = file.Close()
+	}()
+
+	offset, step := c.params()
+	var sealer = &Utils.Sealer{Offset: offset, Step: step, Suite: suite}
+	encrypt, err := sealer.Seal(lic)
 	if err != nil {
 		return err
 	}
-	encrypt = Utils.AddKeyToGMCipher(encrypt, []byte(key), c.Offset, c.Step)
 	_, err = file.Write(encrypt)
 	if err != nil {
 		return err
 	}
+	c.remember(encrypt)
 	return nil
 }
 
-// DecryptDataFromFile 解密License文件
+// DecryptDataFromFile 解密License文件。
+// 指定路径时从该文件读取并将其设为License来源，否则从已配置的Source读取；
+// 不会弹出交互提示，需要交互输入路径时请使用 LoadFrom(&PromptSource{})。
 func (c *Client) DecryptDataFromFile(path ...string) (*Entity.License, error) {
-	if c.Offset == 0 {
-		c.Offset = 1
-	}
-	if c.Step == 0 {
-		c.Step = 1
-	}
-	var licPath string
 	if len(path) >= 1 && path[0] != "" {
-		licPath = path[0]
+		return c.LoadFrom(FileSource(path[0]))
 	}
-	if licPath == "" {
-		prompt := promptui.Prompt{
-			Label:   "请输入license.lic文件路径",
-			Default: "./license.lic",
-		}
+	return c.Load()
+}
 
-		result, err := prompt.Run()
-		if err != nil {
-			return nil, err
-		}
-		if result != "" {
-			licPath = result
-		}
+// LoadFrom 设置License来源并读取License
+func (c *Client) LoadFrom(source LicenseSource) (*Entity.License, error) {
+	c.mu.Lock()
+	c.Source = source
+	c.mu.Unlock()
+	return c.Load()
+}
+
+// Load 从当前License来源读取、解密并校验License。
+// 返回的License归调用方所有，修改它不影响客户端状态。
+func (c *Client) Load() (*Entity.License, error) {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+	return c.load()
+}
+
+// load 读取License并设为当前License，调用方需持有fileMu
+func (c *Client) load() (*Entity.License, error) {
+	var source = c.source()
+	if source == nil {
+		return nil, ErrNoSource
 	}
-	c.licPath = licPath
-	ciphertext, err := os.ReadFile(licPath)
+	ciphertext, err := source.Read()
 	if err != nil {
 		return nil, err
 	}
-	cipher, key := Utils.GetGMCipherAndKey(ciphertext, c.Offset, c.Step)
-	decrypt, err := GM.SM4Decrypt(cipher, key, key)
+	lic, signed, err := c.open(ciphertext)
 	if err != nil {
 		return nil, err
 	}
-	var lic = new(Entity.License)
-	err = json.Unmarshal(decrypt, lic)
+	c.remember(ciphertext)
+	c.mu.Lock()
+	c.signed = signed
+	c.license = lic.Clone()
+	c.mu.Unlock()
+	c.logger().Debug(c.text(I18n.MsgLicenseLoaded), append(Logger.License(lic), Logger.F(Logger.FieldSource, source.String()))...)
+	return lic, nil
+}
+
+// text 按客户端语言输出提示信息
+func (c *Client) text(id I18n.ID, args ...interface{}) string {
+	return I18n.T(c.Locale, id, args...)
+}
+
+// err 按客户端语言输出的错误
+func (c *Client) err(code I18n.ID, args ...interface{}) error {
+	return I18n.In(c.Locale, code, args...)
+}
+
+// logger 客户端日志，未配置时不输出
+func (c *Client) logger() Logger.Logger {
+	return Logger.OrNop(c.Logger)
+}
+
+// licenseFields 当前License的日志字段
+func (c *Client) licenseFields() []Logger.Field {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Logger.License(c.license)
+}
+
+// source 当前License来源
+func (c *Client) source() LicenseSource {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Source
+}
+
+// open 解密校验License并合并运行状态，不修改Client状态；状态文件不存在时创建
+func (c *Client) open(ciphertext []byte) (*Entity.License, bool, error) {
+	lic, signed, err := c.decode(ciphertext)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if c.needsState(signed) {
+		err = c.loadState(lic)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return lic, signed, nil
+}
+
+// needsState 运行状态是否单独保存：签名License与无法写回的来源
+func (c *Client) needsState(signed bool) bool {
+	_, ok := c.writablePath()
+	return signed || !ok
+}
+
+// decode 解密并校验License，拒绝其它产品的License，返回是否带签名
+func (c *Client) decode(ciphertext []byte) (*Entity.License, bool, error) {
+	offset, step := c.params()
+	var opener = &Utils.Opener{Offset: offset, Step: step, PublicKey: c.PublicKey, TrustStore: c.TrustStore}
+	lic, env, err := opener.Open(ciphertext)
+	if Utils.IsTampered(err) {
+		return nil, false, c.err(I18n.CodeTamperedContact, c.DevInfo)
 	}
-	stat, err := Utils.CheckData(lic)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	if !stat {
-		return nil, errors.New(fmt.Sprintf("数据疑似被篡改，请联系:%s！", c.DevInfo))
+	err = c.checkProduct(lic)
+	if err != nil {
+		return nil, false, err
 	}
-	c.License = lic
-	return lic, nil
+	return lic, env.SigAlg != Envelope.SigNone, nil
 }
 
-// EnableLicCheck 启动Lic证书校验
+// writablePath 可以写回License、并在旁边保存运行状态的文件类来源路径，只读来源返回false
+func (c *Client) writablePath() (string, bool) {
+	if source, ok := c.source().(ReadOnlySource); ok && source.ReadOnly() {
+		return "", false
+	}
+	return c.sourcePath()
+}
+
+// sourcePath 文件类来源的当前路径
+func (c *Client) sourcePath() (string, bool) {
+	source, ok := c.source().(PathSource)
+	if !ok {
+		return "", false
+	}
+	path, err := source.Path()
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// EnableLicCheck 启动Lic证书校验。校验失败或检测到时间回拨时调用 OnCheckFailed，
+// 未设置时退出进程；设置后应用可通过 Status 或 Subscribe 获取校验状态并自行限制功能。
 func (c *Client) EnableLicCheck(lic Lic) {
 	go c.licCheck(time.Now(), lic)
 }
 
-// EnableDefaultLicCheck 启动默认Lic证书校验机制
+// EnableDefaultLicCheck 启动默认Lic证书校验机制，licPath为空时使用已配置的Source
 func (c *Client) EnableDefaultLicCheck(licPath string) {
-	c.licPath = licPath
+	if licPath != "" {
+		c.mu.Lock()
+		c.Source = FileSource(licPath)
+		c.mu.Unlock()
+	}
 	go c.licCheck(time.Now(), c.DefaultLic)
 }
 
@@ -238,40 +375,133 @@ func (c *Client) licCheck(lastRunTime time.Time, lic Lic) {
 		var randomInt = rand.Intn(240)
 		var sleepTime = randomInt * 6
 		time.Sleep(time.Duration(sleepTime) * time.Minute)
-		var now = time.Now()
-		if c.License.LastCheckTime.After(now) {
-			c.CheckStatus = false
-			c.License.CheckStatus = false
-			log.Println("请勿随意修改系统时间，否则会影响License授权！")
-			os.Exit(0)
-		}
-		if now.Sub(lastRunTime).Minutes()-float64(sleepTime) >= 30 {
-			c.CheckStatus = false
-			c.License.CheckStatus = false
-			log.Println("请勿随意修改系统时间，否则会影响License授权！")
-			os.Exit(0)
-		}
-		status := lic()
-		if !status {
-			os.Exit(0)
-		}
-		lastRunTime = now
-		c.License.LastCheckTime = &now
-		err := c.encryptDataToLicFile(c.License, c.licPath)
-		if err != nil {
-			return
-		}
+		lastRunTime = c.scheduledCheck(lic, lastRunTime, sleepTime)
 	}
 }
 
-// DefaultLic 默认证书校验规则
-func (c *Client) DefaultLic() bool {
-	license, err := c.DecryptDataFromFile(c.licPath)
+// scheduledCheck 执行一次定时校验，sleepTime为距上次校验的等待分钟数，返回本次校验时间
+func (c *Client) scheduledCheck(lic Lic, lastRunTime time.Time, sleepTime int) time.Time {
+	var now = time.Now()
+	var lastCheckTime = c.lastCheckTime()
+	if (lastCheckTime != nil && lastCheckTime.After(now)) || now.Sub(lastRunTime).Minutes()-float64(sleepTime) >= 30 {
+		c.recordCheck(CheckClock, ErrClockRollback)
+		c.setStatus(false, ErrClockRollback)
+		c.logger().Error(c.text(I18n.MsgClockTampered), c.licenseFields()...)
+		c.checkFailed(ErrClockRollback)
+		return now
+	}
+	c.recordCheck(CheckClock, nil)
+	var seq = c.checkCount()
+	status := lic()
+	// 校验函数未自行记录结果时（自定义校验函数）在此记录
+	if c.checkCount() == seq {
+		c.setStatus(status, nil)
+	}
+	if !status {
+		c.checkFailed(c.failure())
+	}
+	err := c.saveCheckTime(now)
 	if err != nil {
-		log.Println(err.Error())
+		// 无法保存校验时间时重启后无法发现时间回拨，视为校验未通过，下次继续校验
+		c.setStatus(false, err)
+		c.logger().Error(c.text(I18n.MsgSaveStateFailed), append(c.licenseFields(), Logger.Err(err))...)
+	}
+	return now
+}
+
+// checkFailed 定时校验未通过时调用 OnCheckFailed，未设置时退出进程
+func (c *Client) checkFailed(err error) {
+	if c.OnCheckFailed == nil {
+		c.logger().Error(c.text(I18n.MsgCheckFailedExit), append(c.licenseFields(), Logger.Err(err))...)
+		os.Exit(0)
+	}
+	c.logger().Error(c.text(I18n.MsgCheckFailed), append(c.licenseFields(), Logger.Err(err))...)
+	c.OnCheckFailed(err)
+}
+
+// saveCheckTime 记录校验时间并写回License或状态文件
+func (c *Client) saveCheckTime(now time.Time) error {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+	license := c.touch(now)
+	if license == nil {
+		return nil
+	}
+	return c.encryptDataToLicFile(license)
+}
+
+// DefaultLic 默认证书校验规则：License来源变化时先校验新License再替换，
+// 新License无效时发送 EventReloadFailed 事件并继续校验旧License
+func (c *Client) DefaultLic() bool {
+	var source = c.source()
+	if source == nil {
+		c.recordCheck(CheckLoad, ErrNoSource)
+		c.setStatus(false, ErrNoSource)
 		return false
 	}
+	c.fileMu.Lock()
+	old, lic, changed, err := c.reload(source)
+	c.fileMu.Unlock()
+	if changed && err != nil {
+		c.reloaded(source, old, nil, err)
+	}
+	c.mu.RLock()
+	var current = c.license.Clone()
+	if current == nil && err == nil {
+		err = c.loadErr
+	}
+	c.mu.RUnlock()
+	if current != nil && (changed || err == nil) {
+		err = c.verifyLicense(current)
+	} else {
+		// 未加载过有效License或来源无法读取
+		if err == nil {
+			err = ErrNoSource
+		}
+		c.recordCheck(CheckLoad, err)
+	}
+	c.setStatus(err == nil, err)
+	if changed && lic != nil {
+		c.reloaded(source, old, lic, nil)
+	}
+	return err == nil
+}
 
+// verifyLicense 校验License所属产品、绑定的主板与有效期，并记录各项校验结果
+func (c *Client) verifyLicense(license *Entity.License) error {
+	err := c.recordCheck(CheckProduct, c.checkProduct(license))
+	if err != nil {
+		return err
+	}
+	err = c.recordCheck(CheckMotherBoard, c.checkMotherBoard(license))
+	if err != nil {
+		return err
+	}
+	err = c.recordCheck(CheckClock, checkClock(license))
+	if err != nil {
+		return err
+	}
+	return c.recordCheck(CheckValidity, c.checkValidity(license))
+}
+
+// checkProduct 配置了产品ID时，License必须属于该产品
+func (c *Client) checkProduct(license *Entity.License) error {
+	if c.ProductID != "" && license.ProductID != c.ProductID {
+		return c.err(I18n.CodeProductMismatch, license.ProductID)
+	}
+	return nil
+}
+
+// checkClock 最后校验时间晚于当前时间说明系统时间被回拨
+func checkClock(license *Entity.License) error {
+	if license.LastCheckTime != nil && license.LastCheckTime.After(time.Now()) {
+		return ErrClockRollback
+	}
+	return nil
+}
+
+// checkMotherBoard 校验License绑定的主板
+func (c *Client) checkMotherBoard(license *Entity.License) error {
 	// 获取主板ID
 	var motherBoardID string
 	switch runtime.GOOS {
@@ -280,57 +510,66 @@ func (c *Client) DefaultLic() bool {
 	case "windows":
 		motherBoardID = Utils.GetWinMotherBoardID()
 	default:
-		log.Println("未识别到主板ID")
-		return false
+		return c.err(I18n.CodeNoMotherBoardID)
 	}
 
 	// 验证主板ID是否一致
 	if motherBoardID != license.MotherBoardID {
-		log.Println("主板ID比对异常，请检查是否使用了正确的license文件")
-		return false
+		return c.err(I18n.CodeMotherBoardMismatch)
 	}
+	return nil
+}
 
+// checkValidity 校验License有效期
+func (c *Client) checkValidity(license *Entity.License) error {
 	// 验证系统license是否过期
 	var now = time.Now()
-	startAt, err := time.ParseInLocation("2006-01-02T15:04:05", license.StartTime, time.Local)
+	startAt, err := license.StartAt()
 	if err != nil {
-		log.Println("解析时间异常，疑似数据被篡改！")
-		return false
+		return c.err(I18n.CodeBadTime)
 	}
 
 	if now.Before(startAt) {
-		log.Println("证书不在有效期！")
-		return false
+		return c.err(I18n.CodeNotYetValid)
 	}
-	endAt, err := time.ParseInLocation("2006-01-02T15:04:05", license.EndTime, time.Local)
+	endAt, err := license.EndAt()
 	if err != nil {
-		log.Println("解析时间异常，疑似数据被篡改！")
-		return false
+		return c.err(I18n.CodeBadTime)
 	}
-	//endAt = now.AddDate(100, 0, 0)
 	if now.After(endAt) {
-		log.Println("证书已过期，请联系销售人员重新获取授权！")
-		return false
+		return c.err(I18n.CodeExpired)
 	}
-	return true
+	return nil
 }
 
-// RegisterNodeToLicense 注册新节点
+// RegisterNodeToLicense 注册新节点，licPath为空时使用已配置的Source
 func (c *Client) RegisterNodeToLicense(info *Entity.NodeInfo, licPath string) error {
-	if licPath == "" {
-		licPath = c.licPath
-	}
-	license, err := c.DecryptDataFromFile(licPath)
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+	if licPath != "" {
+		c.mu.Lock()
+		c.Source = FileSource(licPath)
+		c.mu.Unlock()
+	}
+	license, err := c.load()
 	if err != nil {
 		return err
 	}
 	if license.UseNodes >= license.AllowNodes {
-		return errors.New("超出允许的节点范围，请联系产品供应商扩容许可")
+		return c.err(I18n.CodeNodeLimit)
 	}
 
 	if license.NodeList == nil {
 		license.NodeList = make([]*Entity.NodeInfo, 0)
 	}
 	license.NodeList = append(license.NodeList, info)
-	return c.encryptDataToLicFile(license, licPath)
+	license.UseNodes++
+	err = c.encryptDataToLicFile(license)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.license = license.Clone()
+	c.mu.Unlock()
+	return nil
 }
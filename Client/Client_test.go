@@ -0,0 +1,286 @@
+package Client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+const (
+	testOffset = 3
+	testStep   = 3
+)
+
+// testLicense 绑定本机主板、当前有效的未签名License
+func testLicense(t *testing.T) *Entity.License {
+	t.Helper()
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		t.Skip("motherboard check is not supported on " + runtime.GOOS)
+	}
+	var now = time.Now()
+	return &Entity.License{
+		StartTime:         Timestamp.Format(now.Add(-time.Hour)),
+		EndTime:           Timestamp.Format(now.AddDate(0, 1, 0)),
+		LicenseCreateTime: Timestamp.Format(now.Add(-time.Hour)),
+		AllowNodes:        64,
+		MotherBoardID:     Utils.GetMotherBoardID(),
+		MacAddr:           "00:11:22:33:44:55",
+		CustomerTag:       "ACME",
+	}
+}
+
+// writeLicense 加密License并写入path
+func writeLicense(t *testing.T, path string, lic *Entity.License) {
+	t.Helper()
+	data, err := Utils.SealLicense(lic, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestClient 从临时目录中的license.lic读取License的客户端
+func newTestClient(t *testing.T, lic *Entity.License) (*Client, string) {
+	t.Helper()
+	var path = filepath.Join(t.TempDir(), "license.lic")
+	writeLicense(t, path, lic)
+	var client = &Client{Offset: testOffset, Step: testStep, DevInfo: "test", Locale: I18n.ZH, Source: FileSource(path)}
+	return client, path
+}
+
+// TestRegisterNodeConcurrent 并发注册节点与定时校验写回时不丢失节点
+func TestRegisterNodeConcurrent(t *testing.T) {
+	client, path := newTestClient(t, testLicense(t))
+	if _, err := client.Load(); err != nil {
+		t.Fatal(err)
+	}
+	const nodes = 16
+	var wg sync.WaitGroup
+	var done = make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := client.saveCheckTime(time.Now()); err != nil {
+				t.Error(err)
+				return
+			}
+			_ = client.Reload()
+			_ = client.Snapshot()
+			_ = client.Status()
+		}
+	}()
+	for i := 0; i < nodes; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var node = &Entity.NodeInfo{NodeName: fmt.Sprintf("node-%d", i), NodeIP: fmt.Sprintf("10.0.0.%d", i)}
+			if err := client.RegisterNodeToLicense(node, ""); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	writer.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lic, _, err := Utils.OpenLicense(data, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lic.UseNodes != nodes || len(lic.NodeList) != nodes {
+		t.Fatalf("file has UseNodes=%d, %d nodes; want %d", lic.UseNodes, len(lic.NodeList), nodes)
+	}
+	if snapshot := client.Snapshot(); snapshot.UseNodes != nodes {
+		t.Fatalf("Snapshot().UseNodes = %d, want %d", snapshot.UseNodes, nodes)
+	}
+}
+
+func TestRegisterNodeLimit(t *testing.T) {
+	var lic = testLicense(t)
+	lic.AllowNodes = 1
+	client, path := newTestClient(t, lic)
+	err := client.RegisterNodeToLicense(&Entity.NodeInfo{NodeName: "a"}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RegisterNodeToLicense(&Entity.NodeInfo{NodeName: "b"}, path)
+	if I18n.Code(err) != I18n.CodeNodeLimit {
+		t.Fatalf("second registration error = %v, want CodeNodeLimit", err)
+	}
+}
+
+// TestProductMismatch 其它产品的License在读取与注册节点时都被拒绝，不会成为当前License
+func TestProductMismatch(t *testing.T) {
+	var lic = testLicense(t)
+	lic.ProductID = "product-b"
+	client, path := newTestClient(t, lic)
+	client.ProductID = "product-a"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Load(); I18n.Code(err) != I18n.CodeProductMismatch {
+		t.Fatalf("Load error = %v, want CodeProductMismatch", err)
+	}
+	err = client.RegisterNodeToLicense(&Entity.NodeInfo{NodeName: "a"}, path)
+	if I18n.Code(err) != I18n.CodeProductMismatch {
+		t.Fatalf("RegisterNodeToLicense error = %v, want CodeProductMismatch", err)
+	}
+	if snapshot := client.Snapshot(); snapshot.ProductID != "" {
+		t.Errorf("license for %s became the current license", snapshot.ProductID)
+	}
+	if written, _ := os.ReadFile(path); string(written) != string(data) {
+		t.Error("license file rewritten for another product")
+	}
+}
+
+// TestConcurrentChecksReloadsReads 校验、热加载与读取并发进行，配合 go test -race 运行
+func TestConcurrentChecksReloadsReads(t *testing.T) {
+	client, path := newTestClient(t, testLicense(t))
+	events, cancel := client.Subscribe()
+	defer cancel()
+	var received sync.WaitGroup
+	received.Add(1)
+	go func() {
+		defer received.Done()
+		for range events {
+		}
+	}()
+	// 事件处理函数在触发事件的goroutine中调用，读取客户端状态不能死锁
+	client.OnEvent(func(Event) {
+		_ = client.Status()
+	})
+
+	var renewals [4][]byte
+	for i := range renewals {
+		var renewed = testLicense(t)
+		renewed.AllowNodes = 10 + i
+		data, err := Utils.SealLicense(renewed, testOffset, testStep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renewals[i] = data
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				client.DefaultLic()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = client.Reload()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var snapshot = client.Snapshot()
+				snapshot.NodeList = append(snapshot.NodeList, &Entity.NodeInfo{})
+				_ = client.Status()
+				_ = client.WriteMetrics(io.Discard)
+			}
+		}()
+		go func(data []byte) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				// 先写临时文件再改名，读取方不会读到写了一半的文件
+				var tmp = fmt.Sprintf("%s.%p.tmp", path, &data)
+				if err := os.WriteFile(tmp, data, 0600); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := os.Rename(tmp, path); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(renewals[i])
+	}
+	wg.Wait()
+	cancel()
+	received.Wait()
+
+	if !client.DefaultLic() || !client.Status() {
+		t.Fatal("license check failed after concurrent reloads")
+	}
+	if snapshot := client.Snapshot(); snapshot.AllowNodes < 10 {
+		t.Fatalf("Snapshot().AllowNodes = %d, want a renewed license", snapshot.AllowNodes)
+	}
+}
+
+// TestScheduledCheckFailed 定时校验未通过或时间回拨时调用OnCheckFailed
+func TestScheduledCheckFailed(t *testing.T) {
+	var expired = testLicense(t)
+	expired.EndTime = Timestamp.Format(time.Now().Add(-time.Minute))
+	client, _ := newTestClient(t, expired)
+	var failures []error
+	client.OnCheckFailed = func(err error) {
+		failures = append(failures, err)
+	}
+	var last = client.scheduledCheck(client.DefaultLic, time.Now(), 0)
+	if len(failures) != 1 || I18n.Code(failures[0]) != I18n.CodeExpired {
+		t.Fatalf("failures = %v, want CodeExpired", failures)
+	}
+
+	// 距上次校验的时间远超等待时间
+	client.scheduledCheck(client.DefaultLic, last.Add(-2*time.Hour), 0)
+	if len(failures) != 2 || !errors.Is(failures[1], ErrClockRollback) {
+		t.Fatalf("failures = %v, want ErrClockRollback", failures)
+	}
+
+	// 自定义校验函数未给出原因
+	client.scheduledCheck(func() bool { return false }, time.Now(), 0)
+	if len(failures) != 3 || !errors.Is(failures[2], ErrCheckFailed) {
+		t.Fatalf("failures = %v, want ErrCheckFailed", failures)
+	}
+}
+
+// TestScheduledCheckSaveFailure 保存校验时间失败时状态为未通过，不调用OnCheckFailed
+func TestScheduledCheckSaveFailure(t *testing.T) {
+	client, path := newTestClient(t, testLicense(t))
+	if !client.DefaultLic() {
+		t.Fatal("license check failed")
+	}
+	client.OnCheckFailed = func(err error) {
+		t.Errorf("OnCheckFailed(%v) called", err)
+	}
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	}
+	client.scheduledCheck(func() bool { return true }, time.Now(), 0)
+	if client.Status() {
+		t.Fatal("status is OK although the check time was not saved")
+	}
+}
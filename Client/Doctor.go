@@ -0,0 +1,131 @@
+package Client
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Doctor 采集诊断信息：全部硬件指纹、网卡、License条款、时区与时钟、最近的校验结果
+func (c *Client) Doctor() *Entity.Diagnostics {
+	var now = time.Now()
+	var zone, offset = now.Zone()
+	var diag = &Entity.Diagnostics{
+		CreatedAt:    now,
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		Fingerprints: Utils.Fingerprints(),
+		NetCards:     Utils.GetAllNetCardInfo(),
+		TimeZone:     Timestamp.LocalZone() + " (" + zone + ")",
+		UTCOffset:    offset,
+		LocalTime:    now.Format(Timestamp.Layout),
+	}
+	diag.Hostname, _ = os.Hostname()
+
+	c.mu.RLock()
+	diag.License = c.license.Clone()
+	diag.Status = c.checkStatus
+	diag.ClockRollbacks = c.clockRollbacks
+	diag.Checks = make(map[string]bool, len(c.checks))
+	for k, v := range c.checks {
+		diag.Checks[k] = v
+	}
+	if !c.lastSuccess.IsZero() {
+		var lastSuccess = c.lastSuccess
+		diag.LastSuccess = &lastSuccess
+	}
+	c.mu.RUnlock()
+
+	// 尚未加载License时尝试读取，不改变客户端状态
+	if diag.License == nil {
+		lic, err := c.peek()
+		if err != nil {
+			diag.LicenseError = err.Error()
+		}
+		diag.License = lic
+	}
+	return diag
+}
+
+// peek 读取并解密License，不修改客户端状态
+func (c *Client) peek() (*Entity.License, error) {
+	var source = c.source()
+	if source == nil {
+		return nil, ErrNoSource
+	}
+	ciphertext, err := source.Read()
+	if err != nil {
+		return nil, err
+	}
+	lic, signed, err := c.decode(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if c.needsState(signed) {
+		// 只读取已有的状态文件，不存在时不创建；与写入串行以免读到写了一半的文件
+		c.fileMu.Lock()
+		err = c.readState(lic)
+		c.fileMu.Unlock()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return lic, nil
+}
+
+// vendorKey 当前的签发公钥：TrustStore中最新的密钥，未配置时使用PublicKey，算法套件按公钥类型确定
+func (c *Client) vendorKey() (*Trust.Key, error) {
+	if c.TrustStore != nil {
+		return c.TrustStore.Latest()
+	}
+	if c.PublicKey == nil {
+		return nil, c.err(I18n.CodeNoPublicKey)
+	}
+	suite, err := Suite.ForPublicKey(c.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return Trust.NewKey(suite, c.PublicKey, time.Time{})
+}
+
+// ExportSupportBundle 采集诊断信息并使用签发公钥加密，只有签发方能够解密分析。
+// key为空时使用TrustStore中当前的签发公钥或PublicKey。
+func (c *Client) ExportSupportBundle(key *Trust.Key) ([]byte, error) {
+	if key == nil {
+		latest, err := c.vendorKey()
+		if err != nil {
+			return nil, err
+		}
+		key = latest
+	}
+	suite, err := Suite.Get(key.Suite)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := Suite.EncrypterOf(suite)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := key.Public()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(c.Doctor())
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encrypter.EncryptTo(pub, data)
+	if err != nil {
+		return nil, err
+	}
+	var bundle = &Entity.SupportBundle{Version: Entity.SupportBundleVersion, KeyID: key.KeyID, Suite: suite.Name(), Data: encrypted}
+	return json.MarshalIndent(bundle, "", "    ")
+}
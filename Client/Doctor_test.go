@@ -0,0 +1,65 @@
+package Client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+)
+
+// TestSupportBundleStdPublicKey 只配置Ed25519签发公钥时按公钥类型选择套件
+func TestSupportBundleStdPublicKey(t *testing.T) {
+	suite, err := Suite.Get(Suite.NameStd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = &Client{Locale: I18n.ZH, PublicKey: priv.Public(), AuditPath: filepath.Join(t.TempDir(), "license.audit")}
+	data, err := client.ExportSupportBundle(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle = new(Entity.SupportBundle)
+	if err = json.Unmarshal(data, bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Suite != Suite.NameStd {
+		t.Errorf("bundle suite = %q, want %q", bundle.Suite, Suite.NameStd)
+	}
+	encrypter, err := Suite.EncrypterOf(suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = encrypter.DecryptWith(priv, bundle.Data); err != nil {
+		t.Fatalf("decrypt bundle: %v", err)
+	}
+
+	// 审计日志同样使用该公钥加密锚点
+	if _, err = client.ExportAudit(); err != nil {
+		t.Fatalf("ExportAudit: %v", err)
+	}
+}
+
+// TestDoctorReadOnly 尚未加载License时诊断只读取License，不创建状态文件
+func TestDoctorReadOnly(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = t.TempDir()
+	var client = signedClient(t, dir, data, store)
+	var diag = client.Doctor()
+	if diag.License == nil || diag.LicenseError != "" {
+		t.Fatalf("Doctor license = %v, error = %q", diag.License, diag.LicenseError)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "license.lic.state")); !os.IsNotExist(err) {
+		t.Fatalf("Doctor created the state file: %v", err)
+	}
+	if snapshot := client.Snapshot(); snapshot.MotherBoardID != "" {
+		t.Fatal("Doctor changed the client license")
+	}
+}
@@ -0,0 +1,123 @@
+package Client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 指标以Prometheus文本格式输出，不依赖Prometheus客户端库。
+//
+//	elstlic_license_expiry_seconds                距到期的秒数，永久授权为+Inf
+//	elstlic_license_allowed_nodes                 允许接入的节点数
+//	elstlic_license_used_nodes                    已接入的节点数
+//	elstlic_license_last_success_timestamp_seconds 最近一次校验通过的时间
+//	elstlic_license_check_status{check="..."}     各校验项结果，1为通过
+//	elstlic_license_status                        总体校验状态，1为通过
+//	elstlic_clock_rollback_total                  检测到系统时间回拨的次数
+
+// metricsContentType Prometheus文本格式
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteMetrics 以Prometheus文本格式输出当前License状态，尚未加载License时只输出校验相关指标
+func (c *Client) WriteMetrics(w io.Writer) error {
+	c.mu.RLock()
+	var license = c.license.Clone()
+	var status = c.checkStatus
+	var lastSuccess = c.lastSuccess
+	var rollbacks = c.clockRollbacks
+	var checks = make(map[string]bool, len(c.checks))
+	for k, v := range c.checks {
+		checks[k] = v
+	}
+	c.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if license != nil {
+		var expiry = math.Inf(1)
+		if !license.PermanentAuth {
+			endAt, err := license.EndAt()
+			if err == nil {
+				expiry = time.Until(endAt).Seconds()
+			} else {
+				expiry = math.NaN()
+			}
+		}
+		writeMetric(&buf, "elstlic_license_expiry_seconds", "gauge", "距License到期的秒数，永久授权为+Inf", "", expiry)
+		writeMetric(&buf, "elstlic_license_allowed_nodes", "gauge", "允许接入的节点数", "", float64(license.AllowNodes))
+		writeMetric(&buf, "elstlic_license_used_nodes", "gauge", "已接入的节点数", "", float64(license.UseNodes))
+	}
+	if !lastSuccess.IsZero() {
+		writeMetric(&buf, "elstlic_license_last_success_timestamp_seconds", "gauge", "最近一次校验通过的Unix时间", "", float64(lastSuccess.Unix()))
+	}
+	if len(checks) > 0 {
+		var names = make([]string, 0, len(checks))
+		for name := range checks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		writeHeader(&buf, "elstlic_license_check_status", "gauge", "各校验项最近一次结果，1为通过")
+		for _, name := range names {
+			writeSample(&buf, "elstlic_license_check_status", `check="`+labelEscaper.Replace(name)+`"`, boolValue(checks[name]))
+		}
+	}
+	writeMetric(&buf, "elstlic_license_status", "gauge", "总体校验状态，1为通过", "", boolValue(status))
+	writeMetric(&buf, "elstlic_clock_rollback_total", "counter", "检测到系统时间回拨的次数", "", float64(rollbacks))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// MetricsHandler 输出License指标的HTTP处理器，可挂载到 /metrics
+func (c *Client) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		_ = c.WriteMetrics(w)
+	})
+}
+
+func writeMetric(buf *bytes.Buffer, name, typ, help, labels string, value float64) {
+	writeHeader(buf, name, typ, help)
+	writeSample(buf, name, labels, value)
+}
+
+func writeHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, typ)
+}
+
+func writeSample(buf *bytes.Buffer, name, labels string, value float64) {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	fmt.Fprintf(buf, "%s %s\n", name, formatValue(value))
+}
+
+// 文本格式中HELP需转义反斜杠与换行，标签值还需转义双引号
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,191 @@
+package Client
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sample 文本格式中的一条样本
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parseMetrics 解析Prometheus文本格式，返回各指标的类型与样本；
+// 样本必须出现在同名指标的TYPE之后，同一指标的HELP与TYPE只能出现一次
+func parseMetrics(t *testing.T, data []byte) (map[string]string, []*sample) {
+	t.Helper()
+	var types = make(map[string]string)
+	var helps = make(map[string]bool)
+	var samples = make([]*sample, 0)
+	var scanner = bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line = scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			var name = strings.SplitN(strings.TrimPrefix(line, "# HELP "), " ", 2)[0]
+			if helps[name] {
+				t.Fatalf("duplicate HELP for %s", name)
+			}
+			helps[name] = true
+		case strings.HasPrefix(line, "# TYPE "):
+			var fields = strings.Fields(strings.TrimPrefix(line, "# TYPE "))
+			if len(fields) != 2 {
+				t.Fatalf("bad TYPE line %q", line)
+			}
+			if _, ok := types[fields[0]]; ok {
+				t.Fatalf("duplicate TYPE for %s", fields[0])
+			}
+			types[fields[0]] = fields[1]
+		case line == "" || strings.HasPrefix(line, "#"):
+			t.Fatalf("unexpected line %q", line)
+		default:
+			var s = parseSample(t, line)
+			if _, ok := types[s.name]; !ok {
+				t.Fatalf("sample %s before its TYPE", s.name)
+			}
+			samples = append(samples, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return types, samples
+}
+
+// parseSample 解析一行样本，还原转义的标签值
+func parseSample(t *testing.T, line string) *sample {
+	t.Helper()
+	var s = &sample{labels: make(map[string]string)}
+	var rest string
+	if i := strings.IndexAny(line, "{ "); i < 0 {
+		t.Fatalf("bad sample %q", line)
+	} else {
+		s.name, rest = line[:i], line[i:]
+	}
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for !strings.HasPrefix(rest, "}") {
+			var eq = strings.Index(rest, `="`)
+			if eq < 0 {
+				t.Fatalf("bad labels in %q", line)
+			}
+			var key, value = rest[:eq], ""
+			rest = rest[eq+2:]
+			for {
+				if rest == "" {
+					t.Fatalf("unterminated label value in %q", line)
+				}
+				var c = rest[0]
+				rest = rest[1:]
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					switch rest[0] {
+					case '\\', '"':
+						c = rest[0]
+					case 'n':
+						c = '\n'
+					default:
+						t.Fatalf("bad escape in %q", line)
+					}
+					rest = rest[1:]
+				} else if c == '\n' {
+					t.Fatalf("raw newline in %q", line)
+				}
+				value += string(c)
+			}
+			s.labels[key] = value
+			rest = strings.TrimPrefix(rest, ",")
+		}
+		rest = rest[1:]
+	}
+	value, err := strconv.ParseFloat(strings.TrimPrefix(rest, " "), 64)
+	if err != nil {
+		t.Fatalf("bad value in %q: %v", line, err)
+	}
+	s.value = value
+	return s
+}
+
+func TestWriteMetricsNoLicense(t *testing.T) {
+	var buf bytes.Buffer
+	if err := new(Client).WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	types, samples := parseMetrics(t, buf.Bytes())
+	var want = map[string]string{"elstlic_license_status": "gauge", "elstlic_clock_rollback_total": "counter"}
+	if len(types) != len(want) || len(samples) != len(want) {
+		t.Fatalf("metrics without license:\n%s", buf.String())
+	}
+	for name, typ := range want {
+		if types[name] != typ {
+			t.Errorf("TYPE %s = %q, want %q", name, types[name], typ)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	client, _ := newTestClient(t, testLicense(t))
+	if !client.DefaultLic() {
+		t.Fatal("license check failed")
+	}
+	var weird = "a\"b\\c\nd"
+	_ = client.recordCheck(weird, errors.New("failed"))
+	_ = client.recordCheck(CheckClock, errors.New("rollback"))
+
+	var buf bytes.Buffer
+	if err := client.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	types, samples := parseMetrics(t, buf.Bytes())
+	var want = map[string]string{
+		"elstlic_license_expiry_seconds":                 "gauge",
+		"elstlic_license_allowed_nodes":                  "gauge",
+		"elstlic_license_used_nodes":                     "gauge",
+		"elstlic_license_last_success_timestamp_seconds": "gauge",
+		"elstlic_license_check_status":                   "gauge",
+		"elstlic_license_status":                         "gauge",
+		"elstlic_clock_rollback_total":                   "counter",
+	}
+	for name, typ := range want {
+		if types[name] != typ {
+			t.Errorf("TYPE %s = %q, want %q", name, types[name], typ)
+		}
+	}
+	if len(types) != len(want) {
+		t.Errorf("unexpected metrics:\n%s", buf.String())
+	}
+
+	var checks = make(map[string]float64)
+	var values = make(map[string]float64)
+	for _, s := range samples {
+		if s.name == "elstlic_license_check_status" {
+			checks[s.labels["check"]] = s.value
+			continue
+		}
+		if len(s.labels) != 0 {
+			t.Errorf("%s has labels %v", s.name, s.labels)
+		}
+		values[s.name] = s.value
+	}
+	if v, ok := checks[weird]; !ok || v != 0 {
+		t.Errorf("escaped label not round-tripped: %v", checks)
+	}
+	if checks[CheckClock] != 0 || values["elstlic_clock_rollback_total"] != 1 {
+		t.Errorf("clock check %v, rollbacks %v", checks[CheckClock], values["elstlic_clock_rollback_total"])
+	}
+	if values["elstlic_license_allowed_nodes"] != 64 {
+		t.Errorf("allowed nodes = %v", values["elstlic_license_allowed_nodes"])
+	}
+	if expiry := values["elstlic_license_expiry_seconds"]; math.IsInf(expiry, 0) || expiry <= 0 {
+		t.Errorf("expiry seconds = %v", expiry)
+	}
+}
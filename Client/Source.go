@@ -0,0 +1,182 @@
+package Client
+
+import (
+	"encoding/base64"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/manifoldco/promptui"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LicenseSource License文件内容来源
+type LicenseSource interface {
+	// Read 读取License文件内容
+	Read() ([]byte, error)
+	// String 来源描述，用于日志与错误信息
+	String() string
+}
+
+// PathSource 对应文件系统路径的来源，运行状态可写在该路径旁
+type PathSource interface {
+	LicenseSource
+	// Path 当前生效的文件路径
+	Path() (string, error)
+}
+
+// ReadOnlySource 只读挂载的来源。运行状态与审计日志不会写在License旁，
+// 未设置 Client.StatePath 与 Client.AuditPath 时运行状态只保存在内存中，不记录审计日志
+type ReadOnlySource interface {
+	PathSource
+	ReadOnly() bool
+}
+
+// ErrNoSource 未配置License来源
+var ErrNoSource error = I18n.E(I18n.CodeNoSource)
+
+// FileSource 文件路径
+type FileSource string
+
+func (f FileSource) Read() ([]byte, error) { return os.ReadFile(string(f)) }
+func (f FileSource) Path() (string, error) { return string(f), nil }
+func (f FileSource) String() string        { return "file:" + string(f) }
+
+// BytesSource 内存数据，可配合 //go:embed 使用
+type BytesSource []byte
+
+func (b BytesSource) Read() ([]byte, error) {
+	if len(b) == 0 {
+		return nil, I18n.E(I18n.CodeEmptyLicense)
+	}
+	return append([]byte(nil), b...), nil
+}
+func (b BytesSource) String() string { return "bytes" }
+
+// ReaderSource 从io.Reader读取，首次读取后缓存内容
+type ReaderSource struct {
+	Reader io.Reader
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// NewReaderSource 创建io.Reader来源
+func NewReaderSource(r io.Reader) *ReaderSource {
+	return &ReaderSource{Reader: r}
+}
+
+func (r *ReaderSource) Read() ([]byte, error) {
+	r.once.Do(func() {
+		r.data, r.err = io.ReadAll(r.Reader)
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]byte(nil), r.data...), nil
+}
+func (r *ReaderSource) String() string { return "reader" }
+
+// EnvSource 环境变量，值为License文件内容的base64编码
+type EnvSource string
+
+func (e EnvSource) Read() ([]byte, error) {
+	value, ok := os.LookupEnv(string(e))
+	if !ok || value == "" {
+		return nil, I18n.E(I18n.CodeEnvNotSet, string(e))
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+}
+func (e EnvSource) String() string { return "env:" + string(e) }
+
+// SecretDirSource Kubernetes风格的挂载目录，每个键对应目录下的一个文件。
+// 挂载目录只读，不会在其中写入License、运行状态或审计日志
+type SecretDirSource struct {
+	Dir string
+	Key string // 为空时使用license.lic
+}
+
+func (s SecretDirSource) Path() (string, error) {
+	var key = s.Key
+	if key == "" {
+		key = "license.lic"
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+func (s SecretDirSource) Read() ([]byte, error) {
+	path, _ := s.Path()
+	return os.ReadFile(path)
+}
+
+func (s SecretDirSource) ReadOnly() bool { return true }
+
+func (s SecretDirSource) String() string {
+	path, _ := s.Path()
+	return "secret:" + path
+}
+
+// SearchPathSource 按顺序查找，使用第一个存在的文件
+type SearchPathSource []string
+
+func (s SearchPathSource) Path() (string, error) {
+	for _, path := range s {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", I18n.E(I18n.CodeNoLicenseFile, strings.Join(s, ", "))
+}
+
+func (s SearchPathSource) Read() ([]byte, error) {
+	path, err := s.Path()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (s SearchPathSource) String() string { return "search:" + strings.Join(s, ",") }
+
+// PromptSource 交互式输入License文件路径，只有显式使用时才会提示
+type PromptSource struct {
+	Default string      // 默认路径，为空时使用./license.lic
+	Locale  I18n.Locale // 提示语言，为空时按LANG检测
+
+	path string
+}
+
+func (p *PromptSource) Path() (string, error) {
+	if p.path != "" {
+		return p.path, nil
+	}
+	var def = p.Default
+	if def == "" {
+		def = "./license.lic"
+	}
+	prompt := promptui.Prompt{
+		Label:   I18n.T(p.Locale, I18n.PromptLicPath),
+		Default: def,
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if result == "" {
+		result = def
+	}
+	p.path = result
+	return p.path, nil
+}
+
+func (p *PromptSource) Read() ([]byte, error) {
+	path, err := p.Path()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (p *PromptSource) String() string { return "prompt:" + p.path }
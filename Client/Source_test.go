@@ -0,0 +1,302 @@
+package Client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+// secretDir 只读的Kubernetes风格挂载目录，包含license.lic
+func secretDir(t *testing.T, data []byte) string {
+	t.Helper()
+	var dir = filepath.Join(t.TempDir(), "secret")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "license.lic"), data, 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chmod(dir, 0755)
+	})
+	return dir
+}
+
+// assertUnchanged 挂载目录中只有license.lic且内容未变
+func assertUnchanged(t *testing.T, dir string, data []byte) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "license.lic" {
+		var names = make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Fatalf("files written to the secret mount: %v", names)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "license.lic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(data) {
+		t.Fatal("license.lic in the secret mount was rewritten")
+	}
+}
+
+// TestSecretDirSourceReadOnly 只读挂载目录中的License可以校验与注册节点，运行状态保存在内存中
+func TestSecretDirSourceReadOnly(t *testing.T) {
+	signed, store := signedLicense(t)
+	unsigned, err := Utils.SealLicense(testLicense(t), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name  string
+		data  []byte
+		store bool
+	}{{"signed", signed, true}, {"unsigned", unsigned, false}} {
+		t.Run(c.name, func(t *testing.T) {
+			var dir = secretDir(t, c.data)
+			var client = &Client{Offset: testOffset, Step: testStep, Locale: I18n.ZH, Source: SecretDirSource{Dir: dir}}
+			if c.store {
+				client.TrustStore = store
+			}
+			if !client.DefaultLic() {
+				t.Fatal("license check failed")
+			}
+			if err := client.saveCheckTime(time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.RegisterNodeToLicense(&Entity.NodeInfo{NodeName: "a"}, ""); err != nil {
+				t.Fatal(err)
+			}
+			lic, err := client.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lic.UseNodes != 1 || lic.LastCheckTime == nil {
+				t.Errorf("in-memory state lost on reload: UseNodes=%d LastCheckTime=%v", lic.UseNodes, lic.LastCheckTime)
+			}
+			assertUnchanged(t, dir, c.data)
+		})
+	}
+}
+
+// TestSecretDirSourceStatePath 设置StatePath与AuditPath时运行状态与审计日志写在指定路径
+func TestSecretDirSourceStatePath(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = secretDir(t, data)
+	var writable = t.TempDir()
+	var client = &Client{
+		Offset:     testOffset,
+		Step:       testStep,
+		Locale:     I18n.ZH,
+		TrustStore: store,
+		Source:     SecretDirSource{Dir: dir},
+		StatePath:  filepath.Join(writable, "license.state"),
+		AuditPath:  filepath.Join(writable, "license.audit"),
+	}
+	if !client.DefaultLic() {
+		t.Fatal("license check failed")
+	}
+	for _, path := range []string{client.StatePath, client.AuditPath, client.AuditPath + ".chain"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s not written: %v", filepath.Base(path), err)
+		}
+	}
+	assertUnchanged(t, dir, data)
+}
+
+func TestEnvSource(t *testing.T) {
+	var data = []byte("license data")
+	t.Setenv("ELSTLIC_TEST_LICENSE", "  "+base64.StdEncoding.EncodeToString(data)+"\n")
+	var source = EnvSource("ELSTLIC_TEST_LICENSE")
+	got, err := source.Read()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	if source.String() != "env:ELSTLIC_TEST_LICENSE" {
+		t.Errorf("String = %q", source.String())
+	}
+
+	t.Setenv("ELSTLIC_TEST_EMPTY", "")
+	for _, name := range []string{"ELSTLIC_TEST_EMPTY", "ELSTLIC_TEST_UNSET"} {
+		if _, err = EnvSource(name).Read(); I18n.Code(err) != I18n.CodeEnvNotSet {
+			t.Errorf("%s Read error = %v, want %s", name, err, I18n.CodeEnvNotSet)
+		}
+	}
+	t.Setenv("ELSTLIC_TEST_LICENSE", "not*base64")
+	if _, err = source.Read(); err == nil {
+		t.Error("Read accepted invalid base64")
+	}
+}
+
+// TestBytesSource 每次读取返回副本，调用方修改不影响来源
+func TestBytesSource(t *testing.T) {
+	var source = BytesSource("license data")
+	got, err := source.Read()
+	if err != nil || string(got) != "license data" {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	got[0] = 'X'
+	if again, _ := source.Read(); string(again) != "license data" {
+		t.Errorf("source modified through a read: %q", again)
+	}
+	if _, err = BytesSource(nil).Read(); I18n.Code(err) != I18n.CodeEmptyLicense {
+		t.Errorf("empty Read error = %v, want %s", err, I18n.CodeEmptyLicense)
+	}
+}
+
+// countingReader 记录Read调用次数
+type countingReader struct {
+	io.Reader
+	calls int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.calls++
+	return r.Reader.Read(p)
+}
+
+// TestReaderSource 只读取一次io.Reader，之后返回缓存内容的副本
+func TestReaderSource(t *testing.T) {
+	var reader = &countingReader{Reader: strings.NewReader("license data")}
+	var source = NewReaderSource(reader)
+	got, err := source.Read()
+	if err != nil || string(got) != "license data" {
+		t.Fatalf("Read = %q, %v", got, err)
+	}
+	var calls = reader.calls
+	got[0] = 'X'
+	again, err := source.Read()
+	if err != nil || string(again) != "license data" {
+		t.Errorf("second Read = %q, %v", again, err)
+	}
+	if reader.calls != calls {
+		t.Errorf("reader read again: %d calls, want %d", reader.calls, calls)
+	}
+
+	var failing = NewReaderSource(io.MultiReader(strings.NewReader("part"), errReader{}))
+	for i := 0; i < 2; i++ {
+		if data, err := failing.Read(); err == nil || data != nil {
+			t.Errorf("Read #%d = %q, %v, want the reader error", i+1, data, err)
+		}
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("device removed") }
+
+func TestSearchPathSource(t *testing.T) {
+	var dir = t.TempDir()
+	var missing, second, third = filepath.Join(dir, "missing.lic"), filepath.Join(dir, "second.lic"), filepath.Join(dir, "third.lic")
+	for path, content := range map[string]string{second: "second", third: "third"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var source = SearchPathSource{missing, second, third}
+	path, err := source.Path()
+	if err != nil || path != second {
+		t.Fatalf("Path = %q, %v, want %s", path, err, second)
+	}
+	if data, err := source.Read(); err != nil || string(data) != "second" {
+		t.Errorf("Read = %q, %v", data, err)
+	}
+	if path, _ = (SearchPathSource{third, second}).Path(); path != third {
+		t.Errorf("Path = %q, want the first existing path %s", path, third)
+	}
+
+	for _, source := range []SearchPathSource{{missing, filepath.Join(dir, "other.lic")}, {}} {
+		_, err = source.Read()
+		if I18n.Code(err) != I18n.CodeNoLicenseFile {
+			t.Fatalf("Read error = %v, want %s", err, I18n.CodeNoLicenseFile)
+		}
+		if len(source) > 0 && !strings.Contains(err.Error(), missing) {
+			t.Errorf("error %q does not list the searched paths", err)
+		}
+	}
+}
+
+// TestLoadNeverPrompts 未配置来源或来源中没有License时直接返回错误，不会读取标准输入提示输入路径
+func TestLoadNeverPrompts(t *testing.T) {
+	stdin, input, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = input.WriteString("prompted.lic\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = input.Close()
+	var saved = os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() {
+		os.Stdin = saved
+		_ = stdin.Close()
+	})
+
+	var client = &Client{Offset: testOffset, Step: testStep, Locale: I18n.ZH}
+	if _, err = client.Load(); err != ErrNoSource {
+		t.Errorf("Load error = %v, want ErrNoSource", err)
+	}
+	if _, err = client.DecryptDataFromFile(); err != ErrNoSource {
+		t.Errorf("DecryptDataFromFile error = %v, want ErrNoSource", err)
+	}
+	if client.DefaultLic() {
+		t.Error("DefaultLic succeeded without a source")
+	}
+	client.Source = SearchPathSource{filepath.Join(t.TempDir(), "license.lic")}
+	if _, err = client.Load(); I18n.Code(err) != I18n.CodeNoLicenseFile {
+		t.Errorf("Load error = %v, want %s", err, I18n.CodeNoLicenseFile)
+	}
+	if client.DefaultLic() {
+		t.Error("DefaultLic succeeded without a license file")
+	}
+	if _, ok := client.source().(*PromptSource); ok {
+		t.Error("source replaced by a prompt")
+	}
+
+	unread, err := io.ReadAll(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(unread) != "prompted.lic\n" {
+		t.Errorf("standard input consumed: %q left", unread)
+	}
+}
+
+// TestLoadFromEnvSource 环境变量中的License可以加载与校验
+func TestLoadFromEnvSource(t *testing.T) {
+	data, err := Utils.SealLicense(testLicense(t), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ELSTLIC_TEST_LICENSE", base64.StdEncoding.EncodeToString(data))
+	var client = &Client{Offset: testOffset, Step: testStep, Locale: I18n.ZH}
+	lic, err := client.LoadFrom(EnvSource("ELSTLIC_TEST_LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lic.CustomerTag != "ACME" {
+		t.Errorf("license = %+v", lic)
+	}
+	if !client.DefaultLic() {
+		t.Fatal("license check failed")
+	}
+}
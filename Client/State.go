@@ -0,0 +1,119 @@
+package Client
+
+import (
+	"errors"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"os"
+)
+
+// 签名License由签发方生成，客户端改写后签名即失效；环境变量、内存等来源也无法写回。
+// 这两种情况下运行状态（最后校验时间、已注册节点）单独加密保存在状态文件中，
+// 默认为 <license路径>.state；无法确定路径或来源只读（如SecretDirSource）且未设置StatePath时只保存在内存中，
+// 重新读取同一主板的License时沿用内存中的运行状态。
+// 状态文件在首次读取License时创建。删除状态文件可清空已注册节点与最后校验时间，
+// 因此已加载过校验时间、或审计链记录过校验通过后，状态文件缺失视为错误。
+
+// statePath 运行状态文件路径，为空表示不持久化
+func (c *Client) statePath() string {
+	if c.StatePath != "" {
+		return c.StatePath
+	}
+	if licPath, ok := c.writablePath(); ok {
+		return licPath + ".state"
+	}
+	return ""
+}
+
+// saveState 保存运行状态
+func (c *Client) saveState(lic *Entity.License) error {
+	var path = c.statePath()
+	if path == "" {
+		return nil
+	}
+	suite, err := Suite.Get(lic.CryptoSuite)
+	if err != nil {
+		return err
+	}
+	var state = &Entity.License{
+		MotherBoardID: lic.MotherBoardID,
+		LastCheckTime: lic.LastCheckTime,
+		UseNodes:      lic.UseNodes,
+		NodeList:      lic.NodeList,
+	}
+	offset, step := c.params()
+	var sealer = &Utils.Sealer{Offset: offset, Step: step, Suite: suite}
+	encrypt, err := sealer.Seal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encrypt, 0600)
+}
+
+// loadState 读取运行状态并合并到License，首次读取时创建状态文件
+func (c *Client) loadState(lic *Entity.License) error {
+	err := c.readState(lic)
+	if errors.Is(err, os.ErrNotExist) {
+		return c.initState(c.statePath(), lic)
+	}
+	return err
+}
+
+// readState 读取运行状态并合并到License，不持久化时沿用内存中的运行状态；
+// 状态文件不存在时返回 os.ErrNotExist
+func (c *Client) readState(lic *Entity.License) error {
+	var path = c.statePath()
+	if path == "" {
+		c.memoryState(lic)
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	offset, step := c.params()
+	state, _, err := Utils.OpenLicense(data, offset, step)
+	if err != nil {
+		return err
+	}
+	if state.MotherBoardID != lic.MotherBoardID {
+		return c.err(I18n.CodeStateMismatch)
+	}
+	lic.LastCheckTime = state.LastCheckTime
+	lic.UseNodes = state.UseNodes
+	lic.NodeList = state.NodeList
+	return nil
+}
+
+// initState 状态文件不存在时，确认License未校验过后以License中的初始值创建运行状态
+func (c *Client) initState(path string, lic *Entity.License) error {
+	c.mu.RLock()
+	var checked = c.license != nil && c.license.LastCheckTime != nil
+	c.mu.RUnlock()
+	if !checked {
+		var err error
+		checked, err = c.checkedBefore()
+		if err != nil {
+			return err
+		}
+	}
+	if checked {
+		return c.err(I18n.CodeStateMissing, path)
+	}
+	return c.saveState(lic)
+}
+
+// memoryState 不持久化运行状态时，沿用当前License中同一主板的运行状态
+func (c *Client) memoryState(lic *Entity.License) {
+	c.mu.RLock()
+	var current = c.license.Clone()
+	c.mu.RUnlock()
+	if current == nil || current.MotherBoardID != lic.MotherBoardID {
+		return
+	}
+	lic.LastCheckTime = current.LastCheckTime
+	lic.UseNodes = current.UseNodes
+	lic.NodeList = current.NodeList
+}
@@ -0,0 +1,123 @@
+package Client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+// signedClient 读取签名License的客户端，运行状态与审计日志保存在dir中
+func signedClient(t *testing.T, dir string, data []byte, store *Trust.Store) *Client {
+	t.Helper()
+	var path = filepath.Join(dir, "license.lic")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &Client{Offset: testOffset, Step: testStep, Locale: I18n.ZH, TrustStore: store, Source: FileSource(path)}
+}
+
+// signedLicense 以一次性签发密钥签名的License与对应的信任列表
+func signedLicense(t *testing.T) ([]byte, *Trust.Store) {
+	t.Helper()
+	signer, err := Signer.NewStubSigner("", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, signer.Public(), time.Now().Add(-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.ID = key.KeyID
+	var sealer = &Utils.Sealer{Offset: testOffset, Step: testStep, Signer: signer}
+	data, err := sealer.Seal(testLicense(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, &Trust.Store{Keys: []*Trust.Key{key}}
+}
+
+func TestStateCreatedOnFirstLoad(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = t.TempDir()
+	var client = signedClient(t, dir, data, store)
+	if _, err := client.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "license.lic.state")); err != nil {
+		t.Fatalf("state file not created: %v", err)
+	}
+}
+
+// TestStateMissingAfterCheck 进程内已有校验时间后删除状态文件，重新读取时报错而不是清空状态
+func TestStateMissingAfterCheck(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = t.TempDir()
+	var client = signedClient(t, dir, data, store)
+	if _, err := client.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.saveCheckTime(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "license.lic.state")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Load(); I18n.Code(err) != I18n.CodeStateMissing {
+		t.Fatalf("Load error = %v, want CodeStateMissing", err)
+	}
+}
+
+// TestStateMissingAfterRestart 重启后状态文件缺失，由审计链中校验通过的记录发现
+func TestStateMissingAfterRestart(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = t.TempDir()
+	var client = signedClient(t, dir, data, store)
+	if !client.DefaultLic() {
+		t.Fatal("license check failed")
+	}
+	if err := os.Remove(filepath.Join(dir, "license.lic.state")); err != nil {
+		t.Fatal(err)
+	}
+
+	var restarted = signedClient(t, dir, data, store)
+	if _, err := restarted.Load(); I18n.Code(err) != I18n.CodeStateMissing {
+		t.Fatalf("Load error = %v, want CodeStateMissing", err)
+	}
+	if restarted.DefaultLic() {
+		t.Fatal("license check passed without the state file")
+	}
+}
+
+// TestStateMissingBeforeSuccess 从未校验通过时没有状态文件不影响读取
+func TestStateMissingBeforeSuccess(t *testing.T) {
+	data, store := signedLicense(t)
+	var dir = t.TempDir()
+	// 要求签名时未签名的License被拒绝，审计链中只有失败记录
+	unsigned, err := Utils.SealLicense(testLicense(t), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = signedClient(t, dir, unsigned, store)
+	if client.DefaultLic() {
+		t.Fatal("unsigned license passed the check")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "license.lic.audit.chain")); err != nil {
+		t.Fatalf("audit chain not written: %v", err)
+	}
+
+	client = signedClient(t, dir, data, store)
+	if _, err = client.Load(); err != nil {
+		t.Fatal(err)
+	}
+}
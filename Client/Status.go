@@ -0,0 +1,126 @@
+package Client
+
+import (
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"time"
+)
+
+var (
+	ErrClockRollback error = I18n.E(I18n.CodeClockRollback) // 检测到系统时间被回拨
+	ErrCheckFailed   error = I18n.E(I18n.CodeCheckFailed)   // 校验函数返回未通过
+)
+
+// 校验项
+const (
+	CheckLoad        = "load"        // 读取、解密与签名校验
+	CheckProduct     = "product"     // 产品ID比对
+	CheckMotherBoard = "motherboard" // 主板ID比对
+	CheckValidity    = "validity"    // 有效期
+	CheckClock       = "clock"       // 系统时间回拨检测
+)
+
+// Status 最近一次校验是否通过
+func (c *Client) Status() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.checkStatus
+}
+
+// Snapshot 当前License的副本，尚未加载License时返回零值
+func (c *Client) Snapshot() Entity.License {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.license == nil {
+		return Entity.License{}
+	}
+	return *c.license.Clone()
+}
+
+// setStatus 记录一次校验结果并写入审计日志，状态变化时发送 EventStatusChanged 事件
+func (c *Client) setStatus(status bool, reason error) {
+	c.mu.Lock()
+	var changed = c.checkStatus != status
+	c.checkStatus = status
+	c.statusErr = reason
+	c.checkSeq++
+	var failed = c.lastFailed
+	c.lastFailed = ""
+	if status {
+		c.lastSuccess = time.Now()
+		failed = ""
+	}
+	if c.license != nil && c.license.CheckStatus != status {
+		var license = c.license.Clone()
+		license.CheckStatus = status
+		c.license = license
+	}
+	c.mu.Unlock()
+	c.audit(status, failed, reason)
+	if changed {
+		c.emit(Event{Type: EventStatusChanged, Time: time.Now(), Err: reason})
+	}
+}
+
+// failure 最近一次校验未通过的原因，自定义校验函数未给出原因时为 ErrCheckFailed
+func (c *Client) failure() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.statusErr == nil {
+		return ErrCheckFailed
+	}
+	return c.statusErr
+}
+
+// checkCount 已记录的校验结果数
+func (c *Client) checkCount() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.checkSeq
+}
+
+// lastCheckTime 当前License的最后校验时间
+func (c *Client) lastCheckTime() *time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.license == nil || c.license.LastCheckTime == nil {
+		return nil
+	}
+	var t = *c.license.LastCheckTime
+	return &t
+}
+
+// touch 记录校验时间并返回更新后License的副本，未加载License时返回空
+func (c *Client) touch(now time.Time) *Entity.License {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.license == nil {
+		return nil
+	}
+	var license = c.license.Clone()
+	license.LastCheckTime = &now
+	c.license = license
+	return license.Clone()
+}
+
+// recordCheck 记录单项校验结果并原样返回err
+func (c *Client) recordCheck(check string, err error) error {
+	c.mu.Lock()
+	if c.checks == nil {
+		c.checks = make(map[string]bool)
+	}
+	c.checks[check] = err == nil
+	if check == CheckClock && err != nil {
+		c.clockRollbacks++
+	}
+	if err != nil {
+		c.lastFailed = check
+	}
+	var fields = append(Logger.License(c.license), Logger.F(Logger.FieldCheck, check))
+	c.mu.Unlock()
+	if err != nil {
+		c.logger().Warn(c.text(I18n.MsgLicenseRejected), append(fields, Logger.Err(err))...)
+	}
+	return err
+}
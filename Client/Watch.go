@@ -0,0 +1,205 @@
+package Client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventType 客户端事件类型
+type EventType string
+
+const (
+	EventLicenseReloaded EventType = "LicenseReloaded"     // 新License校验通过并已替换
+	EventReloadFailed    EventType = "LicenseReloadFailed" // 新License校验失败，继续使用旧License
+	EventStatusChanged   EventType = "StatusChanged"       // 校验状态变化，失败时Err为原因
+)
+
+// Event 客户端事件
+type Event struct {
+	Type   EventType
+	Time   time.Time
+	Source string
+	Old    *Entity.License     // 替换前的License
+	New    *Entity.License     // 新License，校验失败时为空
+	Diff   []Utils.FieldChange // 授权条款变化
+	Err    error               // 校验失败原因
+	Status bool                // 事件发生后的校验状态
+}
+
+// OnEvent 注册事件处理函数，处理函数在触发事件的goroutine中同步调用
+func (c *Client) OnEvent(handler func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// Subscribe 订阅客户端事件，返回事件通道与取消订阅函数。
+// 通道带缓冲，接收方处理不及时时丢弃新事件，不会阻塞校验流程；取消订阅后通道关闭。
+func (c *Client) Subscribe() (<-chan Event, func()) {
+	var ch = make(chan Event, 16)
+	c.mu.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan Event]struct{})
+	}
+	c.subscribers[ch] = struct{}{}
+	c.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			delete(c.subscribers, ch)
+			close(ch)
+			c.mu.Unlock()
+		})
+	}
+}
+
+func (c *Client) emit(event Event) {
+	c.mu.RLock()
+	event.Status = c.checkStatus
+	var handlers = append([]func(Event){}, c.handlers...)
+	for ch := range c.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	c.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// WatchLicense 按interval轮询License来源，内容变化时校验并热加载新License。
+// 文件类来源先比较修改时间与大小，其它来源比较内容摘要。返回的函数用于停止轮询。
+func (c *Client) WatchLicense(interval time.Duration) (stop func()) {
+	var done = make(chan struct{})
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = c.Reload()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Reload 检查License来源是否变化，变化时校验新License，通过后原子替换。
+// 校验失败时保留旧License并返回错误；同一份失败内容只报告一次。
+func (c *Client) Reload() error {
+	var source = c.source()
+	if source == nil {
+		return ErrNoSource
+	}
+	c.fileMu.Lock()
+	old, lic, changed, err := c.reload(source)
+	c.fileMu.Unlock()
+	if !changed {
+		return err
+	}
+	if err == nil {
+		c.setStatus(true, nil)
+	}
+	c.reloaded(source, old, lic, err)
+	return err
+}
+
+// reloaded 记录热加载结果并发送 EventLicenseReloaded 或 EventReloadFailed 事件
+func (c *Client) reloaded(source LicenseSource, old, lic *Entity.License, err error) {
+	if err != nil {
+		c.audit(false, c.takeFailed(), err)
+		c.emit(Event{Type: EventReloadFailed, Time: time.Now(), Source: source.String(), Old: old, Err: err})
+		return
+	}
+	c.logger().Info(c.text(I18n.MsgLicenseReloaded), append(Logger.License(lic), Logger.F(Logger.FieldSource, source.String()))...)
+	c.emit(Event{
+		Type:   EventLicenseReloaded,
+		Time:   time.Now(),
+		Source: source.String(),
+		Old:    old,
+		New:    lic,
+		Diff:   Utils.DiffLicense(old, lic),
+	})
+}
+
+// reload 读取并校验变化的License，通过后替换当前License，调用方需持有fileMu。
+// changed为false表示内容未变化或读取失败，此时不发送事件。
+func (c *Client) reload(source LicenseSource) (old, lic *Entity.License, changed bool, err error) {
+	var stamp = c.sourceStamp()
+	c.mu.RLock()
+	var unchanged = stamp != "" && stamp == c.lastStamp
+	c.mu.RUnlock()
+	if unchanged {
+		return nil, nil, false, nil
+	}
+	ciphertext, err := source.Read()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	var digest = sha256.Sum256(ciphertext)
+	c.mu.Lock()
+	c.lastStamp = stamp
+	if digest == c.lastDigest {
+		c.mu.Unlock()
+		return nil, nil, false, nil
+	}
+	c.lastDigest = digest
+	old = c.license.Clone()
+	c.mu.Unlock()
+
+	lic, signed, err := c.open(ciphertext)
+	c.recordCheck(CheckLoad, err)
+	if err == nil {
+		err = c.verifyLicense(lic)
+	}
+	if err != nil {
+		c.mu.Lock()
+		c.loadErr = err
+		c.mu.Unlock()
+		return old, nil, true, err
+	}
+	c.mu.Lock()
+	c.license = lic.Clone()
+	c.signed = signed
+	c.loadErr = nil
+	c.mu.Unlock()
+	return old, lic, true, nil
+}
+
+// sourceStamp 文件类来源的修改时间与大小，其它来源返回空
+func (c *Client) sourceStamp() string {
+	path, ok := c.sourcePath()
+	if !ok {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%d|%d", path, info.ModTime().UnixNano(), info.Size())
+}
+
+// remember 记录当前License内容，避免自身写回被识别为外部更新
+func (c *Client) remember(ciphertext []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastDigest = sha256.Sum256(ciphertext)
+	c.lastStamp = ""
+}
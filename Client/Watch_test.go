@@ -0,0 +1,102 @@
+package Client
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+// replaceLicense 替换License文件并修改其修改时间，确保轮询能识别变化
+func replaceLicense(t *testing.T, path string, lic *Entity.License, version int) {
+	t.Helper()
+	writeLicense(t, path, lic)
+	var mtime = time.Now().Add(time.Duration(version) * time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nextEvent 读取下一个指定类型的事件
+func nextEvent(t *testing.T, events <-chan Event, typ EventType) Event {
+	t.Helper()
+	for {
+		select {
+		case event := <-events:
+			if event.Type == typ {
+				return event
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+// TestDefaultLicKeepsOldLicense 新License校验失败时继续使用旧License，校验通过后替换
+func TestDefaultLicKeepsOldLicense(t *testing.T) {
+	client, path := newTestClient(t, testLicense(t))
+	if !client.DefaultLic() {
+		t.Fatal("initial license check failed")
+	}
+	events, cancel := client.Subscribe()
+	defer cancel()
+
+	var expired = testLicense(t)
+	expired.AllowNodes = 99
+	expired.EndTime = Timestamp.Format(time.Now().Add(-time.Minute))
+	replaceLicense(t, path, expired, 1)
+	if !client.DefaultLic() {
+		t.Fatal("DefaultLic failed while the old license is still valid")
+	}
+	var failed = nextEvent(t, events, EventReloadFailed)
+	if I18n.Code(failed.Err) != I18n.CodeExpired {
+		t.Errorf("ReloadFailed error = %v, want CodeExpired", failed.Err)
+	}
+	if snapshot := client.Snapshot(); snapshot.AllowNodes != 64 {
+		t.Fatalf("Snapshot().AllowNodes = %d, want old license", snapshot.AllowNodes)
+	}
+
+	var renewed = testLicense(t)
+	renewed.AllowNodes = 128
+	replaceLicense(t, path, renewed, 2)
+	if !client.DefaultLic() {
+		t.Fatal("renewed license check failed")
+	}
+	var reloaded = nextEvent(t, events, EventLicenseReloaded)
+	if reloaded.Old.AllowNodes != 64 || reloaded.New.AllowNodes != 128 {
+		t.Errorf("reload event Old=%d New=%d", reloaded.Old.AllowNodes, reloaded.New.AllowNodes)
+	}
+	var changed bool
+	for _, change := range reloaded.Diff {
+		changed = changed || change.Field == "allow_nodes"
+	}
+	if !changed {
+		t.Errorf("reload diff %+v has no allow_nodes change", reloaded.Diff)
+	}
+	if snapshot := client.Snapshot(); snapshot.AllowNodes != 128 {
+		t.Fatalf("Snapshot().AllowNodes = %d, want renewed license", snapshot.AllowNodes)
+	}
+}
+
+// TestDefaultLicInvalidLicense 没有可用License时报告失败而不是退出进程
+func TestDefaultLicInvalidLicense(t *testing.T) {
+	var expired = testLicense(t)
+	expired.EndTime = Timestamp.Format(time.Now().Add(-time.Minute))
+	client, _ := newTestClient(t, expired)
+	events, cancel := client.Subscribe()
+	defer cancel()
+	if client.DefaultLic() || client.Status() {
+		t.Fatal("expired license passed the check")
+	}
+	nextEvent(t, events, EventReloadFailed)
+	// 同一份无效内容再次校验仍报告失败原因
+	if client.DefaultLic() {
+		t.Fatal("expired license passed the second check")
+	}
+	if snapshot := client.Snapshot(); snapshot.EndTime != "" {
+		t.Errorf("invalid license was installed: %+v", snapshot)
+	}
+}
@@ -1,24 +1,51 @@
 package Entity
 
-import "time"
+import (
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"time"
+)
 
 // License 授权信息列表 包括：授权起始时间、授权到期时间、允许节点数量、MAC地址列表、主板ID
 type License struct {
-	StartTime         string      `json:"start_time"`          // 开始时间，格式为：YYYY-MM-ddTHH:mm:SS
-	EndTime           string      `json:"end_time"`            // 到期时间，格式为：YYYY-MM-ddTHH:mm:SS
-	ClientTimeZone    string      `json:"client_time_zone"`    // 客户端时区
-	LicenseCreateTime string      `json:"license_create_time"` // License创建时间
-	AllowNodes        int         `json:"allow_nodes"`         // 允许接入的计算节点数
-	UseNodes          int         `json:"use_nodes"`           // 已接入计算节点数
-	MacAddr           string      `json:"mac_addr"`            // 授权的管理节点MAC地址
-	MotherBoardID     string      `json:"mother_board_id"`     // 授权的管理节点主板编号
-	PermanentAuth     bool        `json:"permanent_auth"`      // 永久授权
-	CustomerTag       string      `json:"customer_tag"`        // 客户标记
-	ModelRoute        string      `json:"model_route"`         // 模块路由Prefix
-	CheckCode         string      `json:"check_code"`          // 校验码
-	LastCheckTime     *time.Time  `json:"last_check_time"`     // 最后一次校验时间
-	CheckStatus       bool        `json:"check_status"`        // 校验状态
-	NodeList          []*NodeInfo `json:"node_list"`           // 节点列表
+	StartTime         string          `json:"start_time"`               // 开始时间，RFC 3339格式的UTC时间；旧版本为不带时区的YYYY-MM-ddTHH:mm:SS
+	EndTime           string          `json:"end_time"`                 // 到期时间，格式同StartTime
+	ClientTimeZone    string          `json:"client_time_zone"`         // 客户端时区，IANA名称或UTC+08:00形式的偏移，用于解析旧格式时间
+	LicenseCreateTime string          `json:"license_create_time"`      // License创建时间，格式同StartTime
+	MigratedFrom      string          `json:"migrated_from,omitempty"`  // 迁移前的License创建时间，迁移时LicenseCreateTime更新为迁移时间
+	AllowNodes        int             `json:"allow_nodes"`              // 允许接入的计算节点数
+	UseNodes          int             `json:"use_nodes"`                // 已接入计算节点数
+	MacAddr           string          `json:"mac_addr"`                 // 授权的管理节点MAC地址
+	MotherBoardID     string          `json:"mother_board_id"`          // 授权的管理节点主板编号
+	PermanentAuth     bool            `json:"permanent_auth"`           // 永久授权
+	CustomerTag       string          `json:"customer_tag"`             // 客户标记
+	ModelRoute        string          `json:"model_route"`              // 模块路由Prefix
+	CheckCode         string          `json:"check_code"`               // 校验码
+	LastCheckTime     *time.Time      `json:"last_check_time"`          // 最后一次校验时间
+	CheckStatus       bool            `json:"check_status"`             // 校验状态
+	NodeList          []*NodeInfo     `json:"node_list"`                // 节点列表
+	CryptoSuite       string          `json:"crypto_suite,omitempty"`   // 签发时使用的算法套件
+	ProductID         string          `json:"product_id,omitempty"`     // 产品ID，客户端只接受本产品的License
+	Features          []string        `json:"features,omitempty"`       // 授权的功能特性
+	ResellerChain     []*ResellerCert `json:"reseller_chain,omitempty"` // 经销商证书链，由经销商签发时存在
+}
+
+// ResellerCert 经销商证书，由上级签发密钥签名，授权经销商在限额内签发License。
+// 证书链中第一个证书的密钥签发License，每个证书由下一个证书的密钥签名，最后一个证书由厂商根密钥签名。
+type ResellerCert struct {
+	Serial         string   `json:"serial"`          // 证书编号
+	Reseller       string   `json:"reseller"`        // 经销商名称
+	KeyID          string   `json:"key_id"`          // 经销商签发密钥ID
+	Suite          string   `json:"suite"`           // 经销商签发密钥算法套件
+	PublicKey      []byte   `json:"public_key"`      // 经销商签发公钥（DER）
+	Features       []string `json:"features"`        // 允许授权的功能特性
+	MaxNodes       int      `json:"max_nodes"`       // 单个License允许的最大节点数，0表示不限
+	MaxDays        int      `json:"max_days"`        // 单个License最长授权天数，0表示不限
+	AllowPermanent bool     `json:"allow_permanent"` // 是否允许签发永久授权
+	NotBefore      string   `json:"not_before"`      // 证书生效时间，RFC 3339格式的UTC时间；旧版本为不带时区的YYYY-MM-ddTHH:mm:SS
+	NotAfter       string   `json:"not_after"`       // 证书失效时间，格式同NotBefore
+	IssuerKeyID    string   `json:"issuer_key_id"`   // 上级签发密钥ID
+	IssuerSuite    string   `json:"issuer_suite"`    // 上级签发密钥算法套件
+	Signature      []byte   `json:"signature,omitempty"`
 }
 
 // NodeInfo 节点信息，记录仪授权的节点的基础信息
@@ -36,3 +63,138 @@ type NetCard struct {
 	MAC  string
 	IP   string
 }
+
+// StartAt 开始时间，旧格式按ClientTimeZone解析
+func (l *License) StartAt() (time.Time, error) {
+	return Timestamp.Parse(l.StartTime, l.ClientTimeZone)
+}
+
+// EndAt 到期时间，旧格式按ClientTimeZone解析
+func (l *License) EndAt() (time.Time, error) {
+	return Timestamp.Parse(l.EndTime, l.ClientTimeZone)
+}
+
+// IssuedAt License签发时间，旧格式由签发端按本地时间生成
+func (l *License) IssuedAt() (time.Time, error) {
+	return Timestamp.Parse(l.LicenseCreateTime, "")
+}
+
+// Term 授权期限，自开始时间与签发时间中较晚者起算。续期的License保留原开始时间，
+// 签发时间为续期时间，期限自续期时起算
+func (l *License) Term() (time.Duration, error) {
+	start, err := l.StartAt()
+	if err != nil {
+		return 0, err
+	}
+	end, err := l.EndAt()
+	if err != nil {
+		return 0, err
+	}
+	if issued, err := l.IssuedAt(); err == nil && issued.After(start) {
+		start = issued
+	}
+	return end.Sub(start), nil
+}
+
+// Clone 深拷贝License，副本可在其它goroutine中安全读写
+func (l *License) Clone() *License {
+	if l == nil {
+		return nil
+	}
+	var clone = *l
+	if l.LastCheckTime != nil {
+		var lastCheckTime = *l.LastCheckTime
+		clone.LastCheckTime = &lastCheckTime
+	}
+	if l.NodeList != nil {
+		clone.NodeList = make([]*NodeInfo, len(l.NodeList))
+		for i, node := range l.NodeList {
+			if node != nil {
+				var n = *node
+				clone.NodeList[i] = &n
+			}
+		}
+	}
+	if l.Features != nil {
+		clone.Features = append([]string{}, l.Features...)
+	}
+	if l.ResellerChain != nil {
+		clone.ResellerChain = make([]*ResellerCert, len(l.ResellerChain))
+		for i, cert := range l.ResellerChain {
+			if cert != nil {
+				var c = *cert
+				c.PublicKey = append([]byte(nil), cert.PublicKey...)
+				c.Features = append([]string(nil), cert.Features...)
+				c.Signature = append([]byte(nil), cert.Signature...)
+				clone.ResellerChain[i] = &c
+			}
+		}
+	}
+	return &clone
+}
+
+// AuditRecord 客户端校验审计记录，按Seq递增并以Hash串联成链
+type AuditRecord struct {
+	Seq            uint64    `json:"seq"`              // 序号，从1开始连续递增
+	Time           time.Time `json:"time"`             // 校验时间
+	Result         bool      `json:"result"`           // 校验是否通过
+	Check          string    `json:"check,omitempty"`  // 未通过的校验项
+	Error          string    `json:"error,omitempty"`  // 未通过原因
+	Serial         string    `json:"serial,omitempty"` // License序列号
+	HardwareDigest string    `json:"hardware_digest"`  // 主板ID与网卡MAC的摘要
+	Prev           string    `json:"prev"`             // 上一条记录的Hash
+	Hash           string    `json:"hash,omitempty"`   // 本条记录的链式摘要
+}
+
+// AuditEntry 加密后的审计记录，审计日志文件每行一条；第一行为锚点，只有签发方能解密
+type AuditEntry struct {
+	Suite  string `json:"suite"`            // 加密使用的算法套件
+	Data   []byte `json:"data,omitempty"`   // 加密后的AuditRecord
+	KeyID  string `json:"key_id,omitempty"` // 锚点：加密初始密钥使用的签发公钥ID
+	Anchor []byte `json:"anchor,omitempty"` // 锚点：以签发公钥加密的初始审计密钥
+}
+
+// AuditBundle 客户端导出的审计日志包，由签发端校验
+type AuditBundle struct {
+	Version       int           `json:"version"`
+	CreatedAt     time.Time     `json:"created_at"`
+	MotherBoardID string        `json:"mother_board_id"` // 导出时客户端的主板ID
+	Entries       []*AuditEntry `json:"entries"`
+}
+
+// Fingerprint 硬件指纹采集结果
+type Fingerprint struct {
+	Provider string `json:"provider"`
+	Value    string `json:"value"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Diagnostics 客户端诊断信息，用于分析License校验失败的原因
+type Diagnostics struct {
+	CreatedAt      time.Time       `json:"created_at"`
+	Hostname       string          `json:"hostname"`
+	OS             string          `json:"os"`
+	Arch           string          `json:"arch"`
+	Fingerprints   []Fingerprint   `json:"fingerprints"`    // 全部硬件指纹来源的输出
+	NetCards       []NetCard       `json:"net_cards"`       // 网卡列表
+	License        *License        `json:"license"`         // 当前License，未加载时为空
+	LicenseError   string          `json:"license_error"`   // 读取License失败的原因
+	TimeZone       string          `json:"time_zone"`       // 本地时区名称
+	UTCOffset      int             `json:"utc_offset"`      // 本地时区与UTC的偏移秒数
+	LocalTime      string          `json:"local_time"`      // 采集时的本地时间，RFC 3339格式
+	Status         bool            `json:"status"`          // 总体校验状态
+	Checks         map[string]bool `json:"checks"`          // 各校验项最近一次结果
+	LastSuccess    *time.Time      `json:"last_success"`    // 最近一次校验通过时间
+	ClockRollbacks int             `json:"clock_rollbacks"` // 检测到系统时间回拨的次数
+}
+
+// SupportBundleVersion 诊断包格式版本
+const SupportBundleVersion = 1
+
+// SupportBundle 使用签发公钥加密的诊断包，只有签发方能解密
+type SupportBundle struct {
+	Version int    `json:"version"`
+	KeyID   string `json:"key_id"` // 加密使用的签发公钥ID
+	Suite   string `json:"suite"`
+	Data    []byte `json:"data"` // 加密后的Diagnostics
+}
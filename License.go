@@ -13,6 +13,13 @@ func NewClient(offSet, step int, devInfo string) *Client.Client {
 	}
 }
 
+// NewProductClient 创建指定产品的客户端，只接受该产品的License
+func NewProductClient(productID string, offSet, step int, devInfo string) *Client.Client {
+	var client = NewClient(offSet, step, devInfo)
+	client.ProductID = productID
+	return client
+}
+
 func NewServer(offSet, step int, devInfo string) *Server.Server {
 	return &Server.Server{
 		Offset:  offSet,
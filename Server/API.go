@@ -0,0 +1,504 @@
+package Server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openAPIDocument 管理接口的OpenAPI文档
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// API 签发管理REST服务，供CRM等系统代替交互式向导签发、续期、吊销、查询与下载License，并维护客户库。
+// 签发记录接口需要Server配置Inventory，客户接口需要配置Customers；
+// 接口路径以/api/v1/开头，除/openapi.json外均需Bearer令牌；
+// 根路径提供内嵌的网页控制台。
+type API struct {
+	Server *Server
+	Tokens map[string]string // 访问令牌到签发人名称的映射，签发人记录在台账中
+
+	mu sync.Mutex // 串行执行签发、续期与吊销
+}
+
+// IssueResponse 签发与续期的响应，License文件内容为base64编码
+type IssueResponse struct {
+	Record  *LicenseRecord `json:"record"`
+	License []byte         `json:"license"`
+}
+
+// PreviewRequest 预览请求
+type PreviewRequest struct {
+	NodeInfo []byte `json:"node_info"`
+}
+
+// RevokeRequest 吊销请求
+type RevokeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code       I18n.ID     `json:"code,omitempty"` // 错误码，与I18n错误码一致
+	Error      string      `json:"error"`
+	Violations []Violation `json:"violations,omitempty"` // 违反的签发策略
+}
+
+// Handler 管理接口的http.Handler，可直接用于httptest
+func (a *API) Handler() http.Handler {
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIDocument)
+	})
+	mux.HandleFunc("/api/v1/", a.serve)
+	mux.Handle("/", consoleHandler())
+	return mux
+}
+
+// ListenAndServe 在addr上启动管理接口
+func (a *API) ListenAndServe(addr string) error {
+	var server = &http.Server{
+		Addr:              addr,
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	a.Server.logger().Info(a.Server.text(I18n.MsgAPIStarted), Logger.F("addr", addr))
+	return server.ListenAndServe()
+}
+
+// serve 校验令牌并按路径分发请求
+func (a *API) serve(w http.ResponseWriter, r *http.Request) {
+	issuer, ok := a.authenticate(r)
+	if !ok {
+		a.fail(w, r, http.StatusUnauthorized, I18n.E(I18n.CodeUnauthorized))
+		return
+	}
+	var parts = strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/"), "/")
+	// 签发记录相关接口需要签发记录库，客户接口需要客户库
+	var needInventory = parts[0] == "licenses" || parts[0] == "revocations" ||
+		(parts[0] == "customers" && len(parts) == 3)
+	if needInventory && a.Server.Inventory == nil {
+		a.fail(w, r, http.StatusServiceUnavailable, I18n.E(I18n.CodeNoInventory))
+		return
+	}
+	if parts[0] == "customers" && a.Server.Customers == nil {
+		a.fail(w, r, http.StatusServiceUnavailable, I18n.E(I18n.CodeNoCustomerRegistry))
+		return
+	}
+	switch {
+	case len(parts) == 1 && parts[0] == "licenses":
+		a.route(w, r, map[string]func(){
+			http.MethodGet:  func() { a.search(w, r) },
+			http.MethodPost: func() { a.issue(w, r, issuer) },
+		})
+	case len(parts) == 1 && parts[0] == "preview":
+		a.route(w, r, map[string]func(){
+			http.MethodPost: func() { a.preview(w, r) },
+		})
+	case len(parts) == 1 && parts[0] == "plans":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.respond(w, http.StatusOK, a.plans()) },
+		})
+	case len(parts) == 1 && parts[0] == "revocations":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.revocations(w, r) },
+		})
+	case len(parts) == 2 && parts[0] == "licenses":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.get(w, r, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "licenses" && parts[2] == "download":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.download(w, r, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "licenses" && parts[2] == "renew":
+		a.route(w, r, map[string]func(){
+			http.MethodPost: func() { a.renew(w, r, parts[1], issuer) },
+		})
+	case len(parts) == 3 && parts[0] == "licenses" && parts[2] == "revoke":
+		a.route(w, r, map[string]func(){
+			http.MethodPost: func() { a.revoke(w, r, parts[1]) },
+		})
+	case len(parts) == 1 && parts[0] == "customers":
+		a.route(w, r, map[string]func(){
+			http.MethodGet:  func() { a.respond(w, http.StatusOK, a.Server.Customers.List(r.URL.Query().Get("q"))) },
+			http.MethodPost: func() { a.putCustomer(w, r, "") },
+		})
+	case len(parts) == 2 && parts[0] == "customers":
+		a.route(w, r, map[string]func(){
+			http.MethodGet:    func() { a.getCustomer(w, r, parts[1]) },
+			http.MethodPut:    func() { a.putCustomer(w, r, parts[1]) },
+			http.MethodDelete: func() { a.removeCustomer(w, r, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "customers" && parts[2] == "summary":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.customerSummary(w, r, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "customers" && parts[2] == "licenses":
+		a.route(w, r, map[string]func(){
+			http.MethodGet: func() { a.customerLicenses(w, r, parts[1]) },
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// route 按请求方法调用处理函数
+func (a *API) route(w http.ResponseWriter, r *http.Request, handlers map[string]func()) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		var methods = make([]string, 0, len(handlers))
+		for method := range handlers {
+			methods = append(methods, method)
+		}
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	handler()
+}
+
+// authenticate 校验Bearer令牌，返回令牌对应的签发人
+func (a *API) authenticate(r *http.Request) (string, bool) {
+	var token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return "", false
+	}
+	var issuer string
+	var ok = false
+	for candidate, name := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			issuer, ok = name, true
+		}
+	}
+	return issuer, ok
+}
+
+// preview 返回node.info中的硬件信息，不签发
+func (a *API) preview(w http.ResponseWriter, r *http.Request) {
+	var req = new(PreviewRequest)
+	if !a.decode(w, r, req) {
+		return
+	}
+	lic, err := a.Server.Preview(req.NodeInfo)
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	a.respond(w, http.StatusOK, lic)
+}
+
+// plans 可选的授权方案
+func (a *API) plans() []*Plan {
+	if a.Server.Plans == nil {
+		return make([]*Plan, 0)
+	}
+	return a.Server.Plans
+}
+
+func (a *API) issue(w http.ResponseWriter, r *http.Request, issuer string) {
+	var req = new(IssueRequest)
+	if !a.decode(w, r, req) {
+		return
+	}
+	req.Issuer = issuer
+	a.mu.Lock()
+	lic, data, err := a.Server.Issue(req)
+	a.mu.Unlock()
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	a.respondIssued(w, r, Logger.Serial(lic), data)
+}
+
+func (a *API) renew(w http.ResponseWriter, r *http.Request, serial, issuer string) {
+	var req = new(RenewRequest)
+	if !a.decode(w, r, req) {
+		return
+	}
+	req.Issuer = issuer
+	a.mu.Lock()
+	lic, data, err := a.Server.Renew(serial, req)
+	a.mu.Unlock()
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	a.respondIssued(w, r, Logger.Serial(lic), data)
+}
+
+func (a *API) respondIssued(w http.ResponseWriter, r *http.Request, serial string, data []byte) {
+	record, err := a.Server.Inventory.Get(serial)
+	if err != nil {
+		a.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	record.Data = nil
+	w.Header().Set("Location", "/api/v1/licenses/"+serial)
+	a.respond(w, http.StatusCreated, &IssueResponse{Record: record, License: data})
+}
+
+func (a *API) revoke(w http.ResponseWriter, r *http.Request, serial string) {
+	var req = new(RevokeRequest)
+	if !a.decode(w, r, req) {
+		return
+	}
+	a.mu.Lock()
+	record, err := a.Server.Revoke(serial, req.Reason)
+	a.mu.Unlock()
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	record.Data = nil
+	a.respond(w, http.StatusOK, record)
+}
+
+func (a *API) get(w http.ResponseWriter, r *http.Request, serial string) {
+	record, err := a.Server.Inventory.Get(serial)
+	if err != nil {
+		a.fail(w, r, http.StatusNotFound, err)
+		return
+	}
+	record.Data = nil
+	a.respond(w, http.StatusOK, record)
+}
+
+func (a *API) download(w http.ResponseWriter, r *http.Request, serial string) {
+	record, err := a.Server.Inventory.Get(serial)
+	if err != nil {
+		a.fail(w, r, http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="license.lic"`)
+	_, _ = w.Write(record.Data)
+}
+
+// search 查询参数：q、product、plan、issuer、customer_id、revoked、active、
+// expires_before（RFC 3339）、expires_within（天数）
+func (a *API) search(w http.ResponseWriter, r *http.Request) {
+	query, ok := a.query(w, r)
+	if !ok {
+		return
+	}
+	a.respond(w, http.StatusOK, a.Server.Inventory.Search(query))
+}
+
+// query 解析签发记录查询参数，失败时返回400
+func (a *API) query(w http.ResponseWriter, r *http.Request) (*InventoryQuery, bool) {
+	var values = r.URL.Query()
+	var query = &InventoryQuery{
+		Text:       values.Get("q"),
+		Product:    values.Get("product"),
+		Plan:       values.Get("plan"),
+		Issuer:     values.Get("issuer"),
+		CustomerID: values.Get("customer_id"),
+	}
+	if v := values.Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			a.fail(w, r, http.StatusBadRequest, I18n.E(I18n.CodeBadRequest, "active"))
+			return nil, false
+		}
+		query.Active = active
+	}
+	if v := values.Get("expires_within"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			a.fail(w, r, http.StatusBadRequest, I18n.E(I18n.CodeBadRequest, "expires_within"))
+			return nil, false
+		}
+		query.ExpiresBefore = time.Now().AddDate(0, 0, days)
+	}
+	if v := values.Get("revoked"); v != "" {
+		revoked, err := strconv.ParseBool(v)
+		if err != nil {
+			a.fail(w, r, http.StatusBadRequest, I18n.E(I18n.CodeBadRequest, "revoked"))
+			return nil, false
+		}
+		query.Revoked = &revoked
+	}
+	if v := values.Get("expires_before"); v != "" {
+		expiresBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			a.fail(w, r, http.StatusBadRequest, I18n.E(I18n.CodeBadRequest, "expires_before"))
+			return nil, false
+		}
+		query.ExpiresBefore = expiresBefore
+	}
+	return query, true
+}
+
+// revocations 已吊销License的序列号列表
+func (a *API) revocations(w http.ResponseWriter, r *http.Request) {
+	var revoked = true
+	var serials = make([]string, 0)
+	for _, record := range a.Server.Inventory.Search(&InventoryQuery{Revoked: &revoked}) {
+		serials = append(serials, record.Serial)
+	}
+	a.respond(w, http.StatusOK, serials)
+}
+
+// putCustomer id为空时新增客户（POST，ID已存在时返回409），否则更新客户（PUT），路径中的ID优先于请求体
+func (a *API) putCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	var customer = new(Customer)
+	if !a.decode(w, r, customer) {
+		return
+	}
+	var status, save = http.StatusCreated, a.Server.Customers.Add
+	if id != "" {
+		customer.ID = id
+		status, save = http.StatusOK, a.Server.Customers.Put
+	}
+	err := save(customer)
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/customers/"+customer.ID)
+	a.respond(w, status, customer)
+}
+
+func (a *API) getCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	customer, err := a.Server.Customers.Get(id)
+	if err != nil {
+		a.fail(w, r, http.StatusNotFound, err)
+		return
+	}
+	a.respond(w, http.StatusOK, customer)
+}
+
+func (a *API) removeCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	err := a.Server.Customers.Remove(id)
+	if err != nil {
+		a.fail(w, r, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) customerSummary(w http.ResponseWriter, r *http.Request, id string) {
+	summary, err := a.Server.CustomerSummary(id)
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	a.respond(w, http.StatusOK, summary)
+}
+
+// customerLicenses 客户的签发记录，查询参数同search
+func (a *API) customerLicenses(w http.ResponseWriter, r *http.Request, id string) {
+	query, ok := a.query(w, r)
+	if !ok {
+		return
+	}
+	records, err := a.Server.CustomerLicenses(id, query)
+	if err != nil {
+		a.failIssue(w, r, err)
+		return
+	}
+	a.respond(w, http.StatusOK, records)
+}
+
+// decode 解析JSON请求体，失败时返回400
+func (a *API) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	var decoder = json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		a.fail(w, r, http.StatusBadRequest, I18n.E(I18n.CodeBadRequest, err.Error()))
+		return false
+	}
+	return true
+}
+
+// policyCodes 请求格式正确但不允许签发的错误：违反签发策略、超出经销商授权、License已吊销或node.info已过期
+var policyCodes = map[I18n.ID]bool{
+	I18n.CodePolicyViolation: true,
+	I18n.CodePolicyProduct:   true,
+	I18n.CodePolicyEdition:   true,
+	I18n.CodePolicyFeature:   true,
+	I18n.CodePolicyMinNodes:  true,
+	I18n.CodePolicyMaxNodes:  true,
+	I18n.CodePolicyMaxDays:   true,
+	I18n.CodePolicyPermanent: true,
+	I18n.CodePlanCustomerTag: true,
+	I18n.CodeExceedsLimit:    true,
+	I18n.CodeLimitNodes:      true,
+	I18n.CodeLimitFeature:    true,
+	I18n.CodeLimitPermanent:  true,
+	I18n.CodeLimitDays:       true,
+	I18n.CodeCertNodes:       true,
+	I18n.CodeCertDays:        true,
+	I18n.CodeCertPermanent:   true,
+	I18n.CodeCertFeature:     true,
+	I18n.CodeCertExpired:     true,
+	I18n.CodeLicenseRevoked:  true,
+	I18n.CodeNodeInfoExpired: true,
+	I18n.CodeNodeInfoProduct: true,
+	I18n.CodeProductMismatch: true,
+}
+
+// unavailableCodes 签发端缺少相应配置的错误
+var unavailableCodes = map[I18n.ID]bool{
+	I18n.CodeNoInventory:        true,
+	I18n.CodeNoCustomerRegistry: true,
+	I18n.CodeNoLedger:           true,
+	I18n.CodeNoSigner:           true,
+	I18n.CodeProductSigner:      true,
+}
+
+// failIssue 签发类错误：记录不存在为404，违反签发策略为422，签发端未配置为503，
+// 其它请求内容错误（node.info无效、缺少到期时间、未知方案等）为400，未知错误为500
+func (a *API) failIssue(w http.ResponseWriter, r *http.Request, err error) {
+	var policyErr *PolicyError
+	switch code := I18n.Code(err); {
+	case code == "":
+		a.fail(w, r, http.StatusInternalServerError, err)
+	case code == I18n.CodeLicenseNotFound || code == I18n.CodeCustomerNotFound:
+		a.fail(w, r, http.StatusNotFound, err)
+	case code == I18n.CodeCustomerExists:
+		a.fail(w, r, http.StatusConflict, err)
+	case policyCodes[code] || errors.As(err, &policyErr):
+		a.fail(w, r, http.StatusUnprocessableEntity, err)
+	case unavailableCodes[code]:
+		a.fail(w, r, http.StatusServiceUnavailable, err)
+	default:
+		a.fail(w, r, http.StatusBadRequest, err)
+	}
+}
+
+// fail 输出错误响应，错误信息按Accept-Language翻译
+func (a *API) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var locale = a.Server.Locale
+	if lang := r.Header.Get("Accept-Language"); lang != "" {
+		locale = I18n.Parse(lang)
+	}
+	var resp = &ErrorResponse{Code: I18n.Code(err), Error: I18n.Localize(err, locale)}
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		resp.Violations = policyErr.Violations
+	}
+	if status >= http.StatusInternalServerError {
+		a.Server.logger().Error(a.Server.text(I18n.MsgAPIFailed), Logger.F(Logger.FieldPath, r.URL.Path), Logger.Err(err))
+	}
+	a.respond(w, status, resp)
+}
+
+func (a *API) respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
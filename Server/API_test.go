@@ -0,0 +1,342 @@
+package Server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+const testToken = "test-token"
+
+// newTestAPI 使用测试签发端的管理接口
+func newTestAPI(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	server, _ := newTestServer(t)
+	var api = &API{Server: server, Tokens: map[string]string{testToken: "alice"}}
+	var ts = httptest.NewServer(api.Handler())
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+// call 发送带令牌的请求，body不为空时编码为JSON，返回状态码与响应内容
+func call(t *testing.T, ts *httptest.Server, method, path string, body interface{}) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	switch v := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+// errorCode 解析错误响应中的错误码
+func errorCode(t *testing.T, data []byte) I18n.ID {
+	t.Helper()
+	var resp = new(ErrorResponse)
+	if err := json.Unmarshal(data, resp); err != nil {
+		t.Fatalf("decode error response %q: %v", data, err)
+	}
+	return resp.Code
+}
+
+// issue 通过接口签发License
+func issue(t *testing.T, ts *httptest.Server, req *IssueRequest) *IssueResponse {
+	t.Helper()
+	status, data := call(t, ts, http.MethodPost, "/api/v1/licenses", req)
+	if status != http.StatusCreated {
+		t.Fatalf("issue status = %d: %s", status, data)
+	}
+	var resp = new(IssueResponse)
+	if err := json.Unmarshal(data, resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestAPIAuth(t *testing.T) {
+	_, ts := newTestAPI(t)
+	for _, header := range []string{"", "Bearer wrong", testToken, "Basic " + testToken} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/licenses", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, resp.StatusCode)
+		}
+	}
+	resp, err := ts.Client().Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("openapi.json status = %d, want 200", resp.StatusCode)
+	}
+	if status, _ := call(t, ts, http.MethodGet, "/api/v1/licenses", nil); status != http.StatusOK {
+		t.Errorf("authorized status = %d, want 200", status)
+	}
+}
+
+func TestAPIIssue(t *testing.T) {
+	server, ts := newTestAPI(t)
+	var resp = issue(t, ts, &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 30})
+	if resp.Record.Issuer != "alice" || resp.Record.Data != nil {
+		t.Errorf("record = %+v", resp.Record)
+	}
+	lic, _, err := Utils.OpenLicense(resp.License, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lic.AllowNodes != 8 || lic.MotherBoardID != "MB-TEST" {
+		t.Errorf("issued license = %+v", lic)
+	}
+	if _, err = server.Inventory.Get(resp.Record.Serial); err != nil {
+		t.Errorf("issued license not in inventory: %v", err)
+	}
+}
+
+func TestAPIIssueErrors(t *testing.T) {
+	server, ts := newTestAPI(t)
+	server.Policy = &Policy{MaxNodes: 10}
+	var nodeInfo = newNodeInfo(t)
+	var cases = []struct {
+		name   string
+		body   interface{}
+		status int
+		code   I18n.ID
+	}{
+		{"malformed json", `{"node_info":`, http.StatusBadRequest, I18n.CodeBadRequest},
+		{"unknown field", `{"nodes":3}`, http.StatusBadRequest, I18n.CodeBadRequest},
+		{"bad node.info", &IssueRequest{NodeInfo: []byte("garbage"), AllowNodes: 8, Days: 30}, http.StatusBadRequest, ""},
+		{"no end time", &IssueRequest{NodeInfo: nodeInfo, AllowNodes: 8}, http.StatusBadRequest, I18n.CodeNoEndTime},
+		{"end before start", &IssueRequest{NodeInfo: nodeInfo, AllowNodes: 8, EndTime: time.Now().Add(-time.Hour)}, http.StatusBadRequest, I18n.CodeEndBeforeStart},
+		{"unknown plan", &IssueRequest{NodeInfo: nodeInfo, Issuance: Issuance{Plan: "gold"}}, http.StatusBadRequest, I18n.CodeUnknownPlan},
+		{"policy", &IssueRequest{NodeInfo: nodeInfo, AllowNodes: 100, Days: 30}, http.StatusUnprocessableEntity, I18n.CodePolicyViolation},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status, data := call(t, ts, http.MethodPost, "/api/v1/licenses", c.body)
+			if status != c.status {
+				t.Fatalf("status = %d, want %d: %s", status, c.status, data)
+			}
+			if code := errorCode(t, data); c.code != "" && code != c.code {
+				t.Fatalf("code = %q, want %q", code, c.code)
+			}
+		})
+	}
+
+	status, data := call(t, ts, http.MethodPost, "/api/v1/licenses", &IssueRequest{NodeInfo: nodeInfo, AllowNodes: 100, Days: 30})
+	var resp = new(ErrorResponse)
+	if err := json.Unmarshal(data, resp); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusUnprocessableEntity || len(resp.Violations) != 1 || resp.Violations[0].Rule != RuleMaxNodes {
+		t.Fatalf("policy violation response %d: %+v", status, resp)
+	}
+}
+
+func TestAPIRevoke(t *testing.T) {
+	_, ts := newTestAPI(t)
+	var serial = issue(t, ts, &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 30}).Record.Serial
+
+	status, data := call(t, ts, http.MethodPost, "/api/v1/licenses/"+serial+"/revoke", &RevokeRequest{Reason: "refund"})
+	if status != http.StatusOK {
+		t.Fatalf("revoke status = %d: %s", status, data)
+	}
+	var record = new(LicenseRecord)
+	if err := json.Unmarshal(data, record); err != nil {
+		t.Fatal(err)
+	}
+	if !record.Revoked || record.RevokeReason != "refund" {
+		t.Errorf("revoked record = %+v", record)
+	}
+
+	status, data = call(t, ts, http.MethodGet, "/api/v1/revocations", nil)
+	var serials []string
+	if err := json.Unmarshal(data, &serials); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || len(serials) != 1 || serials[0] != serial {
+		t.Errorf("revocations %d: %v", status, serials)
+	}
+
+	status, data = call(t, ts, http.MethodPost, "/api/v1/licenses/"+serial+"/renew", &RenewRequest{Days: 30})
+	if status != http.StatusUnprocessableEntity || errorCode(t, data) != I18n.CodeLicenseRevoked {
+		t.Errorf("renew revoked: %d %s", status, data)
+	}
+	status, data = call(t, ts, http.MethodPost, "/api/v1/licenses/unknown/revoke", &RevokeRequest{})
+	if status != http.StatusNotFound || errorCode(t, data) != I18n.CodeLicenseNotFound {
+		t.Errorf("revoke unknown: %d %s", status, data)
+	}
+	if status, _ = call(t, ts, http.MethodGet, "/api/v1/licenses/"+serial+"/revoke", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET revoke status = %d, want 405", status)
+	}
+}
+
+func TestAPIInventory(t *testing.T) {
+	server, ts := newTestAPI(t)
+	var issued = issue(t, ts, &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 30, CustomerTag: "ACME"})
+	var serial = issued.Record.Serial
+
+	status, data := call(t, ts, http.MethodGet, "/api/v1/licenses?q=acme&active=true", nil)
+	var records []*LicenseRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || len(records) != 1 || records[0].Serial != serial || records[0].Data != nil {
+		t.Fatalf("search %d: %s", status, data)
+	}
+	status, data = call(t, ts, http.MethodGet, "/api/v1/licenses?q=other", nil)
+	if status != http.StatusOK || strings.TrimSpace(string(data)) != "[]" {
+		t.Errorf("search without match %d: %s", status, data)
+	}
+	if status, data = call(t, ts, http.MethodGet, "/api/v1/licenses?expires_within=soon", nil); status != http.StatusBadRequest {
+		t.Errorf("bad query status = %d: %s", status, data)
+	}
+
+	status, data = call(t, ts, http.MethodGet, "/api/v1/licenses/"+serial, nil)
+	if status != http.StatusOK || !strings.Contains(string(data), serial) {
+		t.Errorf("get %d: %s", status, data)
+	}
+	status, data = call(t, ts, http.MethodGet, "/api/v1/licenses/"+serial+"/download", nil)
+	if status != http.StatusOK || !bytes.Equal(data, issued.License) {
+		t.Errorf("download %d: %d bytes", status, len(data))
+	}
+	status, data = call(t, ts, http.MethodGet, "/api/v1/licenses/unknown", nil)
+	if status != http.StatusNotFound || errorCode(t, data) != I18n.CodeLicenseNotFound {
+		t.Errorf("get unknown %d: %s", status, data)
+	}
+
+	server.Inventory = nil
+	status, data = call(t, ts, http.MethodGet, "/api/v1/licenses", nil)
+	if status != http.StatusServiceUnavailable || errorCode(t, data) != I18n.CodeNoInventory {
+		t.Errorf("no inventory %d: %s", status, data)
+	}
+}
+
+// TestAPICustomers POST只新增客户，已存在时返回409；PUT更新客户并保留创建时间
+func TestAPICustomers(t *testing.T) {
+	server, ts := newTestAPI(t)
+	registry, err := OpenCustomerRegistry(filepath.Join(t.TempDir(), "customers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Customers = registry
+
+	status, data := call(t, ts, http.MethodPost, "/api/v1/customers", &Customer{ID: "acme", Name: "ACME"})
+	if status != http.StatusCreated {
+		t.Fatalf("create %d: %s", status, data)
+	}
+	var created = new(Customer)
+	if err = json.Unmarshal(data, created); err != nil {
+		t.Fatal(err)
+	}
+
+	status, data = call(t, ts, http.MethodPost, "/api/v1/customers", &Customer{ID: "acme", Name: "Other"})
+	if status != http.StatusConflict || errorCode(t, data) != I18n.CodeCustomerExists {
+		t.Errorf("create existing %d: %s", status, data)
+	}
+	if customer, _ := registry.Get("acme"); customer.Name != "ACME" {
+		t.Errorf("existing customer overwritten by POST: %+v", customer)
+	}
+
+	status, data = call(t, ts, http.MethodPut, "/api/v1/customers/acme", &Customer{ID: "ignored", Name: "ACME Corp"})
+	if status != http.StatusOK {
+		t.Fatalf("update %d: %s", status, data)
+	}
+	customer, err := registry.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Name != "ACME Corp" || !customer.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("updated customer = %+v, created at %v", customer, created.CreatedAt)
+	}
+	if _, err = registry.Get("ignored"); err == nil {
+		t.Error("PUT used the ID from the request body")
+	}
+}
+
+// TestConsole 网页控制台的静态文件来自内嵌文件，无需令牌
+func TestConsole(t *testing.T) {
+	_, ts := newTestAPI(t)
+	for path, c := range map[string]struct {
+		file, contentType string
+	}{
+		"/":          {"web/index.html", "text/html"},
+		"/app.js":    {"web/app.js", "javascript"},
+		"/style.css": {"web/style.css", "text/css"},
+	} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := ts.Client().Get(ts.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := console.ReadFile(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK || !bytes.Equal(data, want) {
+				t.Fatalf("GET %s = %d, %d bytes, want %s", path, resp.StatusCode, len(data), c.file)
+			}
+			if !strings.Contains(resp.Header.Get("Content-Type"), c.contentType) {
+				t.Errorf("Content-Type = %q, want %s", resp.Header.Get("Content-Type"), c.contentType)
+			}
+		})
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/missing.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing.js = %d, want 404", resp.StatusCode)
+	}
+}
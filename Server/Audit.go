@@ -0,0 +1,206 @@
+package Server
+
+import (
+	"crypto"
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/Audit"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// exportSkew 导出记录与日志包生成时间允许的最大间隔
+const exportSkew = time.Minute
+
+// AuditReport 审计日志校验结果
+type AuditReport struct {
+	MotherBoardID string                `json:"mother_board_id"`
+	AnchorID      string                `json:"anchor_id"`   // 日志起点的锚点标识
+	AnchoredAt    time.Time             `json:"anchored_at"` // 客户端开始记录审计日志的时间
+	ExportedAt    time.Time             `json:"exported_at"` // 最后一条导出记录的时间，应与索取日志的时间相符
+	Records       []*Entity.AuditRecord `json:"records"`     // 可解密的记录
+	Problems      []Audit.Problem       `json:"problems"`    // 发现的删除、修改、时间回拨等问题
+}
+
+// OK 审计链完整且未发现问题
+func (r *AuditReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// VerifyAudit 使用密钥环中的签发私钥解密审计日志锚点，校验客户端 ExportAudit 导出的审计日志包。
+// passphrase为空时交互式输入。
+func (s *Server) VerifyAudit(bundle []byte, ringPath string, passphrase []byte) (*AuditReport, error) {
+	parsed, err := Audit.ParseBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return nil, err
+	}
+	var keys = make(map[string]crypto.Signer)
+	var first *Audit.Anchor
+	var openAnchor = func(entry *Entity.AuditEntry) ([]byte, error) {
+		key, ok := keys[entry.KeyID]
+		if !ok {
+			passphrase, err = readPassphrase(passphrase, s.text(I18n.PromptPassphrase), false)
+			if err != nil {
+				return nil, err
+			}
+			key, err = ring.Private(entry.KeyID, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			keys[entry.KeyID] = key
+		}
+		anchor, err := Audit.OpenAnchor(entry, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == parsed.Entries[0] {
+			first = anchor
+		}
+		return anchor.Seed, nil
+	}
+	records, problems := Audit.Verify(parsed.Entries, openAnchor, s.Locale)
+	var report = &AuditReport{MotherBoardID: parsed.MotherBoardID, Records: records, Problems: problems}
+	if first != nil {
+		report.AnchorID, report.AnchoredAt = Audit.AnchorID(parsed.Entries[0]), first.CreatedAt
+		err = s.pinAuditAnchor(report)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if n := len(records); n > 0 && records[n-1].Check == Audit.CheckExport {
+		report.ExportedAt = records[n-1].Time
+		// 截断到更早的导出记录时，导出时间与日志包生成时间不符
+		if parsed.CreatedAt.Sub(report.ExportedAt) > exportSkew {
+			report.Problems = append(report.Problems, Audit.Problem{Index: len(parsed.Entries) - 1, Seq: records[n-1].Seq, Kind: Audit.ProblemTruncated, Detail: s.text(I18n.MsgAuditStaleExport)})
+		}
+	}
+	if !report.OK() {
+		s.logger().Warn(s.text(I18n.MsgAuditProblems), Logger.F("mother_board_id", parsed.MotherBoardID), Logger.F("problems", len(report.Problems)))
+	}
+	return report, nil
+}
+
+// pinAuditAnchor 登记日志起点，主板或License已登记其他锚点时说明日志被整体丢弃并重新生成
+func (s *Server) pinAuditAnchor(report *AuditReport) error {
+	if s.AuditAnchors == nil {
+		return nil
+	}
+	var subjects = make([]string, 0)
+	if report.MotherBoardID != "" {
+		subjects = append(subjects, "mother_board:"+report.MotherBoardID)
+	}
+	var seen = make(map[string]bool)
+	for _, rec := range report.Records {
+		if rec.Serial != "" && !seen[rec.Serial] {
+			seen[rec.Serial] = true
+			subjects = append(subjects, "serial:"+rec.Serial)
+		}
+	}
+	conflicts, err := s.AuditAnchors.Pin(subjects, &AuditAnchor{AnchorID: report.AnchorID, AnchoredAt: report.AnchoredAt})
+	if err != nil {
+		return err
+	}
+	for _, conflict := range conflicts {
+		report.Problems = append(report.Problems, Audit.Problem{Index: 0, Kind: Audit.ProblemAnchor, Detail: s.text(I18n.MsgAuditReplaced, conflict.Subject, conflict.AnchoredAt.Format(time.RFC3339), conflict.AnchorID)})
+	}
+	return nil
+}
+
+// AuditAnchor 登记的审计日志起点
+type AuditAnchor struct {
+	Subject    string    `json:"subject"`     // 主板ID或License序列号
+	AnchorID   string    `json:"anchor_id"`   // 首次校验时日志起点的锚点标识
+	AnchoredAt time.Time `json:"anchored_at"` // 客户端开始记录审计日志的时间
+	PinnedAt   time.Time `json:"pinned_at"`   // 登记时间
+}
+
+// AuditAnchors 审计日志起点登记，以JSON文件保存，可在多个goroutine中使用。
+// 客户可以丢弃整个审计日志并以新锚点重新记录，每台机器与每个License只认首次校验时的锚点。
+type AuditAnchors struct {
+	path    string
+	mu      sync.Mutex
+	anchors map[string]*AuditAnchor
+}
+
+// OpenAuditAnchors 打开审计日志起点登记，文件不存在时创建空登记
+func OpenAuditAnchors(path string) (*AuditAnchors, error) {
+	var registry = &AuditAnchors{path: path, anchors: make(map[string]*AuditAnchor)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var anchors = make([]*AuditAnchor, 0)
+	err = json.Unmarshal(data, &anchors)
+	if err != nil {
+		return nil, err
+	}
+	for _, anchor := range anchors {
+		registry.anchors[anchor.Subject] = anchor
+	}
+	return registry, nil
+}
+
+// Pin 为尚未登记的subjects登记anchor，返回已登记了其他锚点的记录
+func (a *AuditAnchors) Pin(subjects []string, anchor *AuditAnchor) ([]*AuditAnchor, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var conflicts = make([]*AuditAnchor, 0)
+	var added = make([]string, 0)
+	for _, subject := range subjects {
+		previous, ok := a.anchors[subject]
+		if ok {
+			if previous.AnchorID != anchor.AnchorID {
+				var copied = *previous
+				conflicts = append(conflicts, &copied)
+			}
+			continue
+		}
+		var pinned = *anchor
+		pinned.Subject, pinned.PinnedAt = subject, time.Now().UTC()
+		a.anchors[subject] = &pinned
+		added = append(added, subject)
+	}
+	if len(added) == 0 {
+		return conflicts, nil
+	}
+	err := a.save()
+	if err != nil {
+		for _, subject := range added {
+			delete(a.anchors, subject)
+		}
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// save 按Subject顺序保存登记
+func (a *AuditAnchors) save() error {
+	var anchors = make([]*AuditAnchor, 0, len(a.anchors))
+	for _, anchor := range a.anchors {
+		anchors = append(anchors, anchor)
+	}
+	sort.Slice(anchors, func(i, j int) bool {
+		return anchors[i].Subject < anchors[j].Subject
+	})
+	return saveJSON(a.path, anchors)
+}
+
+// VerifyAuditFile 校验审计日志包文件
+func (s *Server) VerifyAuditFile(path, ringPath string, passphrase []byte) (*AuditReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.VerifyAudit(data, ringPath, passphrase)
+}
@@ -0,0 +1,174 @@
+package Server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Client"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/Audit"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+var testPassphrase = []byte("audit-test")
+
+// newAuditClient 生成签发密钥环，返回记录审计日志的客户端与密钥环路径
+func newAuditClient(t *testing.T, server *Server) (*Client.Client, string) {
+	t.Helper()
+	var dir = t.TempDir()
+	var ringPath = filepath.Join(dir, "keyring.json")
+	_, err := server.Keygen(ringPath, "gm", testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var client = &Client.Client{Locale: I18n.ZH, TrustStore: ring.TrustStore(), AuditPath: filepath.Join(dir, "license.audit")}
+	return client, ringPath
+}
+
+// exportAudit 导出审计日志包，并以mbid作为导出时的主板ID
+func exportAudit(t *testing.T, client *Client.Client, mbid string) []byte {
+	t.Helper()
+	data, err := client.ExportAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle = new(Entity.AuditBundle)
+	if err = json.Unmarshal(data, bundle); err != nil {
+		t.Fatal(err)
+	}
+	bundle.MotherBoardID = mbid
+	data, err = json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func hasProblem(report *AuditReport, kind Audit.ProblemKind) bool {
+	for _, problem := range report.Problems {
+		if problem.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVerifyAuditRoundTrip(t *testing.T) {
+	server, _ := newTestServer(t)
+	client, ringPath := newAuditClient(t, server)
+	anchors, err := OpenAuditAnchors(filepath.Join(t.TempDir(), "anchors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.AuditAnchors = anchors
+
+	var first = exportAudit(t, client, "MB-TEST")
+	report, err := server.VerifyAudit(first, ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.AnchorID == "" || report.ExportedAt.IsZero() {
+		t.Fatalf("report = %+v", report)
+	}
+
+	second, err := server.VerifyAudit(exportAudit(t, client, "MB-TEST"), ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.OK() || second.AnchorID != report.AnchorID || len(second.Records) != 2 {
+		t.Fatalf("second report = %+v", second)
+	}
+
+	// 把日志截断到第一次导出时，导出时间与日志包生成时间不符
+	var parsed = new(Entity.AuditBundle)
+	if err = json.Unmarshal(first, parsed); err != nil {
+		t.Fatal(err)
+	}
+	parsed.CreatedAt = time.Now().Add(time.Hour)
+	truncated, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = server.VerifyAudit(truncated, ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasProblem(report, Audit.ProblemTruncated) {
+		t.Fatalf("truncation to an earlier export not detected: %+v", report.Problems)
+	}
+}
+
+// TestVerifyAuditReplacedLog 客户删除审计日志与审计链状态后重新生成的日志自身完整，由登记的锚点发现
+func TestVerifyAuditReplacedLog(t *testing.T) {
+	server, _ := newTestServer(t)
+	client, ringPath := newAuditClient(t, server)
+	var anchorsPath = filepath.Join(t.TempDir(), "anchors.json")
+	anchors, err := OpenAuditAnchors(anchorsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.AuditAnchors = anchors
+	report, err := server.VerifyAudit(exportAudit(t, client, "MB-TEST"), ringPath, testPassphrase)
+	if err != nil || !report.OK() {
+		t.Fatalf("report = %+v, err = %v", report, err)
+	}
+
+	for _, path := range []string{client.AuditPath, client.AuditPath + ".chain"} {
+		if err = os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var replaced = exportAudit(t, client, "MB-TEST")
+	// 重新打开登记文件，确认锚点已保存
+	server.AuditAnchors, err = OpenAuditAnchors(anchorsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = server.VerifyAudit(replaced, ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasProblem(report, Audit.ProblemAnchor) {
+		t.Fatalf("replaced log not detected: %+v", report.Problems)
+	}
+
+	// 其他机器的日志不受影响
+	var other = &Client.Client{Locale: I18n.ZH, TrustStore: client.TrustStore, AuditPath: filepath.Join(t.TempDir(), "license.audit")}
+	report, err = server.VerifyAudit(exportAudit(t, other, "MB-OTHER"), ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Fatalf("other machine report = %+v", report.Problems)
+	}
+}
+
+func TestVerifyAuditTampered(t *testing.T) {
+	server, _ := newTestServer(t)
+	client, ringPath := newAuditClient(t, server)
+	_ = exportAudit(t, client, "MB-TEST")
+	var bundle = new(Entity.AuditBundle)
+	if err := json.Unmarshal(exportAudit(t, client, "MB-TEST"), bundle); err != nil {
+		t.Fatal(err)
+	}
+	// 删除中间的导出记录
+	bundle.Entries = append(bundle.Entries[:1], bundle.Entries[2:]...)
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := server.VerifyAudit(data, ringPath, testPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasProblem(report, Audit.ProblemGap) {
+		t.Fatalf("deleted record not detected: %+v", report.Problems)
+	}
+}
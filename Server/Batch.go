@@ -0,0 +1,429 @@
+package Server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 批量签发失败原因
+const (
+	BatchTampered = "tampered" // node.info被篡改
+	BatchStale    = "stale"    // node.info已过期
+	BatchPolicy   = "policy"   // 违反签发策略
+	BatchError    = "error"    // 其它错误
+)
+
+// BatchItem 批量签发清单中的一项，未填写的字段使用批次默认值
+type BatchItem struct {
+	NodeInfo    string   `json:"node_info"`              // node.info路径，相对路径相对于清单所在目录
+	CustomerTag string   `json:"customer_tag,omitempty"` // 客户标记
+	CustomerID  string   `json:"customer_id,omitempty"`  // 客户库中的客户ID
+	Plan        string   `json:"plan,omitempty"`         // 授权方案
+	Product     string   `json:"product,omitempty"`
+	Edition     string   `json:"edition,omitempty"`
+	AllowNodes  int      `json:"allow_nodes,omitempty"`
+	Days        int      `json:"days,omitempty"`
+	EndTime     string   `json:"end_time,omitempty"` // RFC 3339格式
+	Permanent   bool     `json:"permanent,omitempty"`
+	Features    []string `json:"features,omitempty"`
+	Override    string   `json:"override,omitempty"` // 违反策略时的放行理由
+	Output      string   `json:"output,omitempty"`   // 输出文件名，须为输出目录内的相对路径，为空时按序号与客户标记生成
+}
+
+// BatchResult 单项签发结果
+type BatchResult struct {
+	NodeInfo    string `json:"node_info"`
+	CustomerTag string `json:"customer_tag,omitempty"`
+	Output      string `json:"output,omitempty"`
+	Serial      string `json:"serial,omitempty"`
+	OK          bool   `json:"ok"`
+	Reason      string `json:"reason,omitempty"` // 失败原因分类
+	Error       string `json:"error,omitempty"`
+}
+
+// BatchReport 批量签发汇总报告
+type BatchReport struct {
+	CreatedAt string         `json:"created_at"`
+	Total     int            `json:"total"`
+	Issued    int            `json:"issued"`
+	Failed    int            `json:"failed"`
+	Results   []*BatchResult `json:"results"`
+}
+
+// LoadManifest 读取批量签发清单。JSON文件为BatchItem数组；CSV文件首行为列名，
+// 列名与BatchItem的JSON字段一致，features以分号分隔；目录则签发其中全部.info文件。
+func LoadManifest(path string) ([]*BatchItem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var items []*BatchItem
+	var base = filepath.Dir(path)
+	switch {
+	case info.IsDir():
+		base = path
+		items, err = dirManifest(path)
+	case strings.EqualFold(filepath.Ext(path), ".csv"):
+		items, err = csvManifest(path)
+	default:
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &items)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		if item.NodeInfo == "" {
+			return nil, I18n.E(I18n.CodeManifestNodeInfo, i+1)
+		}
+		if !filepath.IsAbs(item.NodeInfo) {
+			item.NodeInfo = filepath.Join(base, item.NodeInfo)
+		}
+	}
+	return items, nil
+}
+
+func dirManifest(dir string) ([]*BatchItem, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.info"))
+	if err != nil {
+		return nil, err
+	}
+	var items = make([]*BatchItem, 0, len(matches))
+	for _, match := range matches {
+		items = append(items, &BatchItem{NodeInfo: filepath.Base(match)})
+	}
+	return items, nil
+}
+
+func csvManifest(path string) ([]*BatchItem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	var reader = csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	var header = records[0]
+	var items = make([]*BatchItem, 0, len(records)-1)
+	for _, record := range records[1:] {
+		var item = new(BatchItem)
+		for i, column := range header {
+			var value = strings.TrimSpace(record[i])
+			if value == "" {
+				continue
+			}
+			switch strings.TrimSpace(column) {
+			case "node_info":
+				item.NodeInfo = value
+			case "customer_tag":
+				item.CustomerTag = value
+			case "customer_id":
+				item.CustomerID = value
+			case "plan":
+				item.Plan = value
+			case "product":
+				item.Product = value
+			case "edition":
+				item.Edition = value
+			case "allow_nodes":
+				item.AllowNodes, err = strconv.Atoi(value)
+			case "days":
+				item.Days, err = strconv.Atoi(value)
+			case "end_time":
+				item.EndTime = value
+			case "permanent":
+				item.Permanent, err = strconv.ParseBool(value)
+			case "features":
+				item.Features = strings.Split(value, ";")
+			case "override":
+				item.Override = value
+			case "output":
+				item.Output = value
+			default:
+				return nil, I18n.E(I18n.CodeManifestColumn, column)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", column, err)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// IssueBatch 按清单批量签发，License写入outDir，单项失败不中断批次。
+// defaults中的字段作为清单各项的默认值，可为空；汇总报告同时写入outDir/report.json。
+func (s *Server) IssueBatch(manifestPath, outDir string, defaults *BatchItem) (*BatchReport, error) {
+	items, err := LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		items[i] = mergeBatchItem(item, defaults)
+		if items[i].Output == "" {
+			items[i].Output = s.defaultOutput(i+1, items[i])
+		}
+	}
+	err = checkOutputs(items)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(outDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	var report = &BatchReport{CreatedAt: Timestamp.Format(time.Now()), Total: len(items), Results: make([]*BatchResult, 0, len(items))}
+	for _, item := range items {
+		var result = s.issueBatchItem(item, outDir)
+		if result.OK {
+			report.Issued++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	_, err = writeFile(outDir, reportName, data)
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgBatchDone), Logger.F("total", report.Total), Logger.F("issued", report.Issued), Logger.F("failed", report.Failed))
+	return report, nil
+}
+
+// defaultOutput 按序号与客户标记生成输出文件名，客户标记的取值顺序与签发时一致。
+// 需要从node.info中取MAC地址但无法解析时返回空，由签发时报告错误。
+func (s *Server) defaultOutput(index int, item *BatchItem) string {
+	var tag = item.CustomerTag
+	if tag == "" {
+		tag = item.CustomerID
+	}
+	if tag == "" {
+		nodeInfo, err := os.ReadFile(item.NodeInfo)
+		if err != nil {
+			return ""
+		}
+		lic, err := s.Preview(nodeInfo)
+		if err != nil {
+			return ""
+		}
+		tag = lic.MacAddr
+	}
+	return fmt.Sprintf("%03d-%s.lic", index, safeName.ReplaceAllString(tag, "_"))
+}
+
+// issueBatchItem 签发清单中的一项并写入输出目录，写入成功后才记录签发
+func (s *Server) issueBatchItem(item *BatchItem, outDir string) *BatchResult {
+	var result = &BatchResult{NodeInfo: item.NodeInfo, CustomerTag: item.CustomerTag}
+	var fail = func(err error) *BatchResult {
+		result.Error = err.Error()
+		switch {
+		case I18n.Code(err) == I18n.CodeTamperedContact:
+			result.Reason = BatchTampered
+		case I18n.Code(err) == I18n.CodeNodeInfoExpired:
+			result.Reason = BatchStale
+		case errors.Is(err, ErrPolicyViolation):
+			result.Reason = BatchPolicy
+		default:
+			result.Reason = BatchError
+		}
+		s.logger().Warn(s.text(I18n.MsgBatchFailed), Logger.F(Logger.FieldPath, item.NodeInfo), Logger.F("reason", result.Reason), Logger.Err(err))
+		return result
+	}
+	if item.Output != "" && !localPath(item.Output) {
+		return fail(I18n.E(I18n.CodeManifestOutput, item.Output))
+	}
+	nodeInfo, err := os.ReadFile(item.NodeInfo)
+	if err != nil {
+		return fail(err)
+	}
+	var req = &IssueRequest{
+		NodeInfo:    nodeInfo,
+		Issuance:    Issuance{Plan: item.Plan, Product: item.Product, Edition: item.Edition, CustomerID: item.CustomerID, Override: item.Override},
+		AllowNodes:  item.AllowNodes,
+		Days:        item.Days,
+		Permanent:   item.Permanent,
+		Features:    item.Features,
+		CustomerTag: item.CustomerTag,
+	}
+	if item.EndTime != "" {
+		req.EndTime, err = Timestamp.Parse(item.EndTime, "")
+		if err != nil {
+			return fail(err)
+		}
+	}
+	var written string
+	var deliver = func(lic *Entity.License, data []byte) error {
+		if item.Output == "" {
+			return I18n.E(I18n.CodeManifestOutput, item.Output)
+		}
+		result.Output = item.Output
+		path, err := writeFile(outDir, result.Output, data)
+		if err == nil {
+			written = path
+		}
+		return err
+	}
+	lic, _, err := s.issue(req, deliver)
+	if err != nil {
+		// 写入后记录签发失败时撤回已写入的文件
+		if written != "" {
+			_ = os.Remove(written)
+		}
+		result.Output = ""
+		return fail(err)
+	}
+	result.CustomerTag = lic.CustomerTag
+	result.Serial = Logger.Serial(lic)
+	result.OK = true
+	return result
+}
+
+// localPath path是否为不含..的相对路径
+func localPath(path string) bool {
+	if path == "" || filepath.IsAbs(path) || filepath.VolumeName(path) != "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, `\`) {
+		return false
+	}
+	for _, element := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if element == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// checkOutputs 签发前检查清单中指定或生成的输出文件名：不能与签发报告同名，也不能互相重复。
+// 按不区分大小写比较，避免在不区分大小写的文件系统上互相覆盖。
+func checkOutputs(items []*BatchItem) error {
+	var seen = make(map[string]bool)
+	for _, item := range items {
+		if item.Output == "" || !localPath(item.Output) {
+			continue
+		}
+		var name = strings.ToLower(filepath.Clean(item.Output))
+		if name == reportName {
+			return I18n.E(I18n.CodeManifestReserved, item.Output)
+		}
+		if seen[name] {
+			return I18n.E(I18n.CodeManifestDuplicate, item.Output)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// writeFile 在输出目录内写入name，返回写入的路径。先写临时文件再改名，以0600权限写入；
+// 输出目录内已存在的符号链接（上级目录或目标文件）不会被跟随，遇到时返回错误。
+func writeFile(outDir, name string, data []byte) (string, error) {
+	var dir = outDir
+	for _, element := range strings.Split(filepath.Dir(filepath.Clean(name)), string(filepath.Separator)) {
+		if element == "." {
+			continue
+		}
+		dir = filepath.Join(dir, element)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			err = os.Mkdir(dir, 0755)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", I18n.E(I18n.CodeOutputSymlink, dir)
+		}
+		if !info.IsDir() {
+			return "", I18n.E(I18n.CodeOutputNotDir, dir)
+		}
+	}
+	var path = filepath.Join(outDir, name)
+	info, err := os.Lstat(path)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", I18n.E(I18n.CodeOutputSymlink, path)
+	}
+	// CreateTemp以O_EXCL创建，不会跟随预先放置的同名符号链接
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// reportName 输出目录中签发报告的文件名
+const reportName = "report.json"
+
+// safeName 输出文件名中不允许的字符
+var safeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// mergeBatchItem 以批次默认值补全清单项
+func mergeBatchItem(item, defaults *BatchItem) *BatchItem {
+	if defaults == nil {
+		return item
+	}
+	var merged = *item
+	if merged.CustomerID == "" {
+		merged.CustomerID = defaults.CustomerID
+	}
+	if merged.Plan == "" {
+		merged.Plan = defaults.Plan
+	}
+	if merged.Product == "" {
+		merged.Product = defaults.Product
+	}
+	if merged.Edition == "" {
+		merged.Edition = defaults.Edition
+	}
+	if merged.AllowNodes == 0 {
+		merged.AllowNodes = defaults.AllowNodes
+	}
+	if !merged.Permanent && merged.Days == 0 && merged.EndTime == "" {
+		merged.Permanent = defaults.Permanent
+		merged.Days = defaults.Days
+		merged.EndTime = defaults.EndTime
+	}
+	if merged.Features == nil {
+		merged.Features = defaults.Features
+	}
+	if merged.Override == "" {
+		merged.Override = defaults.Override
+	}
+	return &merged
+}
@@ -0,0 +1,224 @@
+package Server
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+)
+
+// writeManifest 在临时目录中写入node.info与批量签发清单，返回清单路径
+func writeManifest(t *testing.T, items []*BatchItem) string {
+	t.Helper()
+	var dir = t.TempDir()
+	for _, item := range items {
+		err := os.WriteFile(filepath.Join(dir, item.NodeInfo), newNodeInfo(t), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var path = filepath.Join(dir, "manifest.json")
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIssueBatch(t *testing.T) {
+	server, _ := newTestServer(t)
+	var manifest = writeManifest(t, []*BatchItem{
+		{NodeInfo: "a.info", CustomerTag: "ACME", Output: "acme/license.lic"},
+		{NodeInfo: "b.info", CustomerTag: "Beta Corp"},
+	})
+	var outDir = t.TempDir()
+	report, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Issued != 2 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report.Results[0])
+	}
+	for _, name := range []string{"acme/license.lic", "002-Beta_Corp.lic", "report.json"} {
+		info, err := os.Stat(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", name, info.Mode().Perm())
+		}
+	}
+	for _, result := range report.Results {
+		if _, err = server.Inventory.Get(result.Serial); err != nil {
+			t.Errorf("%s not recorded: %v", result.Output, err)
+		}
+	}
+}
+
+func TestIssueBatchRejectsUnsafeOutput(t *testing.T) {
+	server, _ := newTestServer(t)
+	var outDir = filepath.Join(t.TempDir(), "out")
+	var outside = filepath.Join(filepath.Dir(outDir), "outside.lic")
+	var manifest = writeManifest(t, []*BatchItem{
+		{NodeInfo: "a.info", Output: "../outside.lic"},
+		{NodeInfo: "b.info", Output: outside},
+		{NodeInfo: "c.info", Output: "sub/../../outside.lic"},
+	})
+	report, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if _, err = os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("license written outside the output directory: %v", err)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("unsafe items were signed %d times", calls)
+	}
+	if records := server.Inventory.Search(&InventoryQuery{}); len(records) != 0 {
+		t.Errorf("unsafe items recorded: %d", len(records))
+	}
+}
+
+// TestIssueBatchWriteFailure 写入失败的License不记录签发
+func TestIssueBatchWriteFailure(t *testing.T) {
+	server, _ := newTestServer(t)
+	var outDir = t.TempDir()
+	// 输出路径的上级是已存在的文件，无法创建目录
+	err := os.WriteFile(filepath.Join(outDir, "blocked"), nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest = writeManifest(t, []*BatchItem{{NodeInfo: "a.info", Output: "blocked/license.lic"}})
+	report, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 1 {
+		t.Fatalf("report = %+v", report.Results[0])
+	}
+	if records := server.Inventory.Search(&InventoryQuery{}); len(records) != 0 {
+		t.Errorf("undelivered license recorded: %d", len(records))
+	}
+	if _, err = os.Stat(server.LedgerPath); !os.IsNotExist(err) {
+		t.Errorf("undelivered license written to the ledger: %v", err)
+	}
+}
+
+// TestIssueBatchRecordFailure 记录签发失败时撤回已写入的License
+func TestIssueBatchRecordFailure(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.LedgerPath = filepath.Join(t.TempDir(), "missing", "ledger.jsonl")
+	var outDir = t.TempDir()
+	var manifest = writeManifest(t, []*BatchItem{{NodeInfo: "a.info", Output: "license.lic"}})
+	report, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 1 || !strings.Contains(report.Results[0].Error, "ledger.jsonl") {
+		t.Fatalf("result = %+v", report.Results[0])
+	}
+	if _, err = os.Stat(filepath.Join(outDir, "license.lic")); !os.IsNotExist(err) {
+		t.Errorf("unrecorded license left in the output directory: %v", err)
+	}
+	if records := server.Inventory.Search(&InventoryQuery{}); len(records) != 0 {
+		t.Errorf("inventory not rolled back: %d records", len(records))
+	}
+}
+
+// TestIssueBatchRejectsOutputConflicts 输出文件名重复或与签发报告重名时整批不签发
+func TestIssueBatchRejectsOutputConflicts(t *testing.T) {
+	for code, outputs := range map[I18n.ID][]string{
+		I18n.CodeManifestDuplicate: {"acme.lic", "./ACME.lic"},
+		I18n.CodeManifestReserved:  {"a.lic", "Report.json"},
+	} {
+		t.Run(string(code), func(t *testing.T) {
+			server, _ := newTestServer(t)
+			var manifest = writeManifest(t, []*BatchItem{
+				{NodeInfo: "a.info", Output: outputs[0]},
+				{NodeInfo: "b.info", Output: outputs[1]},
+			})
+			var outDir = filepath.Join(t.TempDir(), "out")
+			_, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+			if I18n.Code(err) != code {
+				t.Fatalf("IssueBatch error = %v, want %s", err, code)
+			}
+			if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+				t.Errorf("signed %d licenses before rejecting the manifest", calls)
+			}
+			if _, err = os.Stat(outDir); !os.IsNotExist(err) {
+				t.Errorf("output directory created: %v", err)
+			}
+		})
+	}
+}
+
+// TestIssueBatchRejectsGeneratedConflict 生成的输出文件名与清单中指定的文件名相同时整批不签发
+func TestIssueBatchRejectsGeneratedConflict(t *testing.T) {
+	server, _ := newTestServer(t)
+	var manifest = writeManifest(t, []*BatchItem{
+		{NodeInfo: "a.info", CustomerTag: "acme"},
+		{NodeInfo: "b.info", Output: "001-ACME.lic"},
+	})
+	var outDir = filepath.Join(t.TempDir(), "out")
+	_, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if I18n.Code(err) != I18n.CodeManifestDuplicate {
+		t.Fatalf("IssueBatch error = %v, want %s", err, I18n.CodeManifestDuplicate)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("signed %d licenses before rejecting the manifest", calls)
+	}
+	if _, err = os.Stat(outDir); !os.IsNotExist(err) {
+		t.Errorf("output directory created: %v", err)
+	}
+}
+
+// TestIssueBatchSymlinks 输出目录内已有的符号链接不会被跟随
+func TestIssueBatchSymlinks(t *testing.T) {
+	server, _ := newTestServer(t)
+	var outDir, outside = t.TempDir(), t.TempDir()
+	var target = filepath.Join(outside, "target.lic")
+	if err := os.WriteFile(target, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(outDir, "linked")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(target, filepath.Join(outDir, "file.lic")); err != nil {
+		t.Fatal(err)
+	}
+	var manifest = writeManifest(t, []*BatchItem{
+		{NodeInfo: "a.info", Output: "linked/license.lic"},
+		{NodeInfo: "b.info", Output: "file.lic"},
+	})
+	report, err := server.IssueBatch(manifest, outDir, &BatchItem{AllowNodes: 8, Days: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files written through the symlinked directory: %v", entries)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "original" {
+		t.Errorf("symlinked license overwritten: %q", data)
+	}
+	if records := server.Inventory.Search(&InventoryQuery{}); len(records) != 0 {
+		t.Errorf("undelivered licenses recorded: %d", len(records))
+	}
+}
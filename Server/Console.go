@@ -0,0 +1,21 @@
+package Server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// console 网页控制台静态文件，不依赖外部CDN
+//
+//go:embed web
+var console embed.FS
+
+// consoleHandler 网页控制台，页面本身无需令牌，页面调用的接口使用令牌校验
+func consoleHandler() http.Handler {
+	files, err := fs.Sub(console, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,278 @@
+package Server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/manifoldco/promptui"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Contact 客户联系人
+type Contact struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Role  string `json:"role,omitempty"` // 职责，如商务、技术
+}
+
+// Customer 客户信息，签发时以ID关联
+type Customer struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	ContractNo string    `json:"contract_no,omitempty"` // 合同编号
+	Contacts   []Contact `json:"contacts,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CustomerSummary 客户的授权汇总
+type CustomerSummary struct {
+	Customer       *Customer        `json:"customer"`
+	ActiveLicenses []*LicenseRecord `json:"active_licenses"` // 有效的License
+	TotalNodes     int              `json:"total_nodes"`     // 有效License的授权节点总数
+	NextExpiry     *time.Time       `json:"next_expiry,omitempty"`
+}
+
+// CustomerRegistry 客户库，以JSON文件保存，可在多个goroutine中使用
+type CustomerRegistry struct {
+	path      string
+	mu        sync.RWMutex
+	customers map[string]*Customer
+}
+
+// OpenCustomerRegistry 打开客户库，文件不存在时创建空客户库
+func OpenCustomerRegistry(path string) (*CustomerRegistry, error) {
+	var registry = &CustomerRegistry{path: path, customers: make(map[string]*Customer)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var customers = make([]*Customer, 0)
+	err = json.Unmarshal(data, &customers)
+	if err != nil {
+		return nil, err
+	}
+	for _, customer := range customers {
+		registry.customers[customer.ID] = customer
+	}
+	return registry, nil
+}
+
+// Put 新增或更新客户，更新时保留创建时间
+func (r *CustomerRegistry) Put(customer *Customer) error {
+	return r.put(customer, false)
+}
+
+// Add 新增客户，ID已存在时返回错误而不覆盖
+func (r *CustomerRegistry) Add(customer *Customer) error {
+	return r.put(customer, true)
+}
+
+func (r *CustomerRegistry) put(customer *Customer, create bool) error {
+	customer.ID = strings.TrimSpace(customer.ID)
+	if customer.ID == "" {
+		return I18n.E(I18n.CodeCustomerID)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var copied = *customer
+	copied.Contacts = append([]Contact(nil), customer.Contacts...)
+	copied.UpdatedAt = time.Now().UTC()
+	copied.CreatedAt = copied.UpdatedAt
+	var previous, exists = r.customers[customer.ID]
+	if exists && create {
+		return I18n.E(I18n.CodeCustomerExists, customer.ID)
+	}
+	if exists {
+		copied.CreatedAt = previous.CreatedAt
+	}
+	r.customers[customer.ID] = &copied
+	err := r.save()
+	if err != nil {
+		if exists {
+			r.customers[customer.ID] = previous
+		} else {
+			delete(r.customers, customer.ID)
+		}
+		return err
+	}
+	customer.CreatedAt, customer.UpdatedAt = copied.CreatedAt, copied.UpdatedAt
+	return nil
+}
+
+// Get 按ID获取客户的副本
+func (r *CustomerRegistry) Get(id string) (*Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	customer, ok := r.customers[id]
+	if !ok {
+		return nil, I18n.E(I18n.CodeCustomerNotFound, id)
+	}
+	var copied = *customer
+	copied.Contacts = append([]Contact(nil), customer.Contacts...)
+	return &copied, nil
+}
+
+// List 按ID顺序列出客户，text不为空时匹配ID、名称、合同编号或联系人的子串
+func (r *CustomerRegistry) List(text string) []*Customer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var result = make([]*Customer, 0, len(r.customers))
+	for _, customer := range r.customers {
+		if text != "" && !customer.matches(text) {
+			continue
+		}
+		var copied = *customer
+		copied.Contacts = append([]Contact(nil), customer.Contacts...)
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Remove 删除客户，已签发的License仍保留客户ID
+func (r *CustomerRegistry) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	customer, ok := r.customers[id]
+	if !ok {
+		return I18n.E(I18n.CodeCustomerNotFound, id)
+	}
+	delete(r.customers, id)
+	err := r.save()
+	if err != nil {
+		r.customers[id] = customer
+	}
+	return err
+}
+
+// save 按ID顺序保存客户库
+func (r *CustomerRegistry) save() error {
+	var customers = make([]*Customer, 0, len(r.customers))
+	for _, customer := range r.customers {
+		customers = append(customers, customer)
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		return customers[i].ID < customers[j].ID
+	})
+	return saveJSON(r.path, customers)
+}
+
+func (c *Customer) matches(text string) bool {
+	var fields = []string{c.ID, c.Name, c.ContractNo}
+	for _, contact := range c.Contacts {
+		fields = append(fields, contact.Name, contact.Email, contact.Phone)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), strings.ToLower(text)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerLicenses 查询客户的签发记录，query可为空，其中的CustomerID被忽略
+func (s *Server) CustomerLicenses(id string, query *InventoryQuery) ([]*LicenseRecord, error) {
+	if s.Inventory == nil {
+		return nil, s.err(I18n.CodeNoInventory)
+	}
+	_, err := s.customer(id)
+	if err != nil {
+		return nil, err
+	}
+	var q InventoryQuery
+	if query != nil {
+		q = *query
+	}
+	q.CustomerID = id
+	return s.Inventory.Search(&q), nil
+}
+
+// CustomerSummary 客户信息及有效License、授权节点总数与最近到期时间
+func (s *Server) CustomerSummary(id string) (*CustomerSummary, error) {
+	customer, err := s.customer(id)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.CustomerLicenses(id, &InventoryQuery{Active: true})
+	if err != nil {
+		return nil, err
+	}
+	var summary = &CustomerSummary{Customer: customer, ActiveLicenses: active, TotalNodes: TotalNodes(active)}
+	for _, record := range active {
+		if record.PermanentAuth {
+			continue
+		}
+		end, err := Timestamp.Parse(record.EndTime, "")
+		if err != nil {
+			continue
+		}
+		if summary.NextExpiry == nil || end.Before(*summary.NextExpiry) {
+			summary.NextExpiry = &end
+		}
+	}
+	return summary, nil
+}
+
+// customer 从客户库获取客户，未配置客户库时返回错误
+func (s *Server) customer(id string) (*Customer, error) {
+	if s.Customers == nil {
+		return nil, s.err(I18n.CodeNoCustomerRegistry)
+	}
+	customer, err := s.Customers.Get(id)
+	if err != nil {
+		return nil, s.err(I18n.CodeCustomerNotFound, id)
+	}
+	return customer, nil
+}
+
+// checkCustomer 签发关联客户时客户必须在客户库中
+func (s *Server) checkCustomer(issuance *Issuance) error {
+	if issuance.CustomerID == "" {
+		return nil
+	}
+	_, err := s.customer(issuance.CustomerID)
+	return err
+}
+
+// selectCustomer 配置了客户库时选择签发关联的客户
+func (s *Server) selectCustomer(issuance *Issuance) error {
+	if s.Customers == nil || issuance.CustomerID != "" {
+		return nil
+	}
+	var customers = s.Customers.List("")
+	if len(customers) == 0 {
+		return nil
+	}
+	var items = make([]string, 0, len(customers)+1)
+	for _, customer := range customers {
+		items = append(items, fmt.Sprintf("%s (%s)", customer.Name, customer.ID))
+	}
+	items = append(items, s.text(I18n.MsgNoCustomer))
+	promptSelect := promptui.Select{
+		Label:             s.text(I18n.PromptCustomer),
+		Items:             items,
+		StartInSearchMode: len(customers) > 10,
+		Searcher: func(input string, index int) bool {
+			return strings.Contains(strings.ToLower(items[index]), strings.ToLower(input))
+		},
+	}
+	i, _, err := promptSelect.Run()
+	if err != nil || i == len(customers) {
+		return err
+	}
+	issuance.CustomerID = customers[i].ID
+	return nil
+}
@@ -0,0 +1,199 @@
+package Server
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+func openRegistry(t *testing.T, path string) *CustomerRegistry {
+	t.Helper()
+	registry, err := OpenCustomerRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+// TestCustomerRegistryPersistence 重新打开客户库后客户信息不变
+func TestCustomerRegistryPersistence(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "customers.json")
+	var registry = openRegistry(t, path)
+	var acme = &Customer{ID: " acme ", Name: "ACME", ContractNo: "HT-001", Contacts: []Contact{{Name: "Ann", Email: "ann@acme.test", Role: "技术"}}, Notes: "key account"}
+	for _, customer := range []*Customer{acme, {ID: "beta", Name: "Beta"}, {ID: "gamma", Name: "Gamma"}} {
+		if err := registry.Add(customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if acme.ID != "acme" || acme.CreatedAt.IsZero() {
+		t.Fatalf("added customer = %+v", acme)
+	}
+	if err := registry.Remove("gamma"); err != nil {
+		t.Fatal(err)
+	}
+
+	var reopened = openRegistry(t, path)
+	got, err := reopened.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ACME" || got.ContractNo != "HT-001" || got.Notes != "key account" || len(got.Contacts) != 1 || got.Contacts[0] != acme.Contacts[0] {
+		t.Errorf("reopened customer = %+v", got)
+	}
+	if !got.CreatedAt.Equal(acme.CreatedAt) || !got.UpdatedAt.Equal(acme.UpdatedAt) {
+		t.Errorf("times = %v/%v, want %v/%v", got.CreatedAt, got.UpdatedAt, acme.CreatedAt, acme.UpdatedAt)
+	}
+	if _, err = reopened.Get("gamma"); I18n.Code(err) != I18n.CodeCustomerNotFound {
+		t.Errorf("removed customer error = %v, want %s", err, I18n.CodeCustomerNotFound)
+	}
+	if list := reopened.List(""); len(list) != 2 || list[0].ID != "acme" || list[1].ID != "beta" {
+		t.Errorf("List = %+v", list)
+	}
+
+	// Get返回副本
+	got.Contacts[0].Email = "changed@acme.test"
+	if again, _ := reopened.Get("acme"); again.Contacts[0].Email != "ann@acme.test" {
+		t.Error("registry modified through a returned customer")
+	}
+	if registry := openRegistry(t, filepath.Join(t.TempDir(), "missing.json")); len(registry.List("")) != 0 {
+		t.Error("new registry is not empty")
+	}
+}
+
+// TestCustomerRegistryPut 更新客户时保留创建时间，忽略调用方传入的时间
+func TestCustomerRegistryPut(t *testing.T) {
+	var registry = openRegistry(t, filepath.Join(t.TempDir(), "customers.json"))
+	var first = &Customer{ID: "acme", Name: "ACME"}
+	if err := registry.Put(first); err != nil {
+		t.Fatal(err)
+	}
+	var created = first.CreatedAt
+	time.Sleep(10 * time.Millisecond)
+	var update = &Customer{ID: "acme", Name: "ACME Ltd.", CreatedAt: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := registry.Put(update); err != nil {
+		t.Fatal(err)
+	}
+	got, err := registry.Get("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "ACME Ltd." || !got.CreatedAt.Equal(created) || !got.UpdatedAt.After(created) {
+		t.Errorf("updated customer = %+v, want CreatedAt %v", got, created)
+	}
+	if !update.CreatedAt.Equal(created) || !update.UpdatedAt.Equal(got.UpdatedAt) {
+		t.Errorf("caller's customer times = %v/%v", update.CreatedAt, update.UpdatedAt)
+	}
+	if err = registry.Put(&Customer{ID: "  ", Name: "nobody"}); I18n.Code(err) != I18n.CodeCustomerID {
+		t.Errorf("Put without ID error = %v, want %s", err, I18n.CodeCustomerID)
+	}
+}
+
+func TestCustomerRegistryAdd(t *testing.T) {
+	var registry = openRegistry(t, filepath.Join(t.TempDir(), "customers.json"))
+	if err := registry.Add(&Customer{ID: "acme", Name: "ACME"}); err != nil {
+		t.Fatal(err)
+	}
+	err := registry.Add(&Customer{ID: "acme", Name: "Impostor"})
+	if I18n.Code(err) != I18n.CodeCustomerExists {
+		t.Fatalf("Add error = %v, want %s", err, I18n.CodeCustomerExists)
+	}
+	if got, _ := registry.Get("acme"); got.Name != "ACME" {
+		t.Errorf("existing customer overwritten: %+v", got)
+	}
+}
+
+// TestCustomerRegistryList 按ID、名称、合同编号与联系人搜索，不区分大小写
+func TestCustomerRegistryList(t *testing.T) {
+	var registry = openRegistry(t, filepath.Join(t.TempDir(), "customers.json"))
+	for _, customer := range []*Customer{
+		{ID: "gamma", Name: "Gamma", Contacts: []Contact{{Name: "Eve", Phone: "010-10086"}}},
+		{ID: "acme", Name: "ACME", ContractNo: "HT-2024-7", Contacts: []Contact{{Name: "Ann", Email: "ann@acme.test"}}},
+		{ID: "beta", Name: "Beta", Contacts: []Contact{{Name: "Dan", Email: "dan@beta.test"}, {Name: "Ann Lee"}}},
+	} {
+		if err := registry.Put(customer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for text, want := range map[string][]string{
+		"":              {"acme", "beta", "gamma"},
+		"ACME.TEST":     {"acme"},
+		"10086":         {"gamma"},
+		"ann":           {"acme", "beta"},
+		"ht-2024":       {"acme"},
+		"beta":          {"beta"},
+		"nobody@x.test": {},
+	} {
+		var ids = make([]string, 0)
+		for _, customer := range registry.List(text) {
+			ids = append(ids, customer.ID)
+		}
+		if len(ids) != len(want) {
+			t.Errorf("List(%q) = %v, want %v", text, ids, want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != want[i] {
+				t.Errorf("List(%q) = %v, want %v", text, ids, want)
+				break
+			}
+		}
+	}
+}
+
+// TestCustomerSummary 汇总只统计有效License，已吊销与已续期的License不计入
+func TestCustomerSummary(t *testing.T) {
+	var server = newReminderServer(t, []*Customer{{ID: "acme", Name: "ACME"}, {ID: "beta", Name: "Beta"}}, map[string][]int{"acme": {30, 10}, "beta": {2}})
+	var issue = func(customer string, nodes, days int, permanent bool) string {
+		var req = &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: nodes, Days: days, Permanent: permanent, Issuance: Issuance{CustomerID: customer}}
+		lic, _, err := server.Issue(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Logger.Serial(lic)
+	}
+	issue("acme", 16, 0, true)
+	if _, err := server.Inventory.Revoke(issue("acme", 32, 5, false), "refund"); err != nil {
+		t.Fatal(err)
+	}
+	var original = issue("acme", 2, 3, false)
+	renewed, _, err := server.Renew(original, &RenewRequest{Days: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := server.CustomerSummary("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Customer.Name != "ACME" || len(summary.ActiveLicenses) != 4 {
+		t.Fatalf("summary = %+v, %d active licenses", summary.Customer, len(summary.ActiveLicenses))
+	}
+	// 8+8（newReminderServer签发）+16（永久）+2（续期后）
+	if summary.TotalNodes != 34 {
+		t.Errorf("TotalNodes = %d, want 34", summary.TotalNodes)
+	}
+	for _, record := range summary.ActiveLicenses {
+		if record.Revoked || record.Serial == original {
+			t.Errorf("unexpected active record %+v", record)
+		}
+	}
+	if _, err = server.Inventory.Get(Logger.Serial(renewed)); err != nil {
+		t.Errorf("renewed license not recorded: %v", err)
+	}
+	var want = time.Now().AddDate(0, 0, 10)
+	if summary.NextExpiry == nil || summary.NextExpiry.Sub(want).Abs() > time.Minute {
+		t.Errorf("NextExpiry = %v, want about %s", summary.NextExpiry, Timestamp.Format(want))
+	}
+
+	if _, err = server.CustomerSummary("nobody"); I18n.Code(err) != I18n.CodeCustomerNotFound {
+		t.Errorf("unknown customer error = %v, want %s", err, I18n.CodeCustomerNotFound)
+	}
+	server.Customers = nil
+	if _, err = server.CustomerSummary("acme"); I18n.Code(err) != I18n.CodeNoCustomerRegistry {
+		t.Errorf("no registry error = %v, want %s", err, I18n.CodeNoCustomerRegistry)
+	}
+}
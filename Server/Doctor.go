@@ -0,0 +1,257 @@
+package Server
+
+import (
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Keystore"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 诊断因素
+const (
+	FactorLicense     = "license"     // License能否读取
+	FactorMotherBoard = "motherboard" // 主板ID
+	FactorMAC         = "mac"         // 授权网卡
+	FactorTimeZone    = "timezone"    // 时区
+	FactorClock       = "clock"       // 系统时间
+	FactorValidity    = "validity"    // 有效期
+	FactorChecks      = "checks"      // 最近的校验结果
+)
+
+// Finding 单项诊断结论
+type Finding struct {
+	Factor   string `json:"factor"`
+	OK       bool   `json:"ok"`
+	Expected string `json:"expected,omitempty"` // License中记录的值
+	Actual   string `json:"actual,omitempty"`   // 客户端当前的值
+	Detail   string `json:"detail"`
+}
+
+// DiagnosticReport 诊断包分析结果
+type DiagnosticReport struct {
+	Diagnostics *Entity.Diagnostics `json:"diagnostics"`
+	Findings    []Finding           `json:"findings"`
+}
+
+// OpenSupportBundle 使用密钥环中对应的私钥解密客户端诊断包，passphrase为空时交互式输入
+func (s *Server) OpenSupportBundle(data []byte, ringPath string, passphrase []byte) (*Entity.Diagnostics, error) {
+	var bundle = new(Entity.SupportBundle)
+	err := json.Unmarshal(data, bundle)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Version != Entity.SupportBundleVersion {
+		return nil, s.err(I18n.CodeBundleVersion, bundle.Version)
+	}
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return nil, err
+	}
+	var entry *KeyRingEntry
+	for _, e := range ring.Keys {
+		if e.KeyID == bundle.KeyID {
+			entry = e
+		}
+	}
+	if entry == nil {
+		return nil, s.err(I18n.CodeBundleKey, bundle.KeyID)
+	}
+	passphrase, err = readPassphrase(passphrase, s.text(I18n.PromptPassphrase), false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := Keystore.Decrypt([]byte(entry.PrivateKey), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := Suite.Get(bundle.Suite)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := Suite.EncrypterOf(suite)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := encrypter.DecryptWith(key.Private, bundle.Data)
+	if err != nil {
+		return nil, err
+	}
+	var diag = new(Entity.Diagnostics)
+	err = json.Unmarshal(plaintext, diag)
+	if err != nil {
+		return nil, err
+	}
+	return diag, nil
+}
+
+// AnalyzeSupportBundle 解密并分析客户端诊断包
+func (s *Server) AnalyzeSupportBundle(path, ringPath string, passphrase []byte) (*DiagnosticReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	diag, err := s.OpenSupportBundle(data, ringPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return s.AnalyzeDiagnostics(diag), nil
+}
+
+// AnalyzeDiagnostics 逐项比较诊断信息与License，按Server.Locale说明哪个因素与License不一致
+func (s *Server) AnalyzeDiagnostics(diag *Entity.Diagnostics) *DiagnosticReport {
+	var report = &DiagnosticReport{Diagnostics: diag}
+	var lic = diag.License
+	if lic == nil {
+		report.add(Finding{Factor: FactorLicense, Detail: s.text(I18n.MsgDiagLicenseError, diag.LicenseError)})
+		s.analyzeChecks(report)
+		return report
+	}
+	report.add(Finding{Factor: FactorLicense, OK: true, Detail: s.text(I18n.MsgDiagLicenseOK)})
+	s.analyzeMotherBoard(report, lic)
+	s.analyzeMAC(report, lic)
+	s.analyzeTimeZone(report, lic)
+	s.analyzeClock(report, lic)
+	s.analyzeValidity(report, lic)
+	s.analyzeChecks(report)
+	return report
+}
+
+func (r *DiagnosticReport) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// normalize 与主板ID采集一致：去除空白
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func (s *Server) analyzeMotherBoard(r *DiagnosticReport, lic *Entity.License) {
+	var current string
+	for _, fp := range r.Diagnostics.Fingerprints {
+		if fp.Provider == "motherboard_id" {
+			current = fp.Value
+		}
+	}
+	var finding = Finding{Factor: FactorMotherBoard, Expected: lic.MotherBoardID, Actual: current}
+	if current == lic.MotherBoardID {
+		finding.OK = true
+		finding.Detail = s.text(I18n.MsgDiagBoardOK)
+		r.add(finding)
+		return
+	}
+	// 在其它指纹来源中查找License绑定的值，判断是采集方式变化还是硬件变化
+	var matched = make([]string, 0)
+	for _, fp := range r.Diagnostics.Fingerprints {
+		if fp.Provider == "motherboard_id" || fp.Value == "" || lic.MotherBoardID == "" {
+			continue
+		}
+		if strings.Contains(normalize(fp.Value), lic.MotherBoardID) {
+			matched = append(matched, fp.Provider)
+		}
+	}
+	switch {
+	case current == "":
+		finding.Detail = s.text(I18n.MsgDiagBoardMissing)
+	case len(matched) > 0:
+		finding.Detail = s.text(I18n.MsgDiagBoardCollected, strings.Join(matched, ", "))
+	default:
+		finding.Detail = s.text(I18n.MsgDiagBoardReplaced)
+	}
+	r.add(finding)
+}
+
+func (s *Server) analyzeMAC(r *DiagnosticReport, lic *Entity.License) {
+	var finding = Finding{Factor: FactorMAC, Expected: lic.MacAddr}
+	var macs = make([]string, 0)
+	for _, card := range r.Diagnostics.NetCards {
+		if card.MAC == "" {
+			continue
+		}
+		macs = append(macs, card.Name+"="+card.MAC)
+		if strings.EqualFold(card.MAC, lic.MacAddr) {
+			finding.OK = true
+			finding.Actual = card.Name + "=" + card.MAC
+		}
+	}
+	if finding.OK {
+		finding.Detail = s.text(I18n.MsgDiagMACOK)
+	} else {
+		finding.Actual = strings.Join(macs, ", ")
+		finding.Detail = s.text(I18n.MsgDiagMACMissing)
+	}
+	r.add(finding)
+}
+
+func (s *Server) analyzeTimeZone(r *DiagnosticReport, lic *Entity.License) {
+	var finding = Finding{Factor: FactorTimeZone, Expected: lic.ClientTimeZone, Actual: r.Diagnostics.TimeZone}
+	var same = lic.ClientTimeZone == "" || lic.ClientTimeZone == "Local" || strings.HasPrefix(r.Diagnostics.TimeZone, lic.ClientTimeZone)
+	var legacy = Timestamp.IsLegacy(lic.StartTime) || Timestamp.IsLegacy(lic.EndTime)
+	finding.OK = same || !legacy
+	switch {
+	case same:
+		finding.Detail = s.text(I18n.MsgDiagZoneSame)
+	case !legacy:
+		finding.Detail = s.text(I18n.MsgDiagZoneUTC)
+	default:
+		finding.Detail = s.text(I18n.MsgDiagZoneLegacy)
+	}
+	r.add(finding)
+}
+
+func (s *Server) analyzeClock(r *DiagnosticReport, lic *Entity.License) {
+	var finding = Finding{Factor: FactorClock, Actual: r.Diagnostics.LocalTime, OK: true, Detail: s.text(I18n.MsgDiagClockOK)}
+	if lic.LastCheckTime != nil {
+		finding.Expected = Timestamp.Format(*lic.LastCheckTime)
+		if lic.LastCheckTime.After(r.Diagnostics.CreatedAt) {
+			finding.OK = false
+			finding.Detail = s.text(I18n.MsgDiagClockBehind)
+		}
+	}
+	if r.Diagnostics.ClockRollbacks > 0 {
+		finding.OK = false
+		finding.Detail = s.text(I18n.MsgDiagClockRollbacks, r.Diagnostics.ClockRollbacks)
+	}
+	r.add(finding)
+}
+
+func (s *Server) analyzeValidity(r *DiagnosticReport, lic *Entity.License) {
+	var finding = Finding{Factor: FactorValidity, Expected: lic.StartTime + " ~ " + lic.EndTime, Actual: r.Diagnostics.LocalTime}
+	var now = r.Diagnostics.CreatedAt
+	start, err1 := lic.StartAt()
+	end, err2 := lic.EndAt()
+	switch {
+	case err1 != nil || err2 != nil:
+		finding.Detail = s.text(I18n.MsgDiagBadValidity)
+	case now.Before(start):
+		finding.Detail = s.text(I18n.MsgDiagNotYetValid)
+	case now.After(end):
+		finding.Detail = s.text(I18n.MsgDiagExpired)
+	default:
+		finding.OK = true
+		finding.Detail = s.text(I18n.MsgDiagValid)
+	}
+	r.add(finding)
+}
+
+func (s *Server) analyzeChecks(r *DiagnosticReport) {
+	var failed = make([]string, 0)
+	for check, ok := range r.Diagnostics.Checks {
+		if !ok {
+			failed = append(failed, check)
+		}
+	}
+	sort.Strings(failed)
+	var finding = Finding{Factor: FactorChecks, OK: len(failed) == 0}
+	if finding.OK {
+		finding.Detail = s.text(I18n.MsgDiagChecksOK)
+	} else {
+		finding.Actual = strings.Join(failed, ", ")
+		finding.Detail = s.text(I18n.MsgDiagChecksFailed, finding.Actual)
+	}
+	r.add(finding)
+}
@@ -0,0 +1,54 @@
+package Server
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+// TestAnalyzeDiagnosticsLocale 诊断结论按Server.Locale输出
+func TestAnalyzeDiagnosticsLocale(t *testing.T) {
+	var now = time.Now()
+	var last = now.Add(time.Hour)
+	var diag = &Entity.Diagnostics{
+		CreatedAt:      now,
+		Fingerprints:   []Entity.Fingerprint{{Provider: "motherboard_id", Value: "MB-NEW"}, {Provider: "dmi_uuid", Value: "MB TEST"}},
+		NetCards:       []Entity.NetCard{{Name: "eth0", MAC: "aa:bb:cc:dd:ee:ff"}},
+		TimeZone:       "UTC",
+		LocalTime:      Timestamp.Format(now),
+		Checks:         map[string]bool{"motherboard": false, "validity": true},
+		ClockRollbacks: 2,
+		License: &Entity.License{
+			MotherBoardID:  "MBTEST",
+			MacAddr:        "00:11:22:33:44:55",
+			ClientTimeZone: "Asia/Shanghai",
+			StartTime:      Timestamp.Format(now.AddDate(0, -2, 0)),
+			EndTime:        Timestamp.Format(now.AddDate(0, -1, 0)),
+			LastCheckTime:  &last,
+		},
+	}
+	server, _ := newTestServer(t)
+	server.Locale = I18n.EN
+	var report = server.AnalyzeDiagnostics(diag)
+	if len(report.Findings) != 7 {
+		t.Fatalf("findings = %+v", report.Findings)
+	}
+	for _, finding := range report.Findings {
+		if finding.Detail == "" || strings.IndexFunc(finding.Detail, func(r rune) bool { return unicode.Is(unicode.Han, r) }) >= 0 {
+			t.Errorf("%s detail not in English: %q", finding.Factor, finding.Detail)
+		}
+	}
+	if detail := report.Findings[1].Detail; !strings.Contains(detail, "dmi_uuid") {
+		t.Errorf("motherboard detail = %q", detail)
+	}
+
+	server.Locale = I18n.ZH
+	if detail := server.AnalyzeDiagnostics(diag).Findings[0].Detail; detail != "License读取正常" {
+		t.Errorf("zh detail = %q", detail)
+	}
+}
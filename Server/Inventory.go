@@ -0,0 +1,227 @@
+package Server
+
+import (
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LicenseRecord 签发记录，包含台账信息、吊销状态与License文件内容
+type LicenseRecord struct {
+	LedgerEntry
+	Revoked      bool       `json:"revoked,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+	Data         []byte     `json:"data,omitempty"` // 加密后的License文件内容
+}
+
+// InventoryQuery 签发记录查询条件，零值字段表示不限制
+type InventoryQuery struct {
+	Text          string    // 匹配序列号、客户标记、客户ID、MAC地址或主板ID的子串
+	Product       string    // 产品
+	Plan          string    // 授权方案
+	Issuer        string    // 签发人
+	CustomerID    string    // 客户ID
+	Revoked       *bool     // 是否已吊销
+	Active        bool      // 只查询有效的License：未吊销、未到期且未被续期替代
+	ExpiresBefore time.Time // 在此时间前到期，不含永久授权
+}
+
+// Inventory 签发记录库，以JSON文件保存，可在多个goroutine中使用
+type Inventory struct {
+	path    string
+	mu      sync.RWMutex
+	records []*LicenseRecord
+}
+
+// OpenInventory 打开签发记录库，文件不存在时创建空记录库
+func OpenInventory(path string) (*Inventory, error) {
+	var inventory = &Inventory{path: path, records: make([]*LicenseRecord, 0)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inventory, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &inventory.records)
+	if err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+// save 保存记录库
+func (i *Inventory) save() error {
+	return saveJSON(i.path, i.records)
+}
+
+// saveJSON 先写临时文件再替换，避免写入中断损坏数据文件
+func saveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	var tmp = path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Add 保存签发记录
+func (i *Inventory) Add(record *LicenseRecord) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.records = append(i.records, record)
+	err := i.save()
+	if err != nil {
+		i.records = i.records[:len(i.records)-1]
+	}
+	return err
+}
+
+// remove 撤回最后添加的序列号为serial的签发记录，用于签发台账写入失败时回滚
+func (i *Inventory) remove(serial string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for j := len(i.records) - 1; j >= 0; j-- {
+		if i.records[j].Serial != serial {
+			continue
+		}
+		var previous = i.records
+		i.records = append(i.records[:j:j], i.records[j+1:]...)
+		err := i.save()
+		if err != nil {
+			i.records = previous
+		}
+		return err
+	}
+	return nil
+}
+
+// Get 按序列号获取签发记录的副本
+func (i *Inventory) Get(serial string) (*LicenseRecord, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, record := range i.records {
+		if record.Serial == serial {
+			var copied = *record
+			return &copied, nil
+		}
+	}
+	return nil, I18n.E(I18n.CodeLicenseNotFound, serial)
+}
+
+// Search 按条件查询签发记录，返回不含License文件内容的副本，按签发顺序排列
+func (i *Inventory) Search(query *InventoryQuery) []*LicenseRecord {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var result = make([]*LicenseRecord, 0)
+	var renewed = make(map[string]bool)
+	for _, record := range i.records {
+		if record.RenewOf != "" {
+			renewed[record.RenewOf] = true
+		}
+	}
+	var now = time.Now()
+	for _, record := range i.records {
+		if query != nil && !query.match(record, renewed, now) {
+			continue
+		}
+		var copied = *record
+		copied.Data = nil
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// Revoke 吊销License，已吊销时保持原吊销信息
+func (i *Inventory) Revoke(serial, reason string) (*LicenseRecord, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, record := range i.records {
+		if record.Serial != serial {
+			continue
+		}
+		if !record.Revoked {
+			var now = time.Now().UTC()
+			record.Revoked = true
+			record.RevokedAt = &now
+			record.RevokeReason = reason
+			err := i.save()
+			if err != nil {
+				record.Revoked, record.RevokedAt, record.RevokeReason = false, nil, ""
+				return nil, err
+			}
+		}
+		var copied = *record
+		return &copied, nil
+	}
+	return nil, I18n.E(I18n.CodeLicenseNotFound, serial)
+}
+
+func (q *InventoryQuery) match(record *LicenseRecord, renewed map[string]bool, now time.Time) bool {
+	if q.Text != "" {
+		var found = false
+		for _, field := range []string{record.Serial, record.CustomerTag, record.CustomerID, record.MacAddr, record.MotherBoardID} {
+			if strings.Contains(strings.ToLower(field), strings.ToLower(q.Text)) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Product != "" && record.Product != q.Product {
+		return false
+	}
+	if q.Plan != "" && record.Plan != q.Plan {
+		return false
+	}
+	if q.Issuer != "" && record.Issuer != q.Issuer {
+		return false
+	}
+	if q.CustomerID != "" && record.CustomerID != q.CustomerID {
+		return false
+	}
+	if q.Revoked != nil && record.Revoked != *q.Revoked {
+		return false
+	}
+	if q.Active && (record.Revoked || renewed[record.Serial] || record.Expired(now)) {
+		return false
+	}
+	if !q.ExpiresBefore.IsZero() {
+		if record.PermanentAuth {
+			return false
+		}
+		end, err := Timestamp.Parse(record.EndTime, "")
+		if err != nil || !end.Before(q.ExpiresBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expired License在now时是否已到期，永久授权不会到期
+func (r *LicenseRecord) Expired(now time.Time) bool {
+	if r.PermanentAuth {
+		return false
+	}
+	end, err := Timestamp.Parse(r.EndTime, "")
+	return err != nil || !end.After(now)
+}
+
+// TotalNodes 签发记录的授权节点总数
+func TotalNodes(records []*LicenseRecord) int {
+	var total = 0
+	for _, record := range records {
+		total += record.AllowNodes
+	}
+	return total
+}
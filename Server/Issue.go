@@ -0,0 +1,242 @@
+package Server
+
+import (
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"time"
+)
+
+// IssueRequest 非交互签发请求，指定方案时未填写的字段使用方案条款
+type IssueRequest struct {
+	NodeInfo    []byte    `json:"node_info"` // 客户端生成的node.info内容
+	Issuance              // 方案、产品、版本、签发人与放行理由，签发人为空时使用Server.Issuer
+	AllowNodes  int       `json:"allow_nodes,omitempty"`  // 允许接入的节点数
+	EndTime     time.Time `json:"end_time,omitempty"`     // 到期时间，为零值时按Days计算
+	Days        int       `json:"days,omitempty"`         // 授权天数
+	Permanent   bool      `json:"permanent,omitempty"`    // 永久授权（100年）
+	Features    []string  `json:"features,omitempty"`     // 授权的功能特性
+	CustomerTag string    `json:"customer_tag,omitempty"` // 客户标记，为空时使用客户ID或授权网卡MAC地址
+}
+
+// Issue 按请求签发License，返回License及加密后的文件内容。
+// 违反签发策略时需要在请求中填写放行理由，放行理由与违反项记录在签发台账中。
+func (s *Server) Issue(req *IssueRequest) (*Entity.License, []byte, error) {
+	return s.issue(req, nil)
+}
+
+// issue 签发License，deliver不为空时在记录签发前交付文件内容，交付失败不记录
+func (s *Server) issue(req *IssueRequest, deliver func(lic *Entity.License, data []byte) error) (*Entity.License, []byte, error) {
+	plan, err := s.plan(req.Plan)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err = s.applyPlan(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	lic, err := s.Preview(req.NodeInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	start, err := s.initTimes(lic)
+	if err != nil {
+		return nil, nil, err
+	}
+	lic.AllowNodes = req.AllowNodes
+	lic.Features = append([]string(nil), req.Features...)
+	switch {
+	case req.Permanent:
+		lic.PermanentAuth = true
+		lic.EndTime = Timestamp.Format(start.AddDate(100, 0, 0))
+	case !req.EndTime.IsZero():
+		lic.EndTime = Timestamp.Format(req.EndTime)
+	case req.Days > 0:
+		lic.EndTime = Timestamp.Format(start.AddDate(0, 0, req.Days))
+	default:
+		return nil, nil, s.err(I18n.CodeNoEndTime)
+	}
+	if !req.Permanent && !req.EndTime.IsZero() && !req.EndTime.After(start) {
+		return nil, nil, s.err(I18n.CodeEndBeforeStart, lic.EndTime, Timestamp.Format(start))
+	}
+	lic.CustomerTag = req.CustomerTag
+	if lic.CustomerTag == "" {
+		lic.CustomerTag = req.CustomerID
+	}
+	if lic.CustomerTag == "" {
+		lic.CustomerTag = lic.MacAddr
+	}
+	err = s.checkCustomerTag(plan, lic.CustomerTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	lic.CheckStatus = true
+
+	var issuance = req.Issuance
+	issuance.RenewOf = ""
+	data, err := s.seal(lic, &issuance, deliver)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lic, data, nil
+}
+
+// Preview 解析node.info但不签发，用于签发前确认硬件信息
+func (s *Server) Preview(nodeInfo []byte) (*Entity.License, error) {
+	offset, step := s.params()
+	lic, _, err := Utils.OpenLicense(nodeInfo, offset, step)
+	if Utils.IsTampered(err) {
+		return nil, s.err(I18n.CodeTamperedContact, s.DevInfo)
+	}
+	if err != nil && I18n.Code(err) == "" {
+		return nil, s.err(I18n.CodeBadNodeInfo, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lic, nil
+}
+
+// RenewRequest 续期请求，零值字段保持原License条款
+type RenewRequest struct {
+	EndTime    time.Time `json:"end_time,omitempty"`    // 新的到期时间，为零值时按Days计算
+	Days       int       `json:"days,omitempty"`        // 自原到期时间（已过期时自当前时间）起延长的天数
+	Permanent  bool      `json:"permanent,omitempty"`   // 改为永久授权
+	AllowNodes int       `json:"allow_nodes,omitempty"` // 新的节点数
+	Features   []string  `json:"features,omitempty"`    // 新的功能特性
+	Issuer     string    `json:"issuer,omitempty"`      // 签发人，为空时使用Server.Issuer
+	Override   string    `json:"override,omitempty"`    // 违反策略时的放行理由
+}
+
+// Renew 按签发记录库中的License续期，新License保持原硬件绑定与开始时间，并记录续期来源。
+// 签发时间更新为续期时间，签发策略与经销商证书的最长天数自续期时起算（见 License.Term）。
+func (s *Server) Renew(serial string, req *RenewRequest) (*Entity.License, []byte, error) {
+	if s.Inventory == nil {
+		return nil, nil, s.err(I18n.CodeNoInventory)
+	}
+	record, err := s.Inventory.Get(serial)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Revoked {
+		return nil, nil, s.err(I18n.CodeLicenseRevoked, serial)
+	}
+	offset, step := s.params()
+	lic, _, err := Utils.OpenLicense(record.Data, offset, step)
+	if err != nil {
+		return nil, nil, err
+	}
+	var now = time.Now()
+	lic.LicenseCreateTime = Timestamp.Format(now)
+	if req.AllowNodes > 0 {
+		lic.AllowNodes = req.AllowNodes
+	}
+	if req.Features != nil {
+		lic.Features = append([]string(nil), req.Features...)
+	}
+	switch {
+	case req.Permanent:
+		start, err := lic.StartAt()
+		if err != nil {
+			return nil, nil, err
+		}
+		lic.PermanentAuth = true
+		lic.EndTime = Timestamp.Format(start.AddDate(100, 0, 0))
+	case !req.EndTime.IsZero():
+		lic.EndTime = Timestamp.Format(req.EndTime)
+	case req.Days > 0:
+		end, err := lic.EndAt()
+		if err != nil || end.Before(now) {
+			end = now
+		}
+		lic.EndTime = Timestamp.Format(end.AddDate(0, 0, req.Days))
+	default:
+		return nil, nil, s.err(I18n.CodeNoEndTime)
+	}
+	var issuance = Issuance{
+		Plan:       record.Plan,
+		Product:    record.Product,
+		Edition:    record.Edition,
+		CustomerID: record.CustomerID,
+		Issuer:     req.Issuer,
+		Override:   req.Override,
+		RenewOf:    serial,
+	}
+	data, err := s.seal(lic, &issuance, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lic, data, nil
+}
+
+// Revoke 在签发记录库中吊销License
+func (s *Server) Revoke(serial, reason string) (*LicenseRecord, error) {
+	if s.Inventory == nil {
+		return nil, s.err(I18n.CodeNoInventory)
+	}
+	record, err := s.Inventory.Revoke(serial, reason)
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgLicenseRevoked), Logger.F(Logger.FieldSerial, serial), Logger.F("reason", reason))
+	return record, nil
+}
+
+// seal 按产品选择密钥与策略，检查客户与策略、附加证书链、加密签名并记录签发，签发人为空时使用Server.Issuer。
+// deliver不为空时先交付再记录，交付失败不记录签发。
+func (s *Server) seal(lic *Entity.License, issuance *Issuance, deliver func(lic *Entity.License, data []byte) error) ([]byte, error) {
+	s, err := s.withProduct(lic, issuance)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkCustomer(issuance)
+	if err != nil {
+		return nil, err
+	}
+	if issuance.Issuer == "" {
+		issuance.Issuer = s.Issuer
+	}
+	violations, err := s.checkPolicy(lic, issuance, false)
+	if err != nil {
+		return nil, err
+	}
+	err = s.applyResellerChain(lic)
+	if err != nil {
+		return nil, err
+	}
+	sealer, err := s.sealer()
+	if err != nil {
+		return nil, err
+	}
+	data, err := sealer.Seal(lic)
+	if err != nil {
+		return nil, err
+	}
+	if deliver != nil {
+		err = deliver(lic, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 台账记录失败时不交付License，已交付的由deliver的调用方撤回
+	err = s.record(lic, issuance, violations, data)
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgLicenseIssued), append(Logger.License(lic), Logger.F(Logger.FieldKeyID, signerKeyID(s.Signer)), Logger.F("issuer", issuance.Issuer))...)
+	return data, nil
+}
+
+// params 偏移量与步长，未设置时为1
+func (s *Server) params() (int, int) {
+	var offset, step = s.Offset, s.Step
+	if offset == 0 {
+		offset = 1
+	}
+	if step == 0 {
+		step = 1
+	}
+	return offset, step
+}
@@ -0,0 +1,291 @@
+package Server
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Keystore"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// KeyRingEntry 签发密钥，包含公钥信息与口令加密的私钥PEM
+type KeyRingEntry struct {
+	Trust.Key
+	PrivateKey string `json:"private_key"`
+}
+
+// KeyRing 签发端密钥环，最后一个未退役的密钥为当前签发密钥。
+// 同一密钥环中的私钥使用同一口令加密，口令为空时交互式输入。
+type KeyRing struct {
+	Keys []*KeyRingEntry `json:"keys"`
+	path string
+}
+
+// LoadKeyRing 读取密钥环文件
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ring = &KeyRing{path: path}
+	err = json.Unmarshal(data, ring)
+	if err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Save 保存密钥环，文件仅当前用户可读写。密钥环是全部私钥的唯一副本，
+// 先在同一目录写入0600权限的临时文件再改名替换，写入中断时原文件不受影响，已有文件的宽松权限也随之收紧。
+func (r *KeyRing) Save() error {
+	data, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), "."+filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// CreateTemp以0600权限创建
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Active 当前签发密钥
+func (r *KeyRing) Active() (*KeyRingEntry, error) {
+	for i := len(r.Keys) - 1; i >= 0; i-- {
+		if r.Keys[i].NotAfter == nil {
+			return r.Keys[i], nil
+		}
+	}
+	return nil, I18n.E(I18n.CodeNoActiveKey)
+}
+
+// Signer 解密当前签发密钥并返回签名器
+func (r *KeyRing) Signer(passphrase []byte) (Signer.Signer, error) {
+	entry, err := r.Active()
+	if err != nil {
+		return nil, err
+	}
+	key, err := Keystore.Decrypt([]byte(entry.PrivateKey), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.Signer()
+}
+
+// Private 解密指定密钥ID的私钥，包括已退役的密钥
+func (r *KeyRing) Private(keyID string, passphrase []byte) (crypto.Signer, error) {
+	for _, entry := range r.Keys {
+		if entry.KeyID != keyID {
+			continue
+		}
+		key, err := Keystore.Decrypt([]byte(entry.PrivateKey), passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return key.Private, nil
+	}
+	return nil, fmt.Errorf("%w: %s", Trust.ErrUnknownKey, keyID)
+}
+
+// ChangePassphrase 使用新口令重新加密全部私钥
+func (r *KeyRing) ChangePassphrase(oldPassphrase, newPassphrase []byte) error {
+	for _, entry := range r.Keys {
+		key, err := Keystore.Decrypt([]byte(entry.PrivateKey), oldPassphrase)
+		if err != nil {
+			return err
+		}
+		data, err := Keystore.Encrypt(key, newPassphrase)
+		if err != nil {
+			return err
+		}
+		entry.PrivateKey = string(data)
+	}
+	return nil
+}
+
+// TrustStore 导出全部公钥（含已退役密钥）供客户端使用
+func (r *KeyRing) TrustStore() *Trust.Store {
+	var store = new(Trust.Store)
+	for _, entry := range r.Keys {
+		var key = entry.Key
+		store.Add(&key)
+	}
+	return store
+}
+
+// generate 生成新密钥并加入密钥环，notBefore按秒截断以匹配License签发时间精度
+func (r *KeyRing) generate(suiteName string, notBefore time.Time, passphrase []byte) (*KeyRingEntry, error) {
+	suite, err := Suite.Get(suiteName)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	key, err := Trust.NewKey(suite, priv.Public(), notBefore.Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	data, err := Keystore.Encrypt(&Keystore.Key{KeyID: key.KeyID, Suite: suite.Name(), Private: priv}, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var entry = &KeyRingEntry{Key: *key, PrivateKey: string(data)}
+	r.Keys = append(r.Keys, entry)
+	return entry, nil
+}
+
+// Keygen 创建密钥环并生成首个签发密钥，passphrase为空时交互式输入
+func (s *Server) Keygen(ringPath, suiteName string, passphrase []byte) (*Trust.Key, error) {
+	if _, err := os.Stat(ringPath); err == nil {
+		return nil, s.err(I18n.CodeKeyRingExists)
+	}
+	passphrase, err := readPassphrase(passphrase, s.text(I18n.PromptSetPassphrase), true)
+	if err != nil {
+		return nil, err
+	}
+	var ring = &KeyRing{path: ringPath}
+	entry, err := ring.generate(suiteName, time.Now(), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	err = ring.Save()
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgKeyGenerated), Logger.F(Logger.FieldKeyID, entry.KeyID), Logger.F("suite", entry.Suite))
+	return &entry.Key, s.UseKeyRing(ringPath, passphrase)
+}
+
+// RotateKey 生成新的签发密钥并退役当前密钥，退役密钥的公钥仍保留在信任列表中
+func (s *Server) RotateKey(ringPath, suiteName string, passphrase []byte) (*Trust.Key, error) {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err = readPassphrase(passphrase, s.text(I18n.PromptPassphrase), false)
+	if err != nil {
+		return nil, err
+	}
+	// 确认口令与现有私钥一致，避免密钥环中出现不同口令
+	if _, err = ring.Signer(passphrase); err != nil {
+		return nil, err
+	}
+	if suiteName == "" {
+		if active, err := ring.Active(); err == nil {
+			suiteName = active.Suite
+		}
+	}
+	var now = time.Now().Truncate(time.Second)
+	for _, entry := range ring.Keys {
+		if entry.NotAfter == nil {
+			entry.NotAfter = &now
+		}
+	}
+	entry, err := ring.generate(suiteName, now, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	err = ring.Save()
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgKeyRotated), Logger.F(Logger.FieldKeyID, entry.KeyID), Logger.F("suite", entry.Suite))
+	return &entry.Key, s.UseKeyRing(ringPath, passphrase)
+}
+
+// UseKeyRing 使用密钥环的当前密钥签发，passphrase为空时交互式输入
+func (s *Server) UseKeyRing(ringPath string, passphrase []byte) error {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return err
+	}
+	passphrase, err = readPassphrase(passphrase, s.text(I18n.PromptPassphrase), false)
+	if err != nil {
+		return err
+	}
+	signer, err := ring.Signer(passphrase)
+	if err != nil {
+		return err
+	}
+	s.Signer = signer
+	s.Suite = signer.Suite()
+	return nil
+}
+
+// ExportTrustStore 导出客户端信任列表
+func (s *Server) ExportTrustStore(ringPath, outPath string) error {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return err
+	}
+	data, err := ring.TrustStore().Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// ChangeKeyRingPassphrase 修改密钥环口令，口令为空时交互式输入
+func (s *Server) ChangeKeyRingPassphrase(ringPath string, oldPassphrase, newPassphrase []byte) error {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return err
+	}
+	oldPassphrase, err = readPassphrase(oldPassphrase, s.text(I18n.PromptCurrentPassphrase), false)
+	if err != nil {
+		return err
+	}
+	newPassphrase, err = readPassphrase(newPassphrase, s.text(I18n.PromptNewPassphrase), true)
+	if err != nil {
+		return err
+	}
+	err = ring.ChangePassphrase(oldPassphrase, newPassphrase)
+	if err != nil {
+		return err
+	}
+	return ring.Save()
+}
+
+// ExportPublicKey 导出全部签发公钥（PEM），可通过 //go:embed 编译进客户端并用 Trust.LoadPEM 读取
+func (s *Server) ExportPublicKey(ringPath, outPath string) error {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return err
+	}
+	var data = make([]byte, 0)
+	for _, entry := range ring.Keys {
+		data = append(data, entry.Key.MarshalPEM()...)
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// readPassphrase 未提供口令时交互式输入
+func readPassphrase(passphrase []byte, label string, confirm bool) ([]byte, error) {
+	if len(passphrase) > 0 {
+		return passphrase, nil
+	}
+	return Keystore.ReadPassphrase(label, confirm)
+}
@@ -0,0 +1,109 @@
+package Server
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"os"
+	"time"
+)
+
+// LedgerEntry 签发台账记录，每次签发追加一行JSON
+type LedgerEntry struct {
+	Time          time.Time   `json:"time"`
+	Serial        string      `json:"serial"` // License序列号，与日志中的license_serial一致
+	KeyID         string      `json:"key_id,omitempty"`
+	Issuance                  // 产品、版本、签发人与放行理由
+	CustomerTag   string      `json:"customer_tag"`
+	MotherBoardID string      `json:"mother_board_id"`
+	MacAddr       string      `json:"mac_addr"`
+	AllowNodes    int         `json:"allow_nodes"`
+	StartTime     string      `json:"start_time"`
+	EndTime       string      `json:"end_time"`
+	PermanentAuth bool        `json:"permanent_auth"`
+	Features      []string    `json:"features,omitempty"`
+	Violations    []Violation `json:"violations,omitempty"` // 放行的策略违反项
+}
+
+// record 追加签发台账并保存到签发记录库，未配置时不记录
+func (s *Server) record(lic *Entity.License, issuance *Issuance, violations []Violation, data []byte) error {
+	var entry = &LedgerEntry{
+		Time:          time.Now().UTC(),
+		Serial:        Logger.Serial(lic),
+		KeyID:         signerKeyID(s.Signer),
+		Issuance:      *issuance,
+		CustomerTag:   lic.CustomerTag,
+		MotherBoardID: lic.MotherBoardID,
+		MacAddr:       lic.MacAddr,
+		AllowNodes:    lic.AllowNodes,
+		StartTime:     lic.StartTime,
+		EndTime:       lic.EndTime,
+		PermanentAuth: lic.PermanentAuth,
+		Features:      lic.Features,
+	}
+	if len(violations) > 0 {
+		entry.Violations = violations
+	}
+	if s.Inventory != nil {
+		err := s.Inventory.Add(&LicenseRecord{LedgerEntry: *entry, Data: data})
+		if err != nil {
+			return err
+		}
+	}
+	err := s.appendLedger(entry)
+	if err != nil && s.Inventory != nil {
+		// 台账写入失败时撤回签发记录，签发记录库与台账保持一致
+		if rollbackErr := s.Inventory.remove(entry.Serial); rollbackErr != nil {
+			s.logger().Error(s.text(I18n.MsgLedgerRollback), Logger.F(Logger.FieldSerial, entry.Serial), Logger.Err(rollbackErr))
+		}
+	}
+	return err
+}
+
+// appendLedger 追加一行签发台账，未配置台账路径时忽略
+func (s *Server) appendLedger(entry *LedgerEntry) error {
+	if s.LedgerPath == "" {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.LedgerPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadLedger 读取签发台账
+func ReadLedger(path string) ([]*LedgerEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	var entries = make([]*LedgerEntry, 0)
+	var scanner = bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry = new(LedgerEntry)
+		err = json.Unmarshal(scanner.Bytes(), entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,124 @@
+package Server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/manifoldco/promptui"
+	"os"
+	"regexp"
+)
+
+// Plan 授权方案，定义同一类License的默认条款，签发时可逐项覆盖
+type Plan struct {
+	Name               string   `json:"name"`                           // 方案名称，如Standard-1y
+	Product            string   `json:"product,omitempty"`              // 产品
+	Edition            string   `json:"edition,omitempty"`              // 版本
+	AllowNodes         int      `json:"allow_nodes,omitempty"`          // 节点数
+	Days               int      `json:"days,omitempty"`                 // 授权天数
+	Permanent          bool     `json:"permanent,omitempty"`            // 永久授权
+	Features           []string `json:"features,omitempty"`             // 功能特性
+	CustomerTagPattern string   `json:"customer_tag_pattern,omitempty"` // 客户标记需匹配的正则表达式
+}
+
+// LoadPlans 读取授权方案文件，文件内容为方案数组，方案名称不能为空或重复
+func LoadPlans(path string) ([]*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plans = make([]*Plan, 0)
+	err = json.Unmarshal(data, &plans)
+	if err != nil {
+		return nil, err
+	}
+	var names = make(map[string]bool, len(plans))
+	for i, plan := range plans {
+		if plan.Name == "" {
+			return nil, I18n.E(I18n.CodePlanNoName, i+1)
+		}
+		if names[plan.Name] {
+			return nil, I18n.E(I18n.CodePlanDuplicate, plan.Name)
+		}
+		names[plan.Name] = true
+		if _, err = regexp.Compile(plan.CustomerTagPattern); err != nil {
+			return nil, fmt.Errorf("%s: %w", plan.Name, err)
+		}
+	}
+	return plans, nil
+}
+
+// plan 按名称查找授权方案，名称为空时返回nil
+func (s *Server) plan(name string) (*Plan, error) {
+	if name == "" {
+		return nil, nil
+	}
+	for _, plan := range s.Plans {
+		if plan.Name == name {
+			return plan, nil
+		}
+	}
+	return nil, s.err(I18n.CodeUnknownPlan, name)
+}
+
+// applyPlan 以方案条款补全请求中未填写的字段，返回新的请求
+func (s *Server) applyPlan(req *IssueRequest) (*IssueRequest, error) {
+	plan, err := s.plan(req.Plan)
+	if err != nil || plan == nil {
+		return req, err
+	}
+	var merged = *req
+	if merged.Product == "" {
+		merged.Product = plan.Product
+	}
+	if merged.Edition == "" {
+		merged.Edition = plan.Edition
+	}
+	if merged.AllowNodes == 0 {
+		merged.AllowNodes = plan.AllowNodes
+	}
+	if !merged.Permanent && merged.EndTime.IsZero() && merged.Days == 0 {
+		merged.Permanent = plan.Permanent
+		merged.Days = plan.Days
+	}
+	if merged.Features == nil {
+		merged.Features = plan.Features
+	}
+	return &merged, nil
+}
+
+// checkCustomerTag 校验客户标记符合方案格式，格式需匹配整个客户标记
+func (s *Server) checkCustomerTag(plan *Plan, tag string) error {
+	if plan == nil || plan.CustomerTagPattern == "" {
+		return nil
+	}
+	matched, err := regexp.MatchString("^(?:"+plan.CustomerTagPattern+")$", tag)
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return s.err(I18n.CodePlanCustomerTag, tag, plan.Name, plan.CustomerTagPattern)
+	}
+	return nil
+}
+
+// selectPlan 配置了授权方案时交互式选择，选择自定义时返回nil
+func (s *Server) selectPlan() (*Plan, error) {
+	if len(s.Plans) == 0 {
+		return nil, nil
+	}
+	var items = make([]string, 0, len(s.Plans)+1)
+	for _, plan := range s.Plans {
+		items = append(items, plan.Name)
+	}
+	items = append(items, s.text(I18n.MsgCustomPlan))
+	promptSelect := promptui.Select{
+		Label: s.text(I18n.PromptPlan),
+		Items: items,
+	}
+	i, _, err := promptSelect.Run()
+	if err != nil || i == len(s.Plans) {
+		return nil, err
+	}
+	return s.Plans[i], nil
+}
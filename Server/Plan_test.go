@@ -0,0 +1,145 @@
+package Server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+func testPlans() []*Plan {
+	return []*Plan{
+		{Name: "standard", Product: "edge", Edition: "standard", AllowNodes: 8, Days: 365, Features: []string{"sso"}, CustomerTagPattern: `ACME-\d+|BETA`},
+		{Name: "forever", AllowNodes: 4, Permanent: true, Features: []string{"sso", "audit"}},
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Plans = testPlans()
+	var end = time.Now().AddDate(0, 0, 90)
+	var cases = []struct {
+		name string
+		req  *IssueRequest
+		want IssueRequest
+	}{
+		{"plan terms", &IssueRequest{Issuance: Issuance{Plan: "standard"}},
+			IssueRequest{Issuance: Issuance{Plan: "standard", Product: "edge", Edition: "standard"}, AllowNodes: 8, Days: 365, Features: []string{"sso"}}},
+		{"overrides", &IssueRequest{Issuance: Issuance{Plan: "standard", Product: "core", Edition: "pro"}, AllowNodes: 20, Days: 30, Features: []string{"audit"}},
+			IssueRequest{Issuance: Issuance{Plan: "standard", Product: "core", Edition: "pro"}, AllowNodes: 20, Days: 30, Features: []string{"audit"}}},
+		{"no features", &IssueRequest{Issuance: Issuance{Plan: "standard"}, Features: []string{}},
+			IssueRequest{Issuance: Issuance{Plan: "standard", Product: "edge", Edition: "standard"}, AllowNodes: 8, Days: 365, Features: []string{}}},
+		{"permanent plan", &IssueRequest{Issuance: Issuance{Plan: "forever"}},
+			IssueRequest{Issuance: Issuance{Plan: "forever"}, AllowNodes: 4, Permanent: true, Features: []string{"sso", "audit"}}},
+		{"days override permanent", &IssueRequest{Issuance: Issuance{Plan: "forever"}, Days: 30},
+			IssueRequest{Issuance: Issuance{Plan: "forever"}, AllowNodes: 4, Days: 30, Features: []string{"sso", "audit"}}},
+		{"end time overrides permanent", &IssueRequest{Issuance: Issuance{Plan: "forever"}, EndTime: end},
+			IssueRequest{Issuance: Issuance{Plan: "forever"}, AllowNodes: 4, EndTime: end, Features: []string{"sso", "audit"}}},
+		{"end time overrides days", &IssueRequest{Issuance: Issuance{Plan: "standard"}, EndTime: end},
+			IssueRequest{Issuance: Issuance{Plan: "standard", Product: "edge", Edition: "standard"}, AllowNodes: 8, EndTime: end, Features: []string{"sso"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var before = *c.req
+			got, err := server.applyPlan(c.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Issuance != c.want.Issuance || got.AllowNodes != c.want.AllowNodes || got.Days != c.want.Days ||
+				got.Permanent != c.want.Permanent || !got.EndTime.Equal(c.want.EndTime) {
+				t.Errorf("applyPlan = %+v, want %+v", got, c.want)
+			}
+			if (got.Features == nil) != (c.want.Features == nil) || strings.Join(got.Features, ",") != strings.Join(c.want.Features, ",") {
+				t.Errorf("Features = %#v, want %#v", got.Features, c.want.Features)
+			}
+			if c.req.AllowNodes != before.AllowNodes || c.req.Product != before.Product || c.req.Permanent != before.Permanent {
+				t.Errorf("request modified: %+v", c.req)
+			}
+		})
+	}
+
+	var req = &IssueRequest{AllowNodes: 3}
+	if got, err := server.applyPlan(req); err != nil || got != req {
+		t.Errorf("applyPlan without a plan = %+v, %v", got, err)
+	}
+}
+
+// TestIssuePlan 方案条款写入License，请求中的天数覆盖永久授权，空功能列表不继承方案功能
+func TestIssuePlan(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Plans = testPlans()
+	lic, _, err := server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), Issuance: Issuance{Plan: "forever"}, Days: 30, Features: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lic.PermanentAuth || lic.AllowNodes != 4 || len(lic.Features) != 0 {
+		t.Errorf("license = %+v", lic)
+	}
+
+	_, _, err = server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), Issuance: Issuance{Plan: "gold"}, AllowNodes: 8, Days: 30})
+	if I18n.Code(err) != I18n.CodeUnknownPlan {
+		t.Fatalf("Issue error = %v, want %s", err, I18n.CodeUnknownPlan)
+	}
+	if _, err = server.applyPlan(&IssueRequest{Issuance: Issuance{Plan: "gold"}}); I18n.Code(err) != I18n.CodeUnknownPlan {
+		t.Fatalf("applyPlan error = %v, want %s", err, I18n.CodeUnknownPlan)
+	}
+}
+
+// TestCheckCustomerTag 方案格式需匹配整个客户标记
+func TestCheckCustomerTag(t *testing.T) {
+	server, _ := newTestServer(t)
+	var plan = testPlans()[0]
+	for tag, ok := range map[string]bool{
+		"ACME-1":     true,
+		"ACME-2024":  true,
+		"BETA":       true,
+		"ACME-":      false,
+		"xACME-1":    false,
+		"ACME-1x":    false,
+		"ACME-1BETA": false,
+		"":           false,
+	} {
+		err := server.checkCustomerTag(plan, tag)
+		if ok && err != nil {
+			t.Errorf("checkCustomerTag(%q) = %v", tag, err)
+		}
+		if !ok && I18n.Code(err) != I18n.CodePlanCustomerTag {
+			t.Errorf("checkCustomerTag(%q) = %v, want %s", tag, err, I18n.CodePlanCustomerTag)
+		}
+	}
+	if err := server.checkCustomerTag(nil, "anything"); err != nil {
+		t.Errorf("checkCustomerTag without a plan = %v", err)
+	}
+	if err := server.checkCustomerTag(testPlans()[1], "anything"); err != nil {
+		t.Errorf("checkCustomerTag without a pattern = %v", err)
+	}
+}
+
+func TestLoadPlans(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "plans.json")
+	var load = func(content string) ([]*Plan, error) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadPlans(path)
+	}
+	plans, err := load(`[{"name": "standard", "days": 365}, {"name": "forever", "permanent": true}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 || plans[1].Name != "forever" || !plans[1].Permanent {
+		t.Errorf("plans = %+v", plans)
+	}
+	if _, err = load(`[{"name": "standard"}, {"days": 30}]`); I18n.Code(err) != I18n.CodePlanNoName {
+		t.Errorf("LoadPlans error = %v, want %s", err, I18n.CodePlanNoName)
+	}
+	if _, err = load(`[{"name": "standard"}, {"name": "standard", "days": 30}]`); I18n.Code(err) != I18n.CodePlanDuplicate {
+		t.Errorf("LoadPlans error = %v, want %s", err, I18n.CodePlanDuplicate)
+	}
+	if _, err = load(`[{"name": "standard", "customer_tag_pattern": "("}]`); err == nil || !strings.Contains(err.Error(), "standard") {
+		t.Errorf("LoadPlans error = %v, want a pattern error", err)
+	}
+}
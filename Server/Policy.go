@@ -0,0 +1,223 @@
+package Server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/manifoldco/promptui"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrPolicyViolation 签发违反策略且未填写放行理由
+var ErrPolicyViolation error = I18n.E(I18n.CodePolicyViolation)
+
+// 策略规则
+const (
+	RuleProduct   = "product"
+	RuleEdition   = "edition"
+	RuleFeature   = "feature"
+	RuleMinNodes  = "min_nodes"
+	RuleMaxNodes  = "max_nodes"
+	RuleMaxDays   = "max_days"
+	RulePermanent = "permanent"
+)
+
+// Policy 签发策略，以JSON文件配置，零值字段表示不限制
+type Policy struct {
+	Products         []string            `json:"products,omitempty"`          // 允许签发的产品
+	Editions         map[string][]string `json:"editions,omitempty"`          // 版本及其允许的功能特性
+	MinNodes         int                 `json:"min_nodes,omitempty"`         // 最少节点数
+	MaxNodes         int                 `json:"max_nodes,omitempty"`         // 最多节点数
+	MaxDays          int                 `json:"max_days,omitempty"`          // 非永久授权的最长天数
+	PermanentIssuers []string            `json:"permanent_issuers,omitempty"` // 允许签发永久授权的签发人，未配置时不限制，"*"表示所有人
+}
+
+// DefaultPolicy 未配置策略时使用，与此前固定的最少3个节点一致
+func DefaultPolicy() *Policy {
+	return &Policy{MinNodes: 3}
+}
+
+// LoadPolicy 读取签发策略文件，拼错的字段名视为错误，以免规则被静默忽略
+func LoadPolicy(path string) (*Policy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	var policy = new(Policy)
+	var decoder = json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(policy)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Issuance 签发上下文，用于策略判断并记录在签发台账中
+type Issuance struct {
+	Plan       string `json:"plan,omitempty"`        // 授权方案
+	Product    string `json:"product,omitempty"`     // 产品
+	Edition    string `json:"edition,omitempty"`     // 版本
+	Issuer     string `json:"issuer,omitempty"`      // 签发人
+	CustomerID string `json:"customer_id,omitempty"` // 客户库中的客户ID
+	Override   string `json:"override,omitempty"`    // 违反策略时的放行理由
+	RenewOf    string `json:"renew_of,omitempty"`    // 续期或迁移的原License序列号，由Renew与MigrateLicFile设置
+}
+
+// Violation 违反的策略规则
+type Violation struct {
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+// PolicyError 违反签发策略且未填写放行理由，errors.Is(err, ErrPolicyViolation)成立
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	var details = make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		details = append(details, v.Detail)
+	}
+	return fmt.Sprintf("%s: %s", ErrPolicyViolation, strings.Join(details, "; "))
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// Evaluate 按策略检查License条款，返回全部违反的规则，违反项说明使用locale语言。
+// 授权天数按 License.Term 计算，续期自续期时起算；无法计算时视为违反MaxDays。
+func (p *Policy) Evaluate(lic *Entity.License, issuance *Issuance, locale I18n.Locale) []Violation {
+	var violations = make([]Violation, 0)
+	var add = func(rule string, code I18n.ID, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Detail: I18n.T(locale, code, args...)})
+	}
+	if len(p.Products) > 0 && !contains(p.Products, issuance.Product) {
+		add(RuleProduct, I18n.CodePolicyProduct, issuance.Product)
+	}
+	if len(p.Editions) > 0 {
+		features, ok := p.Editions[issuance.Edition]
+		if !ok {
+			add(RuleEdition, I18n.CodePolicyEdition, issuance.Edition)
+		} else {
+			for _, feature := range lic.Features {
+				if !contains(features, feature) {
+					add(RuleFeature, I18n.CodePolicyFeature, issuance.Edition, feature)
+				}
+			}
+		}
+	}
+	if p.MinNodes > 0 && lic.AllowNodes < p.MinNodes {
+		add(RuleMinNodes, I18n.CodePolicyMinNodes, p.MinNodes)
+	}
+	if p.MaxNodes > 0 && lic.AllowNodes > p.MaxNodes {
+		add(RuleMaxNodes, I18n.CodePolicyMaxNodes, p.MaxNodes)
+	}
+	if lic.PermanentAuth {
+		if p.PermanentIssuers != nil && !contains(p.PermanentIssuers, "*") && !contains(p.PermanentIssuers, issuance.Issuer) {
+			add(RulePermanent, I18n.CodePolicyPermanent, issuance.Issuer)
+		}
+	} else if p.MaxDays > 0 {
+		// 无法计算有效期时按超出处理，不能放行
+		term, err := lic.Term()
+		if err != nil || term > time.Duration(p.MaxDays)*24*time.Hour {
+			add(RuleMaxDays, I18n.CodePolicyMaxDays, p.MaxDays)
+		}
+	}
+	return violations
+}
+
+// policy 当前签发策略，未配置时使用默认策略
+func (s *Server) policy() *Policy {
+	if s.Policy == nil {
+		return DefaultPolicy()
+	}
+	return s.Policy
+}
+
+// checkPolicy 按签发策略检查License条款。违反策略时必须填写放行理由并配置签发台账，
+// interactive为true时提示输入放行理由；返回被放行的违反项
+func (s *Server) checkPolicy(lic *Entity.License, issuance *Issuance, interactive bool) ([]Violation, error) {
+	var violations = s.policy().Evaluate(lic, issuance, s.Locale)
+	if len(violations) == 0 {
+		return nil, nil
+	}
+	var details = make([]string, 0, len(violations))
+	for _, v := range violations {
+		details = append(details, v.Detail)
+	}
+	if interactive && issuance.Override == "" {
+		for _, detail := range details {
+			fmt.Println(s.text(I18n.MsgPolicyViolation, detail))
+		}
+		prompt := promptui.Prompt{
+			Label: s.text(I18n.PromptOverride),
+		}
+		result, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		issuance.Override = strings.TrimSpace(result)
+	}
+	if issuance.Override == "" {
+		return nil, &PolicyError{Violations: violations}
+	}
+	if s.LedgerPath == "" {
+		return nil, s.err(I18n.CodeNoLedger)
+	}
+	s.logger().Warn(s.text(I18n.MsgPolicyOverridden), Logger.F("issuer", issuance.Issuer), Logger.F("override", issuance.Override), Logger.F("violations", strings.Join(details, "; ")))
+	return violations, nil
+}
+
+// selectProduct 策略限定产品与版本且方案未指定时交互式选择，选择版本后授权该版本的全部功能特性
+func (s *Server) selectProduct(lic *Entity.License, issuance *Issuance) error {
+	var policy = s.policy()
+	if len(policy.Products) > 0 && issuance.Product == "" {
+		promptSelect := promptui.Select{
+			Label: s.text(I18n.PromptProduct),
+			Items: policy.Products,
+		}
+		_, result, err := promptSelect.Run()
+		if err != nil {
+			return err
+		}
+		issuance.Product = result
+	}
+	if len(policy.Editions) > 0 && issuance.Edition == "" {
+		var editions = make([]string, 0, len(policy.Editions))
+		for edition := range policy.Editions {
+			editions = append(editions, edition)
+		}
+		sort.Strings(editions)
+		promptSelect := promptui.Select{
+			Label: s.text(I18n.PromptEdition),
+			Items: editions,
+		}
+		_, result, err := promptSelect.Run()
+		if err != nil {
+			return err
+		}
+		issuance.Edition = result
+		lic.Features = append([]string(nil), policy.Editions[result]...)
+	}
+	return nil
+}
+
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
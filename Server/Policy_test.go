@@ -0,0 +1,200 @@
+package Server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+// TestRenewMaxDays 续期保留原开始时间，授权天数自续期时起算
+func TestRenewMaxDays(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Policy = &Policy{MaxDays: 400}
+	// 2020年签发、一年后到期的License
+	_, old := legacyLicense(t, server, true)
+	lic, _, err := server.Renew(Logger.Serial(old), &RenewRequest{Days: 30})
+	if err != nil {
+		t.Fatalf("renew within max_days: %v", err)
+	}
+	if lic.StartTime != old.StartTime {
+		t.Errorf("StartTime = %q, want %q", lic.StartTime, old.StartTime)
+	}
+
+	_, _, err = server.Renew(Logger.Serial(lic), &RenewRequest{EndTime: time.Now().AddDate(0, 0, 401)})
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || policyErr.Violations[0].Rule != RuleMaxDays {
+		t.Fatalf("renew beyond max_days error = %v", err)
+	}
+}
+
+func TestEvaluateLocale(t *testing.T) {
+	var now = time.Now()
+	var lic = &Entity.License{
+		StartTime:         Timestamp.Format(now),
+		EndTime:           Timestamp.Format(now.AddDate(0, 0, 30)),
+		LicenseCreateTime: Timestamp.Format(now),
+		AllowNodes:        1,
+	}
+	var policy = &Policy{MinNodes: 3}
+	for _, locale := range []I18n.Locale{I18n.EN, I18n.ZH} {
+		var violations = policy.Evaluate(lic, &Issuance{}, locale)
+		if len(violations) != 1 {
+			t.Fatalf("violations = %+v", violations)
+		}
+		if want := I18n.T(locale, I18n.CodePolicyMinNodes, 3); violations[0].Detail != want {
+			t.Errorf("%s detail = %q, want %q", locale, violations[0].Detail, want)
+		}
+	}
+}
+
+// TestEvaluateMaxDaysBadTerm 有效期无法计算时按违反MaxDays处理
+func TestEvaluateMaxDaysBadTerm(t *testing.T) {
+	var lic = &Entity.License{
+		StartTime:         Timestamp.Format(time.Now()),
+		EndTime:           "not a time",
+		LicenseCreateTime: Timestamp.Format(time.Now()),
+		AllowNodes:        1,
+	}
+	var violations = (&Policy{MaxDays: 30}).Evaluate(lic, &Issuance{}, I18n.ZH)
+	if len(violations) != 1 || violations[0].Rule != RuleMaxDays {
+		t.Fatalf("violations = %+v, want max_days", violations)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(path, []byte(`{"max_nodes": 10, "permanent_issuers": ["alice"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.MaxNodes != 10 || len(policy.PermanentIssuers) != 1 {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	// 拼错的字段名不能被忽略，否则对应规则不生效
+	if err = os.WriteFile(path, []byte(`{"max_node": 10}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadPolicy(path); err == nil || !strings.Contains(err.Error(), "max_node") {
+		t.Fatalf("LoadPolicy error = %v, want unknown field", err)
+	}
+}
+
+// TestPolicyOverride 违反策略时未填写放行理由拒绝签发，填写后签发并将理由与违反项写入台账
+func TestPolicyOverride(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Policy = &Policy{MaxNodes: 10}
+	var req = &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 100, Days: 30}
+	_, _, err := server.Issue(req)
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrPolicyViolation) || policyErr.Violations[0].Rule != RuleMaxNodes {
+		t.Fatalf("Issue error = %v, want a max_nodes violation", err)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("signed %d licenses without an override", calls)
+	}
+	if _, err = os.Stat(server.LedgerPath); !os.IsNotExist(err) {
+		t.Errorf("refused license written to the ledger: %v", err)
+	}
+
+	req.Override = "framework agreement"
+	req.Issuer = "alice"
+	lic, _, err := server.Issue(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadLedger(server.LedgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("ledger entries = %d, want 1", len(entries))
+	}
+	var entry = entries[0]
+	if entry.Serial != Logger.Serial(lic) || entry.Override != "framework agreement" || entry.Issuer != "alice" {
+		t.Errorf("ledger entry = %+v", entry)
+	}
+	if len(entry.Violations) != 1 || entry.Violations[0].Rule != RuleMaxNodes {
+		t.Errorf("ledger violations = %+v", entry.Violations)
+	}
+}
+
+// TestCheckPolicyNoLedger 未配置签发台账时不能放行违反策略的签发
+func TestCheckPolicyNoLedger(t *testing.T) {
+	server, _ := newTestServer(t)
+	server.Policy = &Policy{MaxNodes: 10}
+	server.LedgerPath = ""
+	_, _, err := server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 100, Days: 30, Issuance: Issuance{Override: "urgent"}})
+	if I18n.Code(err) != I18n.CodeNoLedger {
+		t.Fatalf("Issue error = %v, want %s", err, I18n.CodeNoLedger)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("signed %d licenses without a ledger", calls)
+	}
+
+	// 未违反策略时不需要签发台账
+	if _, _, err = server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 30}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	var now = time.Now()
+	var license = func(permanent bool, features ...string) *Entity.License {
+		return &Entity.License{
+			StartTime:         Timestamp.Format(now),
+			EndTime:           Timestamp.Format(now.AddDate(0, 0, 30)),
+			LicenseCreateTime: Timestamp.Format(now),
+			AllowNodes:        8,
+			PermanentAuth:     permanent,
+			Features:          features,
+		}
+	}
+	var editions = map[string][]string{"standard": {"sso"}, "enterprise": {"sso", "audit"}}
+	var cases = []struct {
+		name     string
+		policy   *Policy
+		lic      *Entity.License
+		issuance *Issuance
+		rules    []string
+	}{
+		{"permanent unrestricted", &Policy{}, license(true), &Issuance{Issuer: "bob"}, nil},
+		{"permanent issuer allowed", &Policy{PermanentIssuers: []string{"alice"}}, license(true), &Issuance{Issuer: "alice"}, nil},
+		{"permanent issuer refused", &Policy{PermanentIssuers: []string{"alice"}}, license(true), &Issuance{Issuer: "bob"}, []string{RulePermanent}},
+		{"permanent nobody", &Policy{PermanentIssuers: []string{}}, license(true), &Issuance{Issuer: "alice"}, []string{RulePermanent}},
+		{"permanent everyone", &Policy{PermanentIssuers: []string{"*"}}, license(true), &Issuance{Issuer: "bob"}, nil},
+		{"permanent ignores max days", &Policy{MaxDays: 10}, license(true), &Issuance{}, nil},
+		{"edition features", &Policy{Editions: editions}, license(false, "sso", "audit"), &Issuance{Edition: "enterprise"}, nil},
+		{"feature outside edition", &Policy{Editions: editions}, license(false, "sso", "audit"), &Issuance{Edition: "standard"}, []string{RuleFeature}},
+		{"unknown edition", &Policy{Editions: editions}, license(false, "sso"), &Issuance{Edition: "gold"}, []string{RuleEdition}},
+		{"product allowed", &Policy{Products: []string{"edge"}}, license(false), &Issuance{Product: "edge"}, nil},
+		{"product refused", &Policy{Products: []string{"edge"}}, license(false), &Issuance{Product: "core"}, []string{RuleProduct}},
+		{"product missing", &Policy{Products: []string{"edge"}}, license(false), &Issuance{}, []string{RuleProduct}},
+		{"all violations", &Policy{Products: []string{"edge"}, Editions: editions, MinNodes: 10, PermanentIssuers: []string{"alice"}}, license(true, "audit"), &Issuance{Edition: "standard", Issuer: "bob"}, []string{RuleProduct, RuleFeature, RuleMinNodes, RulePermanent}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var violations = c.policy.Evaluate(c.lic, c.issuance, I18n.EN)
+			var rules = make([]string, 0, len(violations))
+			for _, v := range violations {
+				rules = append(rules, v.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(c.rules, ",") {
+				t.Errorf("rules = %v, want %v", rules, c.rules)
+			}
+		})
+	}
+}
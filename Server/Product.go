@@ -0,0 +1,97 @@
+package Server
+
+import (
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/manifoldco/promptui"
+	"sort"
+)
+
+// Product 产品配置，每个产品使用独立的签发密钥与签发策略，
+// 客户端以产品ID区分，只接受本产品的License
+type Product struct {
+	Signer Signer.Signer // 产品签发密钥
+	Policy *Policy       // 产品签发策略，为空时使用Server.Policy
+	// SharedSigner 为true时未配置Signer的产品使用Server.Signer签发；
+	// 默认拒绝签发，避免漏配密钥的产品与其它产品共用签发密钥
+	SharedSigner bool
+}
+
+// UseProductKeyRing 使用密钥环的当前密钥签发指定产品，passphrase为空时交互式输入
+func (s *Server) UseProductKeyRing(productID, ringPath string, passphrase []byte) error {
+	ring, err := LoadKeyRing(ringPath)
+	if err != nil {
+		return err
+	}
+	passphrase, err = readPassphrase(passphrase, s.text(I18n.PromptPassphrase), false)
+	if err != nil {
+		return err
+	}
+	signer, err := ring.Signer(passphrase)
+	if err != nil {
+		return err
+	}
+	if s.Products == nil {
+		s.Products = make(map[string]*Product)
+	}
+	if s.Products[productID] == nil {
+		s.Products[productID] = new(Product)
+	}
+	s.Products[productID].Signer = signer
+	return nil
+}
+
+// withProduct 确定签发的产品并写入License，返回使用该产品密钥与策略的Server副本。
+// 产品以签发请求为准，未指定时使用node.info中的产品；两者不一致时拒绝签发。
+func (s *Server) withProduct(lic *Entity.License, issuance *Issuance) (*Server, error) {
+	var id = issuance.Product
+	if id == "" {
+		id = lic.ProductID
+	}
+	if lic.ProductID != "" && lic.ProductID != id {
+		return nil, s.err(I18n.CodeNodeInfoProduct, lic.ProductID, id)
+	}
+	issuance.Product = id
+	lic.ProductID = id
+	if len(s.Products) == 0 {
+		return s, nil
+	}
+	product, ok := s.Products[id]
+	if !ok {
+		return nil, s.err(I18n.CodeUnknownProduct, id)
+	}
+	var ps = *s
+	switch {
+	case product.Signer != nil:
+		ps.Signer = product.Signer
+		ps.Suite = product.Signer.Suite()
+	case !product.SharedSigner:
+		return nil, s.err(I18n.CodeProductSigner, id)
+	}
+	if product.Policy != nil {
+		ps.Policy = product.Policy
+	}
+	return &ps, nil
+}
+
+// selectProductServer node.info未指定产品且配置了多个产品时交互式选择
+func (s *Server) selectProductServer(lic *Entity.License, issuance *Issuance) (*Server, error) {
+	if lic.ProductID == "" && issuance.Product == "" && len(s.Products) > 0 {
+		var ids = make([]string, 0, len(s.Products))
+		for id := range s.Products {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		promptSelect := promptui.Select{
+			Label: s.text(I18n.PromptProduct),
+			Items: ids,
+		}
+		_, result, err := promptSelect.Run()
+		if err != nil {
+			return nil, err
+		}
+		issuance.Product = result
+	}
+	return s.withProduct(lic, issuance)
+}
@@ -0,0 +1,212 @@
+package Server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 提醒邮件未发送的原因
+const (
+	ReminderNoCustomer = "no_customer" // License未关联客户库中的客户
+	ReminderNoContact  = "no_contact"  // 客户没有联系人邮箱
+	ReminderTemplate   = "template"    // 模板读取或渲染失败
+	ReminderSend       = "send"        // 邮件发送失败
+)
+
+// Mailer 邮件发送接口，测试时可替换为本地SMTP桩
+type Mailer interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// SMTPMailer 通过SMTP中继发送邮件，服务器支持时使用STARTTLS
+type SMTPMailer struct {
+	Addr     string // 中继地址，host:port
+	Username string // 用户名，为空时不认证
+	Password string
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(from string, to []string, msg []byte) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, from, to, msg)
+}
+
+// Reminder 到期提醒邮件配置。邮件模板为text/template文件，需定义subject与body两个模板；
+// 模板目录中的<客户ID>.tmpl优先，其次default.tmpl，都不存在时使用按Server.Locale选择的内置模板
+type Reminder struct {
+	Mailer      Mailer
+	From        string
+	Cc          []string // 抄送，如客户经理
+	TemplateDir string   // 自定义模板目录，为空时使用内置模板
+}
+
+// ReminderData 提醒邮件模板数据
+type ReminderData struct {
+	Customer *Customer
+	Licenses []*ExpiryEntry
+	Within   int // 报告的到期天数
+}
+
+// ReminderResult 一个客户的提醒邮件发送结果，未关联客户的License每个单独列出
+type ReminderResult struct {
+	CustomerID string   `json:"customer_id,omitempty"`
+	Serials    []string `json:"serials"`
+	To         []string `json:"to,omitempty"`
+	Sent       bool     `json:"sent"`
+	Reason     string   `json:"reason,omitempty"` // 未发送原因分类
+	Error      string   `json:"error,omitempty"`
+}
+
+// SendReminders 按客户汇总到期报告中的License，向客户联系人发送提醒邮件。
+// 单个客户发送失败不影响其它客户，结果中记录失败原因。
+func (s *Server) SendReminders(report *ExpiryReport, reminder *Reminder) ([]*ReminderResult, error) {
+	if reminder.Mailer == nil || reminder.From == "" {
+		return nil, s.err(I18n.CodeNoMailer)
+	}
+	var results = make([]*ReminderResult, 0)
+	var groups = make(map[string][]*ExpiryEntry)
+	var order = make([]string, 0)
+	for _, entry := range report.Entries {
+		if entry.CustomerID == "" || s.Customers == nil {
+			results = append(results, &ReminderResult{Serials: []string{entry.Serial}, Reason: ReminderNoCustomer})
+			continue
+		}
+		if _, ok := groups[entry.CustomerID]; !ok {
+			order = append(order, entry.CustomerID)
+		}
+		groups[entry.CustomerID] = append(groups[entry.CustomerID], entry)
+	}
+	for _, id := range order {
+		var result = s.remind(report, reminder, id, groups[id])
+		if !result.Sent {
+			s.logger().Warn(s.text(I18n.MsgReminderSkipped), Logger.F("customer", id), Logger.F("reason", result.Reason), Logger.F("error", result.Error))
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// remind 向一个客户发送提醒邮件
+func (s *Server) remind(report *ExpiryReport, reminder *Reminder, id string, entries []*ExpiryEntry) *ReminderResult {
+	var result = &ReminderResult{CustomerID: id}
+	for _, entry := range entries {
+		result.Serials = append(result.Serials, entry.Serial)
+	}
+	customer, err := s.Customers.Get(id)
+	if err != nil {
+		result.Reason, result.Error = ReminderNoCustomer, err.Error()
+		return result
+	}
+	for _, contact := range customer.Contacts {
+		if contact.Email != "" {
+			result.To = append(result.To, contact.Email)
+		}
+	}
+	if len(result.To) == 0 {
+		result.Reason = ReminderNoContact
+		return result
+	}
+	subject, body, err := s.renderReminder(reminder.TemplateDir, &ReminderData{Customer: customer, Licenses: entries, Within: report.Within})
+	if err != nil {
+		result.Reason, result.Error = ReminderTemplate, err.Error()
+		return result
+	}
+	var msg = composeMail(reminder.From, result.To, reminder.Cc, subject, body)
+	err = reminder.Mailer.Send(reminder.From, append(append([]string(nil), result.To...), reminder.Cc...), msg)
+	if err != nil {
+		result.Reason, result.Error = ReminderSend, err.Error()
+		return result
+	}
+	result.Sent = true
+	s.logger().Info(s.text(I18n.MsgReminderSent), Logger.F("customer", id), Logger.F("licenses", len(entries)))
+	return result
+}
+
+// renderReminder 按客户选择模板并渲染邮件主题与正文
+func (s *Server) renderReminder(dir string, data *ReminderData) (string, string, error) {
+	var funcs = template.FuncMap{
+		"date": func(t time.Time) string {
+			return t.Local().Format("2006-01-02")
+		},
+	}
+	var tmpl = template.New("reminder").Funcs(funcs)
+	var name = "reminder_" + string(I18n.Or(s.Locale)) + ".tmpl"
+	var err error
+	path, ok := reminderTemplatePath(dir, data.Customer.ID)
+	if ok {
+		name = path
+		_, err = tmpl.ParseFiles(path)
+	} else {
+		_, err = tmpl.ParseFS(templates, "templates/"+name)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if tmpl.Lookup("subject") == nil || tmpl.Lookup("body") == nil {
+		return "", "", s.err(I18n.CodeReminderTemplate, name)
+	}
+	var subject, body bytes.Buffer
+	err = tmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return "", "", err
+	}
+	err = tmpl.ExecuteTemplate(&body, "body", data)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimLeft(body.String(), "\n"), nil
+}
+
+// reminderTemplatePath 模板目录中客户专用模板或默认模板的路径
+func reminderTemplatePath(dir, customerID string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	for _, name := range []string{customerID + ".tmpl", "default.tmpl"} {
+		var path = filepath.Join(dir, filepath.Base(name))
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// composeMail 生成UTF-8纯文本邮件，主题按RFC 2047编码，正文使用base64
+func composeMail(from string, to, cc []string, subject, body string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	if len(cc) > 0 {
+		fmt.Fprintf(&buf, "Cc: %s\r\n", strings.Join(cc, ", "))
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	var encoded = base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
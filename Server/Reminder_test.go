@@ -0,0 +1,293 @@
+package Server
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentMail 桩邮件发送方记录的一封邮件
+type sentMail struct {
+	from    string
+	to      []string
+	subject string
+	body    string
+}
+
+// stubMailer 记录发送的邮件，Fail中的收件人发送失败
+type stubMailer struct {
+	t    *testing.T
+	Fail string
+	Sent []*sentMail
+}
+
+func (m *stubMailer) Send(from string, to []string, msg []byte) error {
+	for _, addr := range to {
+		if addr == m.Fail {
+			return errors.New("relay rejected " + addr)
+		}
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(msg)))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, parsed.Body))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	m.Sent = append(m.Sent, &sentMail{from: from, to: to, subject: subject, body: string(body)})
+	return nil
+}
+
+// sentTo 按第一个收件人索引已发送的邮件
+func sentTo(m *stubMailer) map[string]*sentMail {
+	var sent = make(map[string]*sentMail)
+	for _, msg := range m.Sent {
+		sent[msg.to[0]] = msg
+	}
+	return sent
+}
+
+// newReminderServer 带客户库的测试签发端，按days为客户签发License
+func newReminderServer(t *testing.T, customers []*Customer, days map[string][]int) *Server {
+	t.Helper()
+	server, _ := newTestServer(t)
+	registry, err := OpenCustomerRegistry(filepath.Join(t.TempDir(), "customers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Customers = registry
+	for _, customer := range customers {
+		if err = registry.Put(customer); err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range days[customer.ID] {
+			var req = &IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: d, Issuance: Issuance{CustomerID: customer.ID}}
+			if _, _, err = server.Issue(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return server
+}
+
+func TestSendReminders(t *testing.T) {
+	var server = newReminderServer(t, []*Customer{
+		{ID: "acme", Name: "ACME", Contacts: []Contact{{Name: "Ann", Email: "ann@acme.test"}, {Name: "Bob"}, {Name: "Cat", Email: "cat@acme.test"}}},
+		{ID: "beta", Name: "Beta", Contacts: []Contact{{Name: "Dan", Email: "dan@beta.test"}}},
+		{ID: "gamma", Name: "Gamma", Contacts: []Contact{{Name: "Eve", Phone: "10086"}}},
+	}, map[string][]int{"acme": {10, 20, 90}, "beta": {5}, "gamma": {15}})
+	if _, _, err := server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 3}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := server.ExpiryReport(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entries) != 5 {
+		t.Fatalf("report entries = %d, want 5", len(report.Entries))
+	}
+
+	var mailer = &stubMailer{t: t}
+	results, err := server.SendReminders(report, &Reminder{Mailer: mailer, From: "licenses@vendor.test", Cc: []string{"am@vendor.test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var byCustomer = make(map[string]*ReminderResult)
+	for _, result := range results {
+		byCustomer[result.CustomerID] = result
+	}
+	if result := byCustomer[""]; result == nil || result.Sent || result.Reason != ReminderNoCustomer {
+		t.Errorf("unlinked license result = %+v", result)
+	}
+	if result := byCustomer["gamma"]; result == nil || result.Sent || result.Reason != ReminderNoContact {
+		t.Errorf("customer without email result = %+v", result)
+	}
+	if result := byCustomer["acme"]; result == nil || !result.Sent || len(result.Serials) != 2 {
+		t.Errorf("acme result = %+v", result)
+	}
+
+	if len(mailer.Sent) != 2 {
+		t.Fatalf("sent %d mails, want 2", len(mailer.Sent))
+	}
+	var sent = sentTo(mailer)
+	var acme = sent["ann@acme.test"]
+	if acme == nil || acme.from != "licenses@vendor.test" || !reflect.DeepEqual(acme.to, []string{"ann@acme.test", "cat@acme.test", "am@vendor.test"}) {
+		t.Fatalf("acme mail = %+v", acme)
+	}
+	// 内置模板按Server.Locale选择，只列出窗口内到期的License
+	if !strings.HasPrefix(acme.subject, "License到期提醒：ACME有2个License将在30天内到期") {
+		t.Errorf("acme subject = %q", acme.subject)
+	}
+	if strings.Count(acme.body, "- 序列号") != 2 {
+		t.Errorf("acme body = %q", acme.body)
+	}
+	if beta := sent["dan@beta.test"]; beta == nil || !reflect.DeepEqual(beta.to, []string{"dan@beta.test", "am@vendor.test"}) {
+		t.Errorf("beta mail = %+v", beta)
+	}
+}
+
+func TestSendRemindersTemplates(t *testing.T) {
+	var server = newReminderServer(t, []*Customer{
+		{ID: "acme", Name: "ACME", Contacts: []Contact{{Email: "ann@acme.test"}}},
+		{ID: "beta", Name: "Beta", Contacts: []Contact{{Email: "dan@beta.test"}}},
+		{ID: "gamma", Name: "Gamma", Contacts: []Contact{{Email: "eve@gamma.test"}}},
+		{ID: "delta", Name: "Delta", Contacts: []Contact{{Email: "fay@delta.test"}}},
+	}, map[string][]int{"acme": {10}, "beta": {10}, "gamma": {10}, "delta": {10}})
+	var dir = t.TempDir()
+	var files = map[string]string{
+		"default.tmpl": `{{define "subject"}}default {{.Customer.ID}} {{.Within}}{{end}}{{define "body"}}{{range .Licenses}}{{.Serial}}{{end}}{{end}}`,
+		"beta.tmpl":    `{{define "subject"}}beta {{len .Licenses}}{{end}}{{define "body"}}custom{{end}}`,
+		"gamma.tmpl":   `{{define "subject"}}no body{{end}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := server.ExpiryReport(14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mailer = &stubMailer{t: t, Fail: "fay@delta.test"}
+	results, err := server.SendReminders(report, &Reminder{Mailer: mailer, From: "licenses@vendor.test", TemplateDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent = sentTo(mailer)
+	if acme := sent["ann@acme.test"]; acme == nil || acme.subject != "default acme 14" {
+		t.Errorf("acme mail = %+v, want the default template", acme)
+	}
+	// 客户模板优先于默认模板
+	if beta := sent["dan@beta.test"]; beta == nil || beta.subject != "beta 1" || beta.body != "custom" {
+		t.Errorf("beta mail = %+v, want the customer template", beta)
+	}
+	for _, result := range results {
+		switch result.CustomerID {
+		case "gamma":
+			if result.Sent || result.Reason != ReminderTemplate {
+				t.Errorf("gamma result = %+v", result)
+			}
+		case "delta":
+			if result.Sent || result.Reason != ReminderSend || !strings.Contains(result.Error, "fay@delta.test") {
+				t.Errorf("delta result = %+v", result)
+			}
+		}
+	}
+}
+
+// smtpSession SMTP桩收到的一次会话
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub 在本机端口上应答一次SMTP会话，不提供STARTTLS，提供PLAIN认证
+func smtpStub(t *testing.T) (string, <-chan *smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	var sessions = make(chan *smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+		var text = textproto.NewConn(conn)
+		var session = new(smtpSession)
+		_ = text.PrintfLine("220 localhost ESMTP stub")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			var verb = strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch verb {
+			case "EHLO":
+				_ = text.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			case "AUTH":
+				session.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+				_ = text.PrintfLine("235 authenticated")
+			case "MAIL":
+				session.from = line
+				_ = text.PrintfLine("250 ok")
+			case "RCPT":
+				session.to = append(session.to, line)
+				_ = text.PrintfLine("250 ok")
+			case "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				session.data = string(data)
+				_ = text.PrintfLine("250 queued")
+			case "QUIT":
+				_ = text.PrintfLine("221 bye")
+				sessions <- session
+				return
+			default:
+				_ = text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), sessions
+}
+
+// TestSMTPMailer 经本机SMTP桩发送邮件，配置用户名时使用PLAIN认证
+func TestSMTPMailer(t *testing.T) {
+	addr, sessions := smtpStub(t)
+	var mailer = &SMTPMailer{Addr: addr, Username: "relay", Password: "secret"}
+	var msg = "Subject: test\r\n\r\nhello\r\n"
+	err := mailer.Send("licenses@vendor.test", []string{"ann@acme.test", "am@vendor.test"}, []byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session *smtpSession
+	select {
+	case session = <-sessions:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP session not finished")
+	}
+	auth, err := base64.StdEncoding.DecodeString(session.auth)
+	if err != nil || string(auth) != "\x00relay\x00secret" {
+		t.Errorf("AUTH = %q (%v)", auth, err)
+	}
+	if session.from != "MAIL FROM:<licenses@vendor.test>" {
+		t.Errorf("MAIL = %q", session.from)
+	}
+	if !reflect.DeepEqual(session.to, []string{"RCPT TO:<ann@acme.test>", "RCPT TO:<am@vendor.test>"}) {
+		t.Errorf("RCPT = %q", session.to)
+	}
+	if session.data != "Subject: test\n\nhello\n" {
+		t.Errorf("DATA = %q", session.data)
+	}
+
+	if err = (&SMTPMailer{Addr: "localhost", Username: "relay"}).Send("a@b.test", []string{"c@d.test"}, []byte(msg)); err == nil {
+		t.Error("Send with an address without a port succeeded")
+	}
+}
@@ -0,0 +1,154 @@
+package Server
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// templates 内置的到期报告与提醒邮件模板
+//
+//go:embed templates
+var templates embed.FS
+
+// 到期报告格式
+const (
+	ReportCSV  = "csv"
+	ReportJSON = "json"
+	ReportHTML = "html"
+)
+
+// ExpiryEntry 到期报告中的一个License
+type ExpiryEntry struct {
+	Serial       string    `json:"serial"`
+	CustomerID   string    `json:"customer_id,omitempty"`
+	CustomerName string    `json:"customer_name,omitempty"`
+	CustomerTag  string    `json:"customer_tag"`
+	Product      string    `json:"product,omitempty"`
+	Plan         string    `json:"plan,omitempty"`
+	AllowNodes   int       `json:"allow_nodes"`
+	EndTime      time.Time `json:"end_time"`
+	DaysLeft     int       `json:"days_left"` // 剩余天数，不足一天按0计
+}
+
+// ExpiryReport 指定天数内到期的有效License，按到期时间排列
+type ExpiryReport struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Within      int            `json:"within_days"`
+	Entries     []*ExpiryEntry `json:"entries"`
+}
+
+// ExpiryReport 从签发记录库生成within天内到期的License报告，不含已吊销、已到期与已续期的License；
+// 配置客户库时填写客户名称
+func (s *Server) ExpiryReport(within int) (*ExpiryReport, error) {
+	if s.Inventory == nil {
+		return nil, s.err(I18n.CodeNoInventory)
+	}
+	var now = time.Now().UTC()
+	var report = &ExpiryReport{GeneratedAt: now, Within: within, Entries: make([]*ExpiryEntry, 0)}
+	var records = s.Inventory.Search(&InventoryQuery{Active: true, ExpiresBefore: now.AddDate(0, 0, within)})
+	for _, record := range records {
+		end, err := Timestamp.Parse(record.EndTime, "")
+		if err != nil {
+			continue
+		}
+		var entry = &ExpiryEntry{
+			Serial:      record.Serial,
+			CustomerID:  record.CustomerID,
+			CustomerTag: record.CustomerTag,
+			Product:     record.Product,
+			Plan:        record.Plan,
+			AllowNodes:  record.AllowNodes,
+			EndTime:     end,
+			DaysLeft:    int(end.Sub(now).Hours() / 24),
+		}
+		if record.CustomerID != "" && s.Customers != nil {
+			if customer, err := s.Customers.Get(record.CustomerID); err == nil {
+				entry.CustomerName = customer.Name
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].EndTime.Before(report.Entries[j].EndTime)
+	})
+	return report, nil
+}
+
+// Write 按格式输出报告，HTML报告的标题与表头使用locale语言
+func (r *ExpiryReport) Write(w io.Writer, format string, locale I18n.Locale) error {
+	switch format {
+	case ReportCSV:
+		return r.writeCSV(w)
+	case ReportJSON:
+		var encoder = json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(r)
+	case ReportHTML:
+		return r.writeHTML(w, I18n.Or(locale))
+	default:
+		return I18n.In(locale, I18n.CodeReportFormat, format)
+	}
+}
+
+// writeCSV 列名与批量签发清单一致
+func (r *ExpiryReport) writeCSV(w io.Writer) error {
+	var writer = csv.NewWriter(w)
+	err := writer.Write([]string{"serial", "customer_id", "customer_name", "customer_tag", "product", "plan", "allow_nodes", "end_time", "days_left"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range r.Entries {
+		err = writer.Write([]string{
+			entry.Serial,
+			entry.CustomerID,
+			entry.CustomerName,
+			entry.CustomerTag,
+			entry.Product,
+			entry.Plan,
+			strconv.Itoa(entry.AllowNodes),
+			Timestamp.Format(entry.EndTime),
+			strconv.Itoa(entry.DaysLeft),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *ExpiryReport) writeHTML(w io.Writer, locale I18n.Locale) error {
+	page, err := template.New("expiry.html").Funcs(template.FuncMap{
+		"text": func(id I18n.ID, args ...interface{}) string {
+			return I18n.T(locale, id, args...)
+		},
+		"time": Timestamp.Format,
+	}).ParseFS(templates, "templates/expiry.html")
+	if err != nil {
+		return err
+	}
+	return page.Execute(w, map[string]interface{}{
+		"Lang":   string(locale),
+		"Report": r,
+		"Labels": map[string]I18n.ID{
+			"Title":     I18n.MsgReportTitle,
+			"Generated": I18n.MsgReportGenerated,
+			"Serial":    I18n.MsgReportSerial,
+			"Customer":  I18n.MsgReportCustomer,
+			"Product":   I18n.MsgReportProduct,
+			"Plan":      I18n.MsgReportPlan,
+			"Nodes":     I18n.MsgReportNodes,
+			"EndTime":   I18n.MsgReportEndTime,
+			"DaysLeft":  I18n.MsgReportDaysLeft,
+			"Empty":     I18n.MsgReportEmpty,
+		},
+	})
+}
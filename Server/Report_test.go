@@ -0,0 +1,111 @@
+package Server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+func testExpiryReport() *ExpiryReport {
+	var now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &ExpiryReport{
+		GeneratedAt: now,
+		Within:      30,
+		Entries: []*ExpiryEntry{
+			{Serial: "S1", CustomerID: "acme", CustomerName: `<script>alert("x")</script> & Co`, CustomerTag: "ACME", Product: "edge", Plan: "standard", AllowNodes: 8, EndTime: now.AddDate(0, 0, 3), DaysLeft: 3},
+			{Serial: "S2", CustomerTag: "Beta, Inc.", AllowNodes: 4, EndTime: now.AddDate(0, 0, 20), DaysLeft: 20},
+		},
+	}
+}
+
+func TestExpiryReportCSV(t *testing.T) {
+	var report = testExpiryReport()
+	var buf bytes.Buffer
+	if err := report.Write(&buf, ReportCSV, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != "serial,customer_id,customer_name,customer_tag,product,plan,allow_nodes,end_time,days_left" {
+		t.Fatalf("rows = %q", rows)
+	}
+	var want = []string{"S1", "acme", report.Entries[0].CustomerName, "ACME", "edge", "standard", "8", Timestamp.Format(report.Entries[0].EndTime), "3"}
+	if strings.Join(rows[1], "|") != strings.Join(want, "|") {
+		t.Errorf("row = %q, want %q", rows[1], want)
+	}
+	if rows[2][3] != "Beta, Inc." || rows[2][8] != "20" {
+		t.Errorf("row = %q", rows[2])
+	}
+}
+
+func TestExpiryReportJSON(t *testing.T) {
+	var report = testExpiryReport()
+	var buf bytes.Buffer
+	if err := report.Write(&buf, ReportJSON, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ExpiryReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Within != 30 || !decoded.GeneratedAt.Equal(report.GeneratedAt) || len(decoded.Entries) != 2 {
+		t.Fatalf("decoded = %+v", decoded)
+	}
+	if *decoded.Entries[0] != *report.Entries[0] {
+		t.Errorf("entry = %+v, want %+v", decoded.Entries[0], report.Entries[0])
+	}
+}
+
+// TestExpiryReportHTML 标题与表头使用指定语言，客户名称经过HTML转义
+func TestExpiryReportHTML(t *testing.T) {
+	var report = testExpiryReport()
+	for _, locale := range []I18n.Locale{I18n.EN, I18n.ZH} {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, ReportHTML, locale); err != nil {
+			t.Fatal(err)
+		}
+		var out = buf.String()
+		for _, want := range []string{
+			`<html lang="` + string(locale) + `">`,
+			"<h1>" + I18n.T(locale, I18n.MsgReportTitle, 30) + "</h1>",
+			"<th>" + I18n.T(locale, I18n.MsgReportSerial) + "</th>",
+			"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; Co (acme)",
+			"<td>Beta, Inc.</td>",
+			`<tr class="urgent">`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s HTML has no %q:\n%s", locale, want, out)
+			}
+		}
+		if strings.Contains(out, "<script>") {
+			t.Errorf("%s HTML contains an unescaped customer name", locale)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (&ExpiryReport{Within: 7}).Write(&buf, ReportHTML, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), I18n.T(I18n.EN, I18n.MsgReportEmpty)) || strings.Contains(buf.String(), "<table>") {
+		t.Errorf("empty report HTML:\n%s", buf.String())
+	}
+}
+
+func TestExpiryReportFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := testExpiryReport().Write(&buf, "xlsx", I18n.EN)
+	if I18n.Code(err) != I18n.CodeReportFormat {
+		t.Fatalf("Write error = %v, want %s", err, I18n.CodeReportFormat)
+	}
+	if !strings.Contains(err.Error(), "xlsx") || buf.Len() != 0 {
+		t.Errorf("error = %v, output = %q", err, buf.String())
+	}
+}
@@ -0,0 +1,110 @@
+package Server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+	"os"
+	"time"
+)
+
+// IssueResellerCert 使用当前签发密钥为经销商签发证书，返回经销商使用的完整证书链。
+// cert中的限额与有效期由调用方填写，公钥信息取自resellerKey；
+// 当前Server本身是经销商时，新证书的限额不能超出自身证书。
+func (s *Server) IssueResellerCert(cert *Entity.ResellerCert, resellerKey *Trust.Key) ([]*Entity.ResellerCert, error) {
+	if s.Signer == nil {
+		return nil, s.err(I18n.CodeNoSigner)
+	}
+	cert.KeyID = resellerKey.KeyID
+	cert.Suite = resellerKey.Suite
+	cert.PublicKey = resellerKey.PublicKey
+	cert.IssuerKeyID = s.Signer.KeyID()
+	cert.IssuerSuite = s.Signer.Suite()
+	cert.Signature = nil
+	if cert.Serial == "" {
+		var serial = make([]byte, 8)
+		if _, err := rand.Read(serial); err != nil {
+			return nil, err
+		}
+		cert.Serial = hex.EncodeToString(serial)
+	}
+	var now = time.Now()
+	if cert.NotBefore == "" {
+		cert.NotBefore = Timestamp.Format(now)
+	}
+	if cert.NotAfter == "" {
+		cert.NotAfter = Timestamp.Format(now.AddDate(1, 0, 0))
+		// 默认有效期不超过上级证书
+		if len(s.ResellerChain) > 0 {
+			parentAfter, err := Timestamp.Parse(s.ResellerChain[0].NotAfter, "")
+			if err == nil && parentAfter.Before(now.AddDate(1, 0, 0)) {
+				cert.NotAfter = s.ResellerChain[0].NotAfter
+			}
+		}
+	}
+	if _, err := Suite.Get(cert.Suite); err != nil {
+		return nil, err
+	}
+	if len(s.ResellerChain) > 0 {
+		err := Trust.CheckCertWithin(cert, s.ResellerChain[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	signed, err := Trust.CertSignedBytes(cert)
+	if err != nil {
+		return nil, err
+	}
+	cert.Signature, err = s.Signer.Sign(signed)
+	if err != nil {
+		return nil, err
+	}
+	s.logger().Info(s.text(I18n.MsgResellerIssued), Logger.F("serial", cert.Serial), Logger.F("reseller", cert.Reseller), Logger.F(Logger.FieldKeyID, cert.KeyID))
+	return append([]*Entity.ResellerCert{cert}, s.ResellerChain...), nil
+}
+
+// SaveResellerChain 保存证书链到文件
+func SaveResellerChain(chain []*Entity.ResellerCert, path string) error {
+	data, err := json.MarshalIndent(chain, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// UseResellerChain 以经销商身份签发，证书链首证书必须对应当前签发密钥
+func (s *Server) UseResellerChain(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var chain = make([]*Entity.ResellerCert, 0)
+	err = json.Unmarshal(data, &chain)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return s.err(I18n.CodeEmptyChain)
+	}
+	if s.Signer == nil || s.Signer.KeyID() != chain[0].KeyID {
+		return s.err(I18n.CodeChainSigner)
+	}
+	s.ResellerChain = chain
+	return nil
+}
+
+// applyResellerChain 经销商签发时附加证书链并校验授权范围
+func (s *Server) applyResellerChain(lic *Entity.License) error {
+	if len(s.ResellerChain) == 0 {
+		lic.ResellerChain = nil
+		return nil
+	}
+	lic.ResellerChain = s.ResellerChain
+	return Trust.CheckLicenseLimits(lic, s.ResellerChain[0])
+}
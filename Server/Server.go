@@ -2,16 +2,19 @@ package Server
 
 import (
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"github.com/lizazacn/ElstLic/Entity"
 	"github.com/lizazacn/ElstLic/Utils"
-	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
 	"github.com/manifoldco/promptui"
 	"os"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,6 +22,25 @@ type Server struct {
 	Offset  int
 	Step    int
 	DevInfo string
+	Suite   string        // 签发使用的算法套件，为空时使用签名器的套件或默认套件
+	Signer  Signer.Signer // 签名器，为空时不签名
+	Logger  Logger.Logger // 日志，为空时不输出
+	Locale  I18n.Locale   // 提示与错误信息语言，为空时按LANG检测
+
+	Policy     *Policy             // 签发策略，为空时使用DefaultPolicy
+	Plans      []*Plan             // 授权方案
+	Issuer     string              // 签发人，用于策略判断并记录在签发台账中
+	LedgerPath string              // 签发台账路径，为空时不记录
+	Inventory  *Inventory          // 签发记录库，保存每个签发的License，为空时不保存
+	Products   map[string]*Product // 按产品ID配置的签发密钥与策略，配置后只能签发其中的产品
+	Customers  *CustomerRegistry   // 客户库，配置后签发可关联客户ID
+
+	AuditAnchors *AuditAnchors // 审计日志起点登记，配置后可发现被整体丢弃并重新生成的审计日志
+
+	// ConfirmMigration 待迁移的License不在签发记录中时由操作人核对内容，返回true才迁移；为空时拒绝迁移
+	ConfirmMigration func(lic *Entity.License) bool
+
+	ResellerChain []*Entity.ResellerCert // 经销商证书链，以经销商身份签发时设置
 }
 
 // CreateLicFile 创建license授权文件
@@ -29,11 +51,11 @@ func (s *Server) CreateLicFile() error {
 	if s.Step == 0 {
 		s.Step = 1
 	}
-	inPutFile := flag.String("i", "", "请指定node.info文件路径")
+	inPutFile := flag.String("i", "", s.text(I18n.MsgFlagNodeInfoPath))
 	flag.Parse()
 	if *inPutFile == "" {
 		prompt := promptui.Prompt{
-			Label:   "请输入node.info文件路径",
+			Label:   s.text(I18n.PromptNodeInfoPath),
 			Default: "./node.info",
 		}
 
@@ -49,66 +71,110 @@ func (s *Server) CreateLicFile() error {
 	if err != nil {
 		return err
 	}
-	cipher, key := Utils.GetGMCipherAndKey(ciphertext, s.Offset, s.Step)
-	decrypt, err := GM.SM4Decrypt(cipher, key, key)
+	lic, _, err := Utils.OpenLicense(ciphertext, s.Offset, s.Step)
+	if Utils.IsTampered(err) {
+		return s.err(I18n.CodeTamperedContact, s.DevInfo)
+	}
 	if err != nil {
 		return err
 	}
-	var lic = new(Entity.License)
-	err = json.Unmarshal(decrypt, lic)
+	// 多产品时按产品选择签发密钥与策略
+	var issuance = &Issuance{Issuer: s.Issuer}
+	ps, err := s.selectProductServer(lic, issuance)
 	if err != nil {
 		return err
 	}
-	stat, err := Utils.CheckData(lic)
+	// 输入必要字段
+	err = ps.inputLicData(lic, issuance)
 	if err != nil {
 		return err
 	}
-	if !stat {
-		return errors.New(fmt.Sprintf("数据疑似被篡改，请联系:%s！", s.DevInfo))
+	// 按签发策略检查，违反时需要填写放行理由
+	_, err = ps.checkPolicy(lic, issuance, true)
+	if err != nil {
+		return err
 	}
-	// 输入必要字段
-	err = s.inputLicData(lic)
+	// 经销商签发时校验授权范围
+	err = ps.applyResellerChain(lic)
 	if err != nil {
 		return err
 	}
 	// 回显License信息
-	fmt.Println("###############授权信息###############")
+	fmt.Println(s.text(I18n.MsgLicenseInfo))
 	licJson, err := json.MarshalIndent(lic, "", "    ")
 	if err != nil {
 		return err
 	}
 	fmt.Println(string(licJson))
-	// 加密数据到文件
-	err = s.encryptDataToFile(lic)
+	// 加密签名并记录签发
+	data, err := ps.seal(lic, issuance, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	return ps.writeLicFile(data)
 }
 
-// inputLicData 填充Lic必要数据
-func (s *Server) inputLicData(lic *Entity.License) error {
+// initTimes 设置开始时间与签发时间，统一以UTC保存，旧版本node.info中不带时区的时间按客户时区解析
+func (s *Server) initTimes(lic *Entity.License) (time.Time, error) {
 	var nowTime = time.Now()
-	start, err := time.ParseInLocation("2006-01-02T15:04:05", lic.StartTime, time.Local)
+	start, err := lic.StartAt()
 	if err != nil {
-		fmt.Printf("解析开始时间字段异常: %v", err.Error())
+		s.logger().Warn(s.text(I18n.MsgStartTimeFallback), Logger.Err(err))
 		start = nowTime
-		lic.StartTime = nowTime.Format("2006-01-02T15:04:05")
 	}
 	if start.AddDate(0, 0, 1).Before(nowTime) {
-		return errors.New("node.info文件已超出48小时有效期！")
+		return start, s.err(I18n.CodeNodeInfoExpired)
 	}
 	if start.After(nowTime) {
 		start = nowTime
-		lic.StartTime = nowTime.Format("2006-01-02T15:04:05")
 	}
-	lic.LicenseCreateTime = nowTime.Format("2006-01-02T15:04:05")
+	lic.StartTime = Timestamp.Format(start)
+	lic.LicenseCreateTime = Timestamp.Format(nowTime)
+	return start, nil
+}
+
+// inputLicData 填充Lic必要数据
+func (s *Server) inputLicData(lic *Entity.License, issuance *Issuance) error {
+	start, err := s.initTimes(lic)
+	if err != nil {
+		return err
+	}
+	var customer = Timestamp.Zone(lic.ClientTimeZone)
+	fmt.Println(s.timeInZones(I18n.MsgStartTime, start, customer))
+
+	// 选择授权方案，方案条款作为后续输入的默认值
+	plan, err := s.selectPlan()
+	if err != nil {
+		return err
+	}
+	var defaults = &Plan{AllowNodes: 3}
+	if plan != nil {
+		defaults = plan
+		issuance.Plan = plan.Name
+		issuance.Edition = plan.Edition
+		if plan.Product != "" {
+			issuance.Product = plan.Product
+		}
+		lic.Features = append([]string(nil), plan.Features...)
+	}
+
+	// 策略限定产品与版本时选择
+	err = s.selectProduct(lic, issuance)
+	if err != nil {
+		return err
+	}
+
+	// 配置客户库时选择客户，客户ID作为客户标记的默认值
+	err = s.selectCustomer(issuance)
+	if err != nil {
+		return err
+	}
 
 reInNodes:
 	// 设置最大节点数
 	prompt := promptui.Prompt{
-		Label:   "请输入允许接入的最大节点数",
-		Default: "3",
+		Label:   s.text(I18n.PromptAllowNodes),
+		Default: strconv.Itoa(defaults.AllowNodes),
 	}
 	result, err := prompt.Run()
 	if err != nil {
@@ -116,20 +182,23 @@ reInNodes:
 	}
 	atoi, err := strconv.Atoi(result)
 	if err != nil {
-		fmt.Println("输入格式异常，请输入数字类型数据！")
+		fmt.Println(s.text(I18n.MsgInvalidNumber))
 		goto reInNodes
 	}
-	if atoi <= 3 {
-		atoi = 3
+	if minNodes := s.policy().MinNodes; atoi < minNodes {
+		atoi = minNodes
 	}
 	lic.AllowNodes = atoi
 
 	// 设置是否永久授权（100年）
 	promptSelect := promptui.Select{
-		Label: "选择是否永久授权",
+		Label: s.text(I18n.PromptPermanent),
 		Items: []string{"yes", "no"},
 		Size:  2,
 	}
+	if plan != nil && !plan.Permanent {
+		promptSelect.CursorPos = 1
+	}
 
 	_, result, err = promptSelect.Run()
 	if err != nil {
@@ -137,37 +206,41 @@ reInNodes:
 	}
 	// 设置结束时间(100年)
 	if result == "yes" {
+		lic.PermanentAuth = true
 		end := start.AddDate(100, 0, 0)
-		lic.EndTime = end.Format("2006-01-02T15:04:05")
+		lic.EndTime = Timestamp.Format(end)
 	}
 
 	if result != "yes" {
 	reInDate:
-		// 设置过期时间
+		// 设置过期时间，默认值以客户时区显示
+		var defaultEnd = start.AddDate(0, 30, 0)
+		if defaults.Days > 0 {
+			defaultEnd = start.AddDate(0, 0, defaults.Days)
+		}
 		prompt = promptui.Prompt{
-			Label:   "设置过期时间",
-			Default: start.AddDate(0, 30, 0).Format("2006-01-02T15:04:05"),
+			Label:   s.text(I18n.PromptEndTime),
+			Default: defaultEnd.In(customer).Format(Timestamp.Layout),
 		}
 		result, err = prompt.Run()
 		if err != nil {
 			return err
 		}
 		// 验证输入格式是否正确
-		dateTimeComp, err := regexp.Compile(`\d\d\d\d-\d\d-\d\dT\d\d:\d\d:\d\d`)
+		end, err := Timestamp.Parse(result, lic.ClientTimeZone)
 		if err != nil {
-			return err
-		}
-		if !dateTimeComp.Match([]byte(result)) {
-			fmt.Println("输入时间格式不正确，请重新输入！")
+			fmt.Println(s.text(I18n.MsgInvalidTime))
 			goto reInDate
 		}
-		lic.EndTime = result
+		lic.EndTime = Timestamp.Format(end)
+		fmt.Println(s.timeInZones(I18n.MsgEndTime, end, customer))
 	}
 
+reInTag:
 	// 设置客户标记
 	prompt = promptui.Prompt{
-		Label:   "设置客户标记",
-		Default: "",
+		Label:   s.text(I18n.PromptCustomerTag),
+		Default: issuance.CustomerID,
 	}
 	result, err = prompt.Run()
 	if err != nil {
@@ -177,15 +250,19 @@ reInNodes:
 	if result == "" {
 		lic.CustomerTag = lic.MacAddr
 	}
+	if s.checkCustomerTag(plan, lic.CustomerTag) != nil {
+		fmt.Println(s.text(I18n.MsgCustomerTagPattern, plan.CustomerTagPattern))
+		goto reInTag
+	}
 	lic.CheckStatus = true
 	return nil
 }
 
-// EncryptDataToFile 加密数据到文件
-func (s *Server) encryptDataToFile(licData *Entity.License) error {
+// writeLicFile 保存License文件到输入的目录
+func (s *Server) writeLicFile(data []byte) error {
 	var path = "./"
 	prompt := promptui.Prompt{
-		Label:   "请输入license.lic文件保存路径",
+		Label:   s.text(I18n.PromptLicSavePath),
 		Default: path,
 	}
 
@@ -210,25 +287,143 @@ func (s *Server) encryptDataToFile(licData *Entity.License) error {
 		_ = file.Close()
 	}()
 
-	licByte, err := json.Marshal(licData)
+	_, err = file.Write(data)
 	if err != nil {
 		return err
 	}
-	licData.CheckCode = GM.SM3SUM(string(licByte))
+	s.logger().Info(s.text(I18n.MsgLicenseSaved), Logger.F(Logger.FieldPath, file.Name()))
+	return nil
+}
+
+// timeInZones 同时按签发端本地时区与客户时区显示时间
+func (s *Server) timeInZones(id I18n.ID, t time.Time, customer *time.Location) string {
+	return s.text(id, t.Local().Format(Timestamp.Layout), customer.String(), t.In(customer).Format(Timestamp.Layout))
+}
 
-	licByte, err = json.Marshal(licData)
+// text 按签发端语言输出提示信息
+func (s *Server) text(id I18n.ID, args ...interface{}) string {
+	return I18n.T(s.Locale, id, args...)
+}
+
+// err 按签发端语言输出的错误
+func (s *Server) err(code I18n.ID, args ...interface{}) error {
+	return I18n.In(s.Locale, code, args...)
+}
+
+// logger 签发端日志，未配置时不输出
+func (s *Server) logger() Logger.Logger {
+	return Logger.OrNop(s.Logger)
+}
+
+// signerKeyID 签名器的密钥ID，未配置签名器时为空
+func signerKeyID(signer Signer.Signer) string {
+	if signer == nil {
+		return ""
+	}
+	return signer.KeyID()
+}
+
+// sealer 按Server配置生成License加密封装器
+func (s *Server) sealer() (*Utils.Sealer, error) {
+	offset, step := s.params()
+	var sealer = &Utils.Sealer{Offset: offset, Step: step, Signer: s.Signer}
+	if s.Suite != "" {
+		suite, err := Suite.Get(s.Suite)
+		if err != nil {
+			return nil, err
+		}
+		sealer.Suite = suite
+	}
+	return sealer, nil
+}
+
+// MigrateLicFile 将旧格式或旧加密算法的License重新加密签名为当前格式。
+// 只迁移通过完整性校验、且与签发台账或签发记录库中未吊销的记录一致的License，
+// 找不到记录时需ConfirmMigration确认，避免为客户自行生成的License签名。
+// 迁移后的签发时间为当前时间，以便当前签发密钥的有效期覆盖，原签发时间保存在MigratedFrom中。
+// 配置了Products时使用License所属产品的签发密钥。
+func (s *Server) MigrateLicFile(inPath, outPath string) error {
+	offset, step := s.params()
+	ciphertext, err := os.ReadFile(inPath)
 	if err != nil {
 		return err
 	}
-	var key = licData.CheckCode[:16]
-	encrypt, err := GM.SM4Encrypt(licByte, []byte(key), []byte(key))
+	var opener = &Utils.Opener{Offset: offset, Step: step}
+	lic, _, err := opener.Open(ciphertext)
 	if err != nil {
 		return err
 	}
-	encrypt = Utils.AddKeyToGMCipher(encrypt, []byte(key), s.Offset, s.Step)
-	_, err = file.Write(encrypt)
+	var serial = Logger.Serial(lic)
+	entry, err := s.issuedRecord(serial, lic)
 	if err != nil {
 		return err
 	}
-	return nil
+	var issuance = new(Issuance)
+	if entry != nil {
+		*issuance = entry.Issuance
+	} else if s.ConfirmMigration == nil || !s.ConfirmMigration(lic.Clone()) {
+		return s.err(I18n.CodeMigrateUnknown, serial)
+	}
+	issuance.RenewOf, issuance.Override = serial, ""
+	if lic.MigratedFrom == "" {
+		lic.MigratedFrom = lic.LicenseCreateTime
+	}
+	lic.LicenseCreateTime = Timestamp.Format(time.Now())
+	// 按记录的产品或License中的产品ID选择签发密钥，与签发时一致
+	ps, err := s.withProduct(lic, issuance)
+	if err != nil {
+		return err
+	}
+	sealer, err := ps.sealer()
+	if err != nil {
+		return err
+	}
+	encrypt, err := sealer.Seal(lic)
+	if err != nil {
+		return err
+	}
+	if outPath == "" {
+		outPath = inPath
+	}
+	err = os.WriteFile(outPath, encrypt, 0600)
+	if err != nil {
+		return err
+	}
+	s.logger().Info(s.text(I18n.MsgLicenseMigrated), Logger.F(Logger.FieldPath, outPath), Logger.F(Logger.FieldKeyID, signerKeyID(ps.Signer)), Logger.F("migrated_from", serial))
+	return ps.record(lic, issuance, nil, encrypt)
+}
+
+// issuedRecord 在签发记录库或签发台账中查找序列号对应的签发记录，并核对授权内容。
+// 记录不存在时返回nil，记录已吊销或内容不一致时返回错误。
+func (s *Server) issuedRecord(serial string, lic *Entity.License) (*LedgerEntry, error) {
+	var entry *LedgerEntry
+	if s.Inventory != nil {
+		record, err := s.Inventory.Get(serial)
+		if err == nil {
+			if record.Revoked {
+				return nil, s.err(I18n.CodeLicenseRevoked, serial)
+			}
+			entry = &record.LedgerEntry
+		}
+	}
+	if entry == nil && s.LedgerPath != "" {
+		entries, err := ReadLedger(s.LedgerPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Serial == serial {
+				entry = e
+			}
+		}
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	if entry.CustomerTag != lic.CustomerTag || entry.MotherBoardID != lic.MotherBoardID || entry.MacAddr != lic.MacAddr ||
+		entry.AllowNodes != lic.AllowNodes || entry.StartTime != lic.StartTime || entry.EndTime != lic.EndTime ||
+		entry.PermanentAuth != lic.PermanentAuth || strings.Join(entry.Features, ",") != strings.Join(lic.Features, ",") {
+		return nil, s.err(I18n.CodeMigrateUnknown, serial)
+	}
+	return entry, nil
 }
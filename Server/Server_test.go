@@ -0,0 +1,335 @@
+package Server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+const (
+	testOffset = 3
+	testStep   = 3
+)
+
+// newTestServer 使用一次性签发密钥与临时签发记录库的签发端，返回客户端使用的信任列表
+func newTestServer(t *testing.T) (*Server, *Trust.Store) {
+	t.Helper()
+	signer, err := Signer.NewStubSigner("", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, signer.Public(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.ID = key.KeyID
+	var dir = t.TempDir()
+	inventory, err := OpenInventory(filepath.Join(dir, "inventory.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server = &Server{
+		Offset:     testOffset,
+		Step:       testStep,
+		DevInfo:    "test",
+		Signer:     signer,
+		Locale:     I18n.ZH,
+		Inventory:  inventory,
+		LedgerPath: filepath.Join(dir, "ledger.jsonl"),
+		Policy:     &Policy{},
+	}
+	return server, &Trust.Store{Keys: []*Trust.Key{key}}
+}
+
+// newNodeInfo 客户端生成的node.info内容
+func newNodeInfo(t *testing.T) []byte {
+	t.Helper()
+	var lic = &Entity.License{
+		StartTime:      Timestamp.Format(time.Now()),
+		ClientTimeZone: "Asia/Shanghai",
+		MacAddr:        "00:11:22:33:44:55",
+		MotherBoardID:  "MB-TEST",
+	}
+	data, err := Utils.SealLicense(lic, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// legacyLicense 密钥启用前签发的未签名License，与签发记录一致
+func legacyLicense(t *testing.T, s *Server, record bool) (string, *Entity.License) {
+	t.Helper()
+	var issued = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var lic = &Entity.License{
+		StartTime:         Timestamp.Format(issued),
+		EndTime:           Timestamp.Format(time.Now().AddDate(1, 0, 0)),
+		LicenseCreateTime: Timestamp.Format(issued),
+		AllowNodes:        8,
+		MacAddr:           "00:11:22:33:44:55",
+		MotherBoardID:     "MB-TEST",
+		CustomerTag:       "ACME",
+	}
+	data, err := Utils.SealLicense(lic, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record {
+		err = s.record(lic, &Issuance{Issuer: "alice"}, nil, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var path = filepath.Join(t.TempDir(), "license.lic")
+	err = os.WriteFile(path, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, lic
+}
+
+func TestMigrateRecordedLicense(t *testing.T) {
+	server, store := newTestServer(t)
+	path, old := legacyLicense(t, server, true)
+	var out = filepath.Join(t.TempDir(), "migrated.lic")
+	err := server.MigrateLicFile(path, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("migrated file mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧签发时间早于密钥启用时间，迁移后仍能按信任列表打开
+	lic, _, err := (&Utils.Opener{Offset: testOffset, Step: testStep, TrustStore: store}).Open(data)
+	if err != nil {
+		t.Fatalf("open migrated license: %v", err)
+	}
+	if lic.MigratedFrom != old.LicenseCreateTime {
+		t.Errorf("MigratedFrom = %q, want %q", lic.MigratedFrom, old.LicenseCreateTime)
+	}
+	if lic.AllowNodes != old.AllowNodes || lic.EndTime != old.EndTime {
+		t.Errorf("migrated terms changed: %+v", lic)
+	}
+	record, err := server.Inventory.Get(Logger.Serial(lic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.RenewOf != Logger.Serial(old) || record.Issuer != "alice" {
+		t.Errorf("migration record = %+v", record.Issuance)
+	}
+}
+
+func TestMigrateUnknownLicense(t *testing.T) {
+	server, _ := newTestServer(t)
+	// 客户自行生成的License不在签发记录中
+	path, _ := legacyLicense(t, server, false)
+	var out = filepath.Join(t.TempDir(), "migrated.lic")
+	err := server.MigrateLicFile(path, out)
+	if !errors.Is(err, I18n.E(I18n.CodeMigrateUnknown)) {
+		t.Fatalf("MigrateLicFile error = %v, want CodeMigrateUnknown", err)
+	}
+	if _, err = os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("output written for unknown license: %v", err)
+	}
+	if server.Signer.(*Signer.StubSigner).Calls != 0 {
+		t.Error("unknown license was signed")
+	}
+
+	var confirmed *Entity.License
+	server.ConfirmMigration = func(lic *Entity.License) bool {
+		confirmed = lic
+		return lic.AllowNodes == 8
+	}
+	err = server.MigrateLicFile(path, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed == nil || confirmed.CustomerTag != "ACME" {
+		t.Errorf("ConfirmMigration got %+v", confirmed)
+	}
+}
+
+func TestMigrateRevokedLicense(t *testing.T) {
+	server, _ := newTestServer(t)
+	path, lic := legacyLicense(t, server, true)
+	_, err := server.Inventory.Revoke(Logger.Serial(lic), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.MigrateLicFile(path, filepath.Join(t.TempDir(), "migrated.lic"))
+	if !errors.Is(err, I18n.E(I18n.CodeLicenseRevoked)) {
+		t.Fatalf("MigrateLicFile error = %v, want CodeLicenseRevoked", err)
+	}
+}
+
+// TestMigrateProductLicense 迁移产品License时使用产品的签发密钥，产品未配置密钥时不使用Server.Signer
+func TestMigrateProductLicense(t *testing.T) {
+	server, _ := newTestServer(t)
+	own, err := Signer.NewStubSigner("", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, own.Public(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	own.ID = key.KeyID
+	server.Products = map[string]*Product{"edge": {Signer: own}, "missing": {}}
+
+	var migrate = func(product string) (string, error) {
+		var now = time.Now()
+		var lic = &Entity.License{
+			StartTime:         Timestamp.Format(now),
+			EndTime:           Timestamp.Format(now.AddDate(1, 0, 0)),
+			LicenseCreateTime: Timestamp.Format(now),
+			AllowNodes:        8,
+			MacAddr:           "00:11:22:33:44:55",
+			MotherBoardID:     "MB-TEST",
+			CustomerTag:       "ACME",
+			ProductID:         product,
+		}
+		data, err := Utils.SealLicense(lic, testOffset, testStep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = server.record(lic, &Issuance{Issuer: "alice", Product: product}, nil, data); err != nil {
+			t.Fatal(err)
+		}
+		var dir = t.TempDir()
+		var path, out = filepath.Join(dir, "license.lic"), filepath.Join(dir, "migrated.lic")
+		if err = os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return out, server.MigrateLicFile(path, out)
+	}
+
+	out, err := migrate("edge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lic, env, err := (&Utils.Opener{Offset: testOffset, Step: testStep, TrustStore: &Trust.Store{Keys: []*Trust.Key{key}}}).Open(data)
+	if err != nil {
+		t.Fatalf("open with the product trust store: %v", err)
+	}
+	if env.KeyID != own.ID || lic.ProductID != "edge" {
+		t.Errorf("migrated with key %q for product %q", env.KeyID, lic.ProductID)
+	}
+	record, err := server.Inventory.Get(Logger.Serial(lic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Product != "edge" {
+		t.Errorf("migration record product = %q", record.Product)
+	}
+
+	if _, err = migrate("missing"); I18n.Code(err) != I18n.CodeProductSigner {
+		t.Fatalf("migrate without a product key error = %v, want %s", err, I18n.CodeProductSigner)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("server key signed %d migrated product licenses", calls)
+	}
+}
+
+// TestIssueProductSigner 未配置签发密钥的产品只有显式开启SharedSigner时才使用Server.Signer
+func TestIssueProductSigner(t *testing.T) {
+	server, _ := newTestServer(t)
+	own, err := Signer.NewStubSigner("own-key", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Products = map[string]*Product{
+		"missing": {},
+		"shared":  {SharedSigner: true},
+		"own":     {Signer: own},
+	}
+	var issue = func(product string) (string, error) {
+		_, data, err := server.Issue(&IssueRequest{NodeInfo: newNodeInfo(t), AllowNodes: 8, Days: 30, Issuance: Issuance{Product: product}})
+		if err != nil {
+			return "", err
+		}
+		env, err := Envelope.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return env.KeyID, nil
+	}
+
+	if _, err = issue("missing"); I18n.Code(err) != I18n.CodeProductSigner {
+		t.Fatalf("issue without a product key error = %v, want %s", err, I18n.CodeProductSigner)
+	}
+	if calls := server.Signer.(*Signer.StubSigner).Calls; calls != 0 {
+		t.Errorf("server key signed %d licenses for a product without a key", calls)
+	}
+	if keyID, err := issue("shared"); err != nil || keyID != server.Signer.KeyID() {
+		t.Errorf("shared product signed with %q: %v", keyID, err)
+	}
+	if keyID, err := issue("own"); err != nil || keyID != "own-key" {
+		t.Errorf("product signed with %q: %v", keyID, err)
+	}
+}
+
+// TestChangeKeyRingPassphraseMode 重写密钥环时收紧已有文件的权限，且不留下临时文件
+func TestChangeKeyRingPassphraseMode(t *testing.T) {
+	server, _ := newTestServer(t)
+	var dir = t.TempDir()
+	var ringPath = filepath.Join(dir, "keyring.json")
+	if _, err := server.Keygen(ringPath, Suite.NameGM, []byte("old-passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(ringPath, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := server.ChangeKeyRingPassphrase(ringPath, []byte("old-passphrase"), []byte("new-passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(ringPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key ring mode = %v, want 0600", info.Mode().Perm())
+	}
+	if err = server.UseKeyRing(ringPath, []byte("new-passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files left beside the key ring: %v", entries)
+	}
+}
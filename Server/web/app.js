@@ -0,0 +1,336 @@
+// ElstLic 签发控制台，调用 /api/v1/ 管理接口，令牌仅保存在当前会话中
+(function () {
+  "use strict";
+
+  var $ = function (id) { return document.getElementById(id); };
+  var plans = [];
+
+  // labels 界面文字，{0}、{1}为参数占位符；页面中的中文为未加载脚本时的默认文字
+  var labels = {
+    zh: {
+      title: "ElstLic 签发控制台", tabIssue: "签发", tabRecords: "签发记录", logout: "退出",
+      login: "登录", token: "访问令牌",
+      issueTitle: "签发License", step1: "1. 上传node.info", step2: "2. 授权条款",
+      plan: "授权方案", customPlan: "自定义", customerTag: "客户标记", customerTagHint: "为空时使用MAC地址",
+      allowNodes: "节点数", days: "授权天数", endTime: "到期时间", permanent: "永久授权",
+      features: "功能特性", featuresHint: "以逗号分隔", override: "放行理由", overrideHint: "违反签发策略时填写",
+      issue: "签发", searchHint: "序列号、客户标记、MAC或主板ID", all: "全部", valid: "有效", revoked: "已吊销",
+      expiresBefore: "到期早于", search: "查询",
+      serial: "序列号", planColumn: "方案", issuer: "签发人", issuedAt: "签发时间", state: "状态",
+      motherBoardID: "主板ID", macAddr: "MAC地址", clientZone: "客户端时区", createdAt: "生成时间", suite: "算法套件",
+      pattern: "需符合: {0}", issued: "已签发 {0}，客户 {1}，到期 {2}", downloadLicense: "下载 license.lic",
+      issueOk: "签发成功", permanentShort: "永久", renewedFrom: "续期自 {0}",
+      download: "下载", renew: "续期", revoke: "吊销",
+      renewPrompt: "延长天数（自原到期时间起）", renewed: "已续期为 {0}",
+      revokePrompt: "吊销 {0} 的原因", revokeOk: "已吊销"
+    },
+    en: {
+      title: "ElstLic Issuing Console", tabIssue: "Issue", tabRecords: "Records", logout: "Log out",
+      login: "Log in", token: "Access token",
+      issueTitle: "Issue a license", step1: "1. Upload node.info", step2: "2. License terms",
+      plan: "Plan", customPlan: "Custom", customerTag: "Customer tag", customerTagHint: "MAC address if empty",
+      allowNodes: "Nodes", days: "Days", endTime: "Expires", permanent: "Permanent",
+      features: "Features", featuresHint: "Comma separated", override: "Override reason", overrideHint: "Required when the policy is violated",
+      issue: "Issue", searchHint: "Serial, customer tag, MAC or mainboard ID", all: "All", valid: "Valid", revoked: "Revoked",
+      expiresBefore: "Expires before", search: "Search",
+      serial: "Serial", planColumn: "Plan", issuer: "Issuer", issuedAt: "Issued", state: "State",
+      motherBoardID: "Mainboard ID", macAddr: "MAC address", clientZone: "Client time zone", createdAt: "Created", suite: "Crypto suite",
+      pattern: "Must match: {0}", issued: "Issued {0} for {1}, expires {2}", downloadLicense: "Download license.lic",
+      issueOk: "License issued", permanentShort: "Permanent", renewedFrom: "Renewed from {0}",
+      download: "Download", renew: "Renew", revoke: "Revoke",
+      renewPrompt: "Days to extend (from the current expiry)", renewed: "Renewed as {0}",
+      revokePrompt: "Reason for revoking {0}", revokeOk: "Revoked"
+    }
+  };
+  var lang = /^zh/i.test(navigator.language || "") ? "zh" : "en";
+
+  // t 按当前语言取界面文字
+  function t(key) {
+    var args = Array.prototype.slice.call(arguments, 1);
+    var text = labels[lang][key] || labels.zh[key] || key;
+    return text.replace(/\{(\d+)\}/g, function (m, i) { return args[i] === undefined ? m : args[i]; });
+  }
+
+  function translate() {
+    document.documentElement.lang = lang === "zh" ? "zh-CN" : "en";
+    document.querySelectorAll("[data-i18n]").forEach(function (el) {
+      el.textContent = t(el.dataset.i18n);
+    });
+    document.querySelectorAll("[data-i18n-placeholder]").forEach(function (el) {
+      el.placeholder = t(el.dataset.i18nPlaceholder);
+    });
+  }
+
+  function token() { return sessionStorage.getItem("elstlic-token") || ""; }
+
+  function show(text, ok) {
+    var box = $("message");
+    box.textContent = text;
+    box.className = ok ? "ok" : "";
+    box.hidden = false;
+    clearTimeout(show.timer);
+    show.timer = setTimeout(function () { box.hidden = true; }, ok ? 3000 : 8000);
+  }
+
+  // api 调用管理接口，失败时抛出带违反项的错误信息
+  function api(method, path, body) {
+    // 错误信息与界面使用同一语言
+    var init = { method: method, headers: { "Authorization": "Bearer " + token(), "Accept-Language": lang } };
+    if (body !== undefined) {
+      init.headers["Content-Type"] = "application/json";
+      init.body = JSON.stringify(body);
+    }
+    return fetch("/api/v1/" + path, init).then(function (resp) {
+      if (resp.status === 401) {
+        logout();
+      }
+      var type = resp.headers.get("Content-Type") || "";
+      var parsed = type.indexOf("application/json") === 0 ? resp.json() : resp.blob();
+      return parsed.then(function (data) {
+        if (!resp.ok) {
+          var text = (data && data.error) || resp.statusText;
+          (data && data.violations || []).forEach(function (v) { text += "\n- " + v.detail; });
+          throw new Error(text);
+        }
+        return data;
+      });
+    });
+  }
+
+  function switchTab(name) {
+    document.querySelectorAll("nav button[data-tab]").forEach(function (b) {
+      b.classList.toggle("active", b.dataset.tab === name);
+    });
+    $("issue").hidden = name !== "issue";
+    $("records").hidden = name !== "records";
+    if (name === "records") {
+      search();
+    }
+  }
+
+  function login() {
+    $("login").hidden = true;
+    $("logout").hidden = false;
+    switchTab("issue");
+    api("GET", "plans").then(function (list) {
+      plans = list;
+      var select = $("plan");
+      select.length = 1;
+      list.forEach(function (p, i) { select.add(new Option(p.name, String(i))); });
+    }).catch(function (e) { show(e.message); });
+  }
+
+  function logout() {
+    sessionStorage.removeItem("elstlic-token");
+    $("login").hidden = false;
+    $("issue").hidden = true;
+    $("records").hidden = true;
+    $("logout").hidden = true;
+  }
+
+  // readBase64 读取上传文件为base64，对应接口中的[]byte字段
+  function readBase64(file) {
+    return new Promise(function (resolve, reject) {
+      var reader = new FileReader();
+      reader.onload = function () { resolve(String(reader.result).split(",")[1] || ""); };
+      reader.onerror = function () { reject(reader.error); };
+      reader.readAsDataURL(file);
+    });
+  }
+
+  function download(blob, name) {
+    var url = URL.createObjectURL(blob);
+    var a = document.createElement("a");
+    a.href = url;
+    a.download = name;
+    document.body.appendChild(a);
+    a.click();
+    a.remove();
+    URL.revokeObjectURL(url);
+  }
+
+  function base64Blob(b64) {
+    var bin = atob(b64);
+    var bytes = new Uint8Array(bin.length);
+    for (var i = 0; i < bin.length; i++) {
+      bytes[i] = bin.charCodeAt(i);
+    }
+    return new Blob([bytes], { type: "application/octet-stream" });
+  }
+
+  function row(table, label, value) {
+    var tr = table.insertRow();
+    var th = document.createElement("th");
+    th.textContent = label;
+    tr.appendChild(th);
+    tr.insertCell().textContent = value === undefined || value === null || value === "" ? "-" : value;
+  }
+
+  function preview() {
+    var file = $("node-info").files[0];
+    var table = $("preview");
+    table.hidden = true;
+    if (!file) {
+      return;
+    }
+    readBase64(file).then(function (b64) {
+      return api("POST", "preview", { node_info: b64 });
+    }).then(function (lic) {
+      table.innerHTML = "";
+      row(table, t("motherBoardID"), lic.mother_board_id);
+      row(table, t("macAddr"), lic.mac_addr);
+      row(table, t("clientZone"), lic.client_time_zone);
+      row(table, t("createdAt"), lic.start_time);
+      row(table, t("suite"), lic.crypto_suite);
+      table.hidden = false;
+    }).catch(function (e) { show(e.message); });
+  }
+
+  function applyPlan() {
+    var p = plans[Number($("plan").value)];
+    if ($("plan").value === "" || !p) {
+      return;
+    }
+    $("allow-nodes").value = p.allow_nodes || "";
+    $("days").value = p.days || "";
+    $("end-time").value = "";
+    $("permanent").checked = !!p.permanent;
+    $("features").value = (p.features || []).join(",");
+    $("customer-tag").pattern = p.customer_tag_pattern || "";
+    $("customer-tag").title = p.customer_tag_pattern ? t("pattern", p.customer_tag_pattern) : "";
+  }
+
+  function issue(event) {
+    event.preventDefault();
+    var file = $("node-info").files[0];
+    readBase64(file).then(function (b64) {
+      var plan = plans[Number($("plan").value)];
+      var req = {
+        node_info: b64,
+        plan: $("plan").value === "" || !plan ? "" : plan.name,
+        customer_tag: $("customer-tag").value.trim(),
+        allow_nodes: Number($("allow-nodes").value) || 0,
+        days: Number($("days").value) || 0,
+        permanent: $("permanent").checked,
+        override: $("override").value.trim()
+      };
+      var features = $("features").value.split(",").map(function (f) { return f.trim(); }).filter(Boolean);
+      if (features.length) {
+        req.features = features;
+      }
+      if ($("end-time").value) {
+        req.end_time = new Date($("end-time").value).toISOString();
+      }
+      return api("POST", "licenses", req);
+    }).then(function (resp) {
+      var rec = resp.record;
+      var box = $("issue-result");
+      box.innerHTML = "";
+      var p = document.createElement("p");
+      p.textContent = t("issued", rec.serial, rec.customer_tag, rec.end_time);
+      var btn = document.createElement("button");
+      btn.textContent = t("downloadLicense");
+      btn.type = "button";
+      btn.onclick = function () { download(base64Blob(resp.license), "license.lic"); };
+      box.appendChild(p);
+      box.appendChild(btn);
+      box.hidden = false;
+      show(t("issueOk"), true);
+    }).catch(function (e) { show(e.message); });
+  }
+
+  function search(event) {
+    if (event) {
+      event.preventDefault();
+    }
+    var params = new URLSearchParams();
+    if ($("q").value.trim()) {
+      params.set("q", $("q").value.trim());
+    }
+    if ($("revoked").value) {
+      params.set("revoked", $("revoked").value);
+    }
+    if ($("expires-before").value) {
+      params.set("expires_before", new Date($("expires-before").value).toISOString());
+    }
+    api("GET", "licenses?" + params.toString()).then(function (list) {
+      var tbody = $("record-table").tBodies[0];
+      tbody.innerHTML = "";
+      list.slice().reverse().forEach(function (rec) {
+        var tr = tbody.insertRow();
+        [rec.serial, rec.customer_tag, rec.plan || "-", rec.allow_nodes, rec.permanent_auth ? t("permanentShort") : rec.end_time,
+          rec.issuer || "-", rec.time].forEach(function (v) { tr.insertCell().textContent = v; });
+        var state = tr.insertCell();
+        state.textContent = rec.revoked ? t("revoked") : (rec.renew_of ? t("renewedFrom", rec.renew_of) : t("valid"));
+        if (rec.revoked) {
+          state.className = "revoked";
+          state.title = rec.revoke_reason || "";
+        }
+        var actions = tr.insertCell();
+        action(actions, t("download"), "secondary", function () {
+          api("GET", "licenses/" + rec.serial + "/download").then(function (blob) {
+            download(blob, "license.lic");
+          }).catch(function (e) { show(e.message); });
+        });
+        if (!rec.revoked) {
+          action(actions, t("renew"), "secondary", function () { renew(rec); });
+          action(actions, t("revoke"), "danger", function () { revoke(rec); });
+        }
+      });
+    }).catch(function (e) { show(e.message); });
+  }
+
+  function action(cell, text, cls, fn) {
+    var b = document.createElement("button");
+    b.type = "button";
+    b.textContent = text;
+    b.className = cls;
+    b.onclick = fn;
+    cell.appendChild(b);
+  }
+
+  function renew(rec) {
+    var days = prompt(t("renewPrompt"), "365");
+    if (!days) {
+      return;
+    }
+    api("POST", "licenses/" + rec.serial + "/renew", { days: Number(days) || 0 }).then(function (resp) {
+      download(base64Blob(resp.license), "license.lic");
+      show(t("renewed", resp.record.serial), true);
+      search();
+    }).catch(function (e) { show(e.message); });
+  }
+
+  function revoke(rec) {
+    var reason = prompt(t("revokePrompt", rec.serial));
+    if (reason === null) {
+      return;
+    }
+    api("POST", "licenses/" + rec.serial + "/revoke", { reason: reason }).then(function () {
+      show(t("revokeOk"), true);
+      search();
+    }).catch(function (e) { show(e.message); });
+  }
+
+  translate();
+  document.querySelectorAll("nav button[data-tab]").forEach(function (b) {
+    b.onclick = function () { if (token()) { switchTab(b.dataset.tab); } };
+  });
+  $("logout").onclick = logout;
+  $("login-form").onsubmit = function (event) {
+    event.preventDefault();
+    sessionStorage.setItem("elstlic-token", $("token").value);
+    $("token").value = "";
+    login();
+  };
+  $("node-info").onchange = preview;
+  $("plan").onchange = applyPlan;
+  $("issue-form").onsubmit = issue;
+  $("search-form").onsubmit = search;
+
+  if (token()) {
+    login();
+  } else {
+    logout();
+  }
+})();
@@ -0,0 +1,268 @@
+package Audit
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"time"
+)
+
+// 审计日志的第一行是锚点：客户端生成随机初始密钥k1，以签发公钥加密后写入，只有签发方能解密。
+// 第i条记录以k_i派生的密钥加密并计算链式摘要，写入后 k_(i+1) = H("next" || k_i)，旧密钥随即丢弃。
+// 客户端只保存下一条记录的密钥，无法改写已写入的记录；导出时追加一条使用当前密钥的导出记录，
+// 删除日志末尾的记录后无法再生成能接上的导出记录。签发端解密初始密钥后逐条推导密钥并校验整条链。
+// 客户端可以丢弃整个日志并用新锚点重新生成，签发端需登记每台机器的锚点（AnchorID）以发现这种替换。
+
+// BundleVersion 审计日志包格式版本
+const BundleVersion = 2
+
+// CheckExport 导出审计日志时追加的记录，审计日志包须以该记录结尾
+const CheckExport = "export"
+
+// lookahead 记录无法解密时向后尝试的密钥数，用于定位被删除的记录
+const lookahead = 1024
+
+// ProblemKind 校验发现的问题类型
+type ProblemKind string
+
+const (
+	ProblemAnchor    ProblemKind = "anchor"    // 缺少签发方可验证的起点，或日志在中途重新开始
+	ProblemDecrypt   ProblemKind = "decrypt"   // 记录无法解密，内容被修改或替换
+	ProblemHash      ProblemKind = "hash"      // 记录内容与Hash不一致
+	ProblemChain     ProblemKind = "chain"     // Prev与上一条记录的Hash不一致，中间记录被删除或替换
+	ProblemGap       ProblemKind = "gap"       // 序号不连续
+	ProblemOrder     ProblemKind = "order"     // 记录序号与位置不符，记录重复或顺序被调整
+	ProblemClock     ProblemKind = "clock"     // 记录时间早于上一条，系统时间被回拨
+	ProblemTruncated ProblemKind = "truncated" // 日志不以导出记录结尾，末尾记录被删除
+)
+
+// Problem 审计日志校验发现的问题
+type Problem struct {
+	Index  int         `json:"index"` // 在日志中的位置，从0开始
+	Seq    uint64      `json:"seq"`
+	Kind   ProblemKind `json:"kind"`
+	Detail string      `json:"detail"`
+}
+
+// Anchor 锚点中以签发公钥加密的内容
+type Anchor struct {
+	Seed      []byte    `json:"seed"`       // 初始审计密钥
+	CreatedAt time.Time `json:"created_at"` // 开始记录审计日志的时间
+}
+
+// Chain 客户端保存的审计链状态
+type Chain struct {
+	Suite string `json:"suite"`
+	Key   []byte `json:"key"`  // 下一条记录使用的密钥
+	Seq   uint64 `json:"seq"`  // 最后一条记录的序号
+	Head  string `json:"head"` // 最后一条记录的Hash
+
+	Checked time.Time `json:"checked,omitempty"` // 最后一次校验通过的时间，由客户端设置
+}
+
+// NewAnchor 生成随机初始密钥，以签发公钥加密为锚点记录，返回锚点与新的审计链
+func NewAnchor(suite Suite.CryptoSuite, keyID string, pub crypto.PublicKey) (*Entity.AuditEntry, *Chain, error) {
+	encrypter, err := Suite.EncrypterOf(suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	var seed = make([]byte, 32)
+	_, err = rand.Read(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(&Anchor{Seed: seed, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, nil, err
+	}
+	anchor, err := encrypter.EncryptTo(pub, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry = &Entity.AuditEntry{Suite: suite.Name(), KeyID: keyID, Anchor: anchor}
+	return entry, &Chain{Suite: suite.Name(), Key: seed}, nil
+}
+
+// AnchorID 锚点的标识，签发端按机器登记以发现被整体替换的日志
+func AnchorID(entry *Entity.AuditEntry) string {
+	var sum = sha256.Sum256(entry.Anchor)
+	return hex.EncodeToString(sum[:16])
+}
+
+// OpenAnchor 使用签发私钥解密锚点
+func OpenAnchor(entry *Entity.AuditEntry, priv crypto.Signer) (*Anchor, error) {
+	if len(entry.Anchor) == 0 {
+		return nil, I18n.E(I18n.CodeNotAnchor)
+	}
+	suite, err := Suite.Get(entry.Suite)
+	if err != nil {
+		return nil, err
+	}
+	encrypter, err := Suite.EncrypterOf(suite)
+	if err != nil {
+		return nil, err
+	}
+	data, err := encrypter.DecryptWith(priv, entry.Anchor)
+	if err != nil {
+		return nil, err
+	}
+	var anchor = new(Anchor)
+	err = json.Unmarshal(data, anchor)
+	if err != nil {
+		return nil, err
+	}
+	if len(anchor.Seed) == 0 {
+		return nil, I18n.E(I18n.CodeAnchorNoSeed)
+	}
+	return anchor, nil
+}
+
+// Append 将记录接在链尾：设置Seq、Prev与Hash，加密后推进密钥
+func (c *Chain) Append(rec *Entity.AuditRecord) (*Entity.AuditEntry, error) {
+	suite, err := Suite.Get(c.Suite)
+	if err != nil {
+		return nil, err
+	}
+	rec.Seq = c.Seq + 1
+	rec.Prev = c.Head
+	hash, err := Digest(suite, derive(suite, "mac", c.Key), rec)
+	if err != nil {
+		return nil, err
+	}
+	rec.Hash = hash
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := suite.Encrypt(derive(suite, "enc", c.Key)[:16], data)
+	if err != nil {
+		return nil, err
+	}
+	c.Key = derive(suite, "next", c.Key)
+	c.Seq, c.Head = rec.Seq, rec.Hash
+	return &Entity.AuditEntry{Suite: suite.Name(), Data: encrypted}, nil
+}
+
+// derive 由链上的密钥派生用途为label的密钥
+func derive(suite Suite.CryptoSuite, label string, key []byte) []byte {
+	return suite.Hash(append([]byte("elst-audit|"+label+"|"), key...))
+}
+
+// Digest 计算记录的链式摘要，rec.Hash不参与计算
+func Digest(suite Suite.CryptoSuite, key []byte, rec *Entity.AuditRecord) (string, error) {
+	var unsigned = *rec
+	unsigned.Hash = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	var inner = suite.Hash(append(append([]byte{}, key...), data...))
+	return hex.EncodeToString(suite.Hash(append(append([]byte{}, key...), inner...))), nil
+}
+
+// open 使用链上的密钥解密记录
+func open(suite Suite.CryptoSuite, key []byte, entry *Entity.AuditEntry) (*Entity.AuditRecord, error) {
+	data, err := suite.Decrypt(derive(suite, "enc", key)[:16], entry.Data)
+	if err != nil {
+		return nil, err
+	}
+	var rec = new(Entity.AuditRecord)
+	err = json.Unmarshal(data, rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Verify 从锚点推导密钥，解密并校验整条审计链，返回可解密的记录与发现的问题。
+// openAnchor 使用签发私钥解密锚点，返回初始密钥；问题说明按locale语言输出。
+func Verify(entries []*Entity.AuditEntry, openAnchor func(*Entity.AuditEntry) ([]byte, error), locale I18n.Locale) ([]*Entity.AuditRecord, []Problem) {
+	var records = make([]*Entity.AuditRecord, 0, len(entries))
+	var problems = make([]Problem, 0)
+	var suite Suite.CryptoSuite
+	var key []byte
+	var prev *Entity.AuditRecord
+	var expected uint64
+	for i, entry := range entries {
+		if len(entry.Anchor) > 0 {
+			if i > 0 {
+				problems = append(problems, Problem{Index: i, Kind: ProblemAnchor, Detail: I18n.T(locale, I18n.MsgAuditRestarted)})
+			}
+			seed, err := openAnchor(entry)
+			if err == nil {
+				suite, err = Suite.Get(entry.Suite)
+			}
+			if err != nil {
+				problems = append(problems, Problem{Index: i, Kind: ProblemAnchor, Detail: I18n.Localize(err, locale)})
+				suite, key = nil, nil
+				continue
+			}
+			key, prev, expected = seed, nil, 1
+			continue
+		}
+		if key == nil {
+			problems = append(problems, Problem{Index: i, Kind: ProblemAnchor, Detail: I18n.T(locale, I18n.MsgAuditNoAnchor)})
+			continue
+		}
+		// 中间记录被删除时，后续记录使用更靠后的密钥，向后推导定位
+		var rec *Entity.AuditRecord
+		var skipped uint64
+		var k = key
+		for ; skipped <= lookahead; skipped++ {
+			var err error
+			rec, err = open(suite, k, entry)
+			if err == nil {
+				break
+			}
+			k = derive(suite, "next", k)
+		}
+		if rec == nil {
+			problems = append(problems, Problem{Index: i, Seq: expected, Kind: ProblemDecrypt, Detail: I18n.T(locale, I18n.MsgAuditDecrypt)})
+			key = derive(suite, "next", key)
+			expected++
+			prev = nil
+			continue
+		}
+		records = append(records, rec)
+		hash, err := Digest(suite, derive(suite, "mac", k), rec)
+		if err != nil || hash != rec.Hash {
+			problems = append(problems, Problem{Index: i, Seq: rec.Seq, Kind: ProblemHash, Detail: I18n.T(locale, I18n.MsgAuditHash)})
+		}
+		switch {
+		case skipped > 0:
+			problems = append(problems, Problem{Index: i, Seq: rec.Seq, Kind: ProblemGap, Detail: I18n.T(locale, I18n.MsgAuditGap, expected, expected+skipped-1)})
+		case rec.Seq != expected:
+			problems = append(problems, Problem{Index: i, Seq: rec.Seq, Kind: ProblemOrder, Detail: I18n.T(locale, I18n.MsgAuditOrder, rec.Seq, expected)})
+		case prev != nil && rec.Prev != prev.Hash:
+			problems = append(problems, Problem{Index: i, Seq: rec.Seq, Kind: ProblemChain, Detail: I18n.T(locale, I18n.MsgAuditChain)})
+		}
+		if prev != nil && rec.Time.Before(prev.Time) {
+			problems = append(problems, Problem{Index: i, Seq: rec.Seq, Kind: ProblemClock, Detail: I18n.T(locale, I18n.MsgAuditClock, rec.Time.Format(time.RFC3339), prev.Time.Format(time.RFC3339))})
+		}
+		key = derive(suite, "next", k)
+		expected = expected + skipped + 1
+		prev = rec
+	}
+	if len(records) == 0 || records[len(records)-1].Check != CheckExport || prev == nil {
+		problems = append(problems, Problem{Index: len(entries) - 1, Kind: ProblemTruncated, Detail: I18n.T(locale, I18n.MsgAuditTruncated)})
+	}
+	return records, problems
+}
+
+// ParseBundle 解析审计日志包
+func ParseBundle(data []byte) (*Entity.AuditBundle, error) {
+	var bundle = new(Entity.AuditBundle)
+	err := json.Unmarshal(data, bundle)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Version != BundleVersion {
+		return nil, I18n.E(I18n.CodeAuditVersion, bundle.Version)
+	}
+	return bundle, nil
+}
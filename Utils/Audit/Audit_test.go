@@ -0,0 +1,188 @@
+package Audit
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+)
+
+// newLog 以一次性签发密钥生成锚点，追加checks对应的记录，返回日志、审计链与签发私钥
+func newLog(t *testing.T, checks ...string) ([]*Entity.AuditEntry, *Chain, crypto.Signer) {
+	t.Helper()
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, chain := newAnchor(t, priv)
+	return appendRecords(t, entries, chain, checks...), chain, priv
+}
+
+// newAnchor 以priv的公钥生成锚点
+func newAnchor(t *testing.T, priv crypto.Signer) ([]*Entity.AuditEntry, *Chain) {
+	t.Helper()
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor, chain, err := NewAnchor(suite, "key-1", priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*Entity.AuditEntry{anchor}, chain
+}
+
+// opener 使用priv解密锚点
+func opener(priv crypto.Signer) func(*Entity.AuditEntry) ([]byte, error) {
+	return func(entry *Entity.AuditEntry) ([]byte, error) {
+		anchor, err := OpenAnchor(entry, priv)
+		if err != nil {
+			return nil, err
+		}
+		return anchor.Seed, nil
+	}
+}
+
+func appendRecords(t *testing.T, entries []*Entity.AuditEntry, chain *Chain, checks ...string) []*Entity.AuditEntry {
+	t.Helper()
+	var base = time.Now()
+	for i, check := range checks {
+		entry, err := chain.Append(&Entity.AuditRecord{Time: base.Add(time.Duration(i) * time.Second), Result: check == "", Check: check, Serial: "S1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// kinds 问题类型集合
+func kinds(problems []Problem) map[ProblemKind]bool {
+	var result = make(map[ProblemKind]bool)
+	for _, problem := range problems {
+		result[problem.Kind] = true
+	}
+	return result
+}
+
+func TestVerifyIntact(t *testing.T) {
+	entries, _, priv := newLog(t, "", "expired", "", CheckExport)
+	records, problems := Verify(entries, opener(priv), I18n.ZH)
+	if len(problems) != 0 {
+		t.Fatalf("intact log has problems: %+v", problems)
+	}
+	if len(records) != 4 || records[1].Check != "expired" || records[3].Seq != 4 {
+		t.Fatalf("records = %+v", records)
+	}
+}
+
+func TestVerifyTamperedRecord(t *testing.T) {
+	entries, _, priv := newLog(t, "", "expired", "", CheckExport)
+	entries[2].Data[len(entries[2].Data)-1] ^= 1
+	_, problems := Verify(entries, opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemDecrypt] {
+		t.Fatalf("tampered record not detected: %+v", problems)
+	}
+}
+
+func TestVerifyDeletedRecord(t *testing.T) {
+	entries, _, priv := newLog(t, "", "expired", "", CheckExport)
+	entries = append(entries[:2], entries[3:]...)
+	records, problems := Verify(entries, opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemGap] {
+		t.Fatalf("deleted record not detected: %+v", problems)
+	}
+	// 删除之后的记录仍能解密
+	if len(records) != 3 || records[len(records)-1].Check != CheckExport {
+		t.Fatalf("records = %+v", records)
+	}
+}
+
+// TestVerifyReorderedSeq 密钥位置正确但序号不符时报告顺序问题，而不是颠倒的缺失区间
+func TestVerifyReorderedSeq(t *testing.T) {
+	entries, chain, priv := newLog(t, "", "expired")
+	chain.Seq--
+	entries = appendRecords(t, entries, chain, "", CheckExport)
+	_, problems := Verify(entries, opener(priv), I18n.EN)
+	if len(problems) == 0 || problems[0].Kind != ProblemOrder || problems[0].Seq != 2 {
+		t.Fatalf("problems = %+v", problems)
+	}
+	if kinds(problems)[ProblemGap] {
+		t.Errorf("reordered record reported as a gap: %+v", problems)
+	}
+	if problems[0].Detail != "The record has sequence 2 but 3 was expected: records were duplicated or reordered" {
+		t.Errorf("detail = %q", problems[0].Detail)
+	}
+}
+
+func TestVerifyTruncated(t *testing.T) {
+	entries, _, priv := newLog(t, "", "expired", "", CheckExport)
+	_, problems := Verify(entries[:3], opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemTruncated] {
+		t.Fatalf("truncated log not detected: %+v", problems)
+	}
+}
+
+// TestVerifyTruncatedToExport 截断到更早的导出记录时链本身完整，由签发端比较导出时间发现
+func TestVerifyTruncatedToExport(t *testing.T) {
+	entries, chain, priv := newLog(t, "", CheckExport)
+	var exported = len(entries)
+	entries = appendRecords(t, entries, chain, "expired", CheckExport)
+	records, problems := Verify(entries[:exported], opener(priv), I18n.ZH)
+	if len(problems) != 0 {
+		t.Fatalf("problems = %+v", problems)
+	}
+	if records[len(records)-1].Check != CheckExport {
+		t.Fatal("log does not end with the earlier export")
+	}
+}
+
+func TestVerifyMissingAnchor(t *testing.T) {
+	entries, _, priv := newLog(t, "", CheckExport)
+	_, problems := Verify(entries[1:], opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemAnchor] {
+		t.Fatalf("missing anchor not detected: %+v", problems)
+	}
+}
+
+// TestVerifyForeignAnchor 客户以自己的密钥生成锚点，签发私钥无法解密
+func TestVerifyForeignAnchor(t *testing.T) {
+	forged, _, _ := newLog(t, "", CheckExport)
+	_, _, priv := newLog(t)
+	records, problems := Verify(forged, opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemAnchor] || len(records) != 0 {
+		t.Fatalf("foreign anchor accepted: records=%d problems=%+v", len(records), problems)
+	}
+}
+
+func TestVerifyRestartedLog(t *testing.T) {
+	entries, _, priv := newLog(t, "", "expired")
+	// 删除审计链状态后客户端从新锚点重新开始
+	restart, chain := newAnchor(t, priv)
+	entries = appendRecords(t, append(entries, restart...), chain, CheckExport)
+	_, problems := Verify(entries, opener(priv), I18n.ZH)
+	if !kinds(problems)[ProblemAnchor] {
+		t.Fatalf("restarted log not detected: %+v", problems)
+	}
+}
+
+func TestAnchorCreatedAt(t *testing.T) {
+	entries, _, priv := newLog(t)
+	anchor, err := OpenAnchor(entries[0], priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(anchor.CreatedAt) > time.Minute || len(anchor.Seed) != 32 {
+		t.Fatalf("anchor = %+v", anchor)
+	}
+	if AnchorID(entries[0]) == "" {
+		t.Fatal("empty anchor id")
+	}
+}
@@ -0,0 +1,65 @@
+package Utils
+
+import (
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"reflect"
+	"strings"
+)
+
+// FieldChange License条款字段变化
+type FieldChange struct {
+	Field string `json:"field"` // JSON字段名
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// runtimeFields 运行期字段，不属于授权条款
+var runtimeFields = map[string]bool{
+	"check_code":      true,
+	"last_check_time": true,
+	"check_status":    true,
+	"use_nodes":       true,
+	"node_list":       true,
+}
+
+// DiffLicense 比较两个License的授权条款，返回变化的字段
+func DiffLicense(oldLic, newLic *Entity.License) []FieldChange {
+	var changes = make([]FieldChange, 0)
+	if oldLic == nil {
+		oldLic = new(Entity.License)
+	}
+	if newLic == nil {
+		newLic = new(Entity.License)
+	}
+	var oldValue = reflect.ValueOf(oldLic).Elem()
+	var newValue = reflect.ValueOf(newLic).Elem()
+	var typ = oldValue.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		var name = strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || runtimeFields[name] {
+			continue
+		}
+		var o, n = oldValue.Field(i).Interface(), newValue.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Old: formatField(o), New: formatField(n)})
+	}
+	return changes
+}
+
+func formatField(v interface{}) string {
+	switch value := v.(type) {
+	case []string:
+		return strings.Join(value, ",")
+	case []*Entity.ResellerCert:
+		var serials = make([]string, 0, len(value))
+		for _, cert := range value {
+			serials = append(serials, cert.Serial)
+		}
+		return strings.Join(serials, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,211 @@
+package Envelope
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"sync"
+)
+
+// License/节点信息文件封装格式（多字节整数均为大端序）：
+//
+//	Magic      [6]byte  固定为 "ELSTLC"
+//	Version    uint8    封装格式版本
+//	EncAlg     uint8    对称加密算法ID
+//	SigAlg     uint8    签名算法ID
+//	HashAlg    uint8    摘要算法ID
+//	KeyIDLen   uint8    密钥ID长度
+//	KeyID      []byte   签发密钥ID
+//	PayloadLen uint32   载荷长度
+//	Payload    []byte   加密后的License数据
+//	SigLen     uint16   签名长度，未签名时为0
+//	Signature  []byte   对Magic至Payload全部字节的签名
+//
+// 不带Magic的文件视为版本0（旧格式）：整个文件即为插入了SM4密钥的base64密文。
+
+// Magic 文件头标识
+var Magic = []byte("ELSTLC")
+
+const (
+	VersionLegacy  uint8 = 0 // 旧格式，无文件头
+	VersionV1      uint8 = 1 // 带文件头的封装格式
+	CurrentVersion       = VersionV1
+)
+
+// 对称加密算法ID
+const (
+	EncNone   uint8 = 0
+	EncSM4CBC uint8 = 1 // SM4-CBC，无认证，仅用于读取旧文件
+	EncAESGCM uint8 = 2 // AES-128-GCM，随机nonce置于密文前
+	EncSM4GCM uint8 = 3 // SM4-GCM，随机nonce置于密文前
+)
+
+// 各加密算法的密钥均按Offset/Step插入载荷
+
+// 签名算法ID
+const (
+	SigNone    uint8 = 0
+	SigSM2     uint8 = 1
+	SigEd25519 uint8 = 2
+)
+
+// 摘要算法ID
+const (
+	HashNone   uint8 = 0
+	HashSM3    uint8 = 1
+	HashSHA256 uint8 = 2
+)
+
+var (
+	ErrUnknownVersion error = I18n.E(I18n.CodeUnknownVersion)
+	ErrTruncated      error = I18n.E(I18n.CodeTruncated)
+)
+
+// Envelope License文件封装
+type Envelope struct {
+	Version   uint8
+	EncAlg    uint8
+	SigAlg    uint8
+	HashAlg   uint8
+	KeyID     string
+	Payload   []byte
+	Signature []byte
+}
+
+// Reader 按版本解析文件内容
+type Reader func(data []byte) (*Envelope, error)
+
+var (
+	readersMu sync.RWMutex
+	readers   = make(map[uint8]Reader)
+)
+
+func init() {
+	RegisterReader(VersionLegacy, readLegacy)
+	RegisterReader(VersionV1, readV1)
+}
+
+// RegisterReader 注册指定版本的解析器，重复注册会覆盖旧的解析器
+func RegisterReader(version uint8, reader Reader) {
+	readersMu.Lock()
+	defer readersMu.Unlock()
+	readers[version] = reader
+}
+
+// Parse 识别文件版本并交给对应的解析器
+func Parse(data []byte) (*Envelope, error) {
+	var version = VersionLegacy
+	if bytes.HasPrefix(data, Magic) {
+		if len(data) <= len(Magic) {
+			return nil, ErrTruncated
+		}
+		version = data[len(Magic)]
+	}
+	readersMu.RLock()
+	reader, ok := readers[version]
+	readersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return reader(data)
+}
+
+// Marshal 按Version序列化，未设置版本的新封装使用当前版本
+func (e *Envelope) Marshal() ([]byte, error) {
+	if e.Version == VersionLegacy {
+		e.Version = CurrentVersion
+	}
+	if len(e.KeyID) > 0xFF {
+		return nil, I18n.E(I18n.CodeKeyIDTooLong)
+	}
+	if len(e.Signature) > 0xFFFF {
+		return nil, I18n.E(I18n.CodeSignatureTooLong)
+	}
+	var buf = bytes.NewBuffer(e.SignedBytes())
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(e.Signature)))
+	buf.Write(e.Signature)
+	return buf.Bytes(), nil
+}
+
+// SignedBytes 返回参与签名的字节（Magic至Payload）
+func (e *Envelope) SignedBytes() []byte {
+	var buf = new(bytes.Buffer)
+	buf.Write(Magic)
+	buf.WriteByte(e.Version)
+	buf.WriteByte(e.EncAlg)
+	buf.WriteByte(e.SigAlg)
+	buf.WriteByte(e.HashAlg)
+	buf.WriteByte(uint8(len(e.KeyID)))
+	buf.WriteString(e.KeyID)
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(e.Payload)))
+	buf.Write(e.Payload)
+	return buf.Bytes()
+}
+
+// readLegacy 解析旧格式文件
+func readLegacy(data []byte) (*Envelope, error) {
+	return &Envelope{
+		Version: VersionLegacy,
+		EncAlg:  EncSM4CBC,
+		SigAlg:  SigNone,
+		HashAlg: HashSM3,
+		Payload: data,
+	}, nil
+}
+
+// readV1 解析V1格式文件
+func readV1(data []byte) (*Envelope, error) {
+	var r = bytes.NewReader(data[len(Magic):])
+	var header [5]byte
+	if _, err := readFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	var env = &Envelope{
+		Version: header[0],
+		EncAlg:  header[1],
+		SigAlg:  header[2],
+		HashAlg: header[3],
+	}
+	var keyID = make([]byte, header[4])
+	if _, err := readFull(r, keyID); err != nil {
+		return nil, err
+	}
+	env.KeyID = string(keyID)
+
+	var payloadLen uint32
+	if err := binary.Read(r, binary.BigEndian, &payloadLen); err != nil {
+		return nil, ErrTruncated
+	}
+	if int64(payloadLen) > int64(r.Len()) {
+		return nil, ErrTruncated
+	}
+	env.Payload = make([]byte, payloadLen)
+	if _, err := readFull(r, env.Payload); err != nil {
+		return nil, err
+	}
+
+	var sigLen uint16
+	if err := binary.Read(r, binary.BigEndian, &sigLen); err != nil {
+		return nil, ErrTruncated
+	}
+	env.Signature = make([]byte, sigLen)
+	if _, err := readFull(r, env.Signature); err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, I18n.E(I18n.CodeTrailingData)
+	}
+	return env, nil
+}
+
+// readFull 读取len(buf)字节，剩余数据不足时返回ErrTruncated
+func readFull(r *bytes.Reader, buf []byte) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	if r.Len() < len(buf) {
+		return 0, ErrTruncated
+	}
+	return r.Read(buf)
+}
@@ -0,0 +1,128 @@
+package Envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+func newEnvelope() *Envelope {
+	return &Envelope{
+		EncAlg:    EncSM4GCM,
+		SigAlg:    SigSM2,
+		HashAlg:   HashSM3,
+		KeyID:     "gm-0011",
+		Payload:   []byte("payload"),
+		Signature: []byte("signature"),
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	var data = []byte("bGVnYWN5IGNpcGhlcnRleHQ=")
+	env, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != VersionLegacy || env.EncAlg != EncSM4CBC || env.SigAlg != SigNone || env.HashAlg != HashSM3 {
+		t.Errorf("legacy envelope = %+v", env)
+	}
+	if !bytes.Equal(env.Payload, data) || env.KeyID != "" || env.Signature != nil {
+		t.Errorf("legacy payload = %q, key ID = %q, signature = %x", env.Payload, env.KeyID, env.Signature)
+	}
+}
+
+// TestRoundTrip 新封装按当前版本序列化，解析后各字段与参与签名的字节不变
+func TestRoundTrip(t *testing.T) {
+	var env = newEnvelope()
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != CurrentVersion || data[len(Magic)] != CurrentVersion {
+		t.Fatalf("version = %d, header version = %d, want %d", env.Version, data[len(Magic)], CurrentVersion)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version != VersionV1 || parsed.EncAlg != env.EncAlg || parsed.SigAlg != env.SigAlg || parsed.HashAlg != env.HashAlg || parsed.KeyID != env.KeyID {
+		t.Errorf("parsed = %+v, want %+v", parsed, env)
+	}
+	if !bytes.Equal(parsed.Payload, env.Payload) || !bytes.Equal(parsed.Signature, env.Signature) {
+		t.Errorf("payload = %q, signature = %q", parsed.Payload, parsed.Signature)
+	}
+	if !bytes.Equal(parsed.SignedBytes(), env.SignedBytes()) {
+		t.Error("signed bytes changed by the round trip")
+	}
+	remarshaled, err := parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(remarshaled, data) {
+		t.Errorf("Marshal(Parse(data)) = %x, want %x", remarshaled, data)
+	}
+}
+
+// TestSignedBytesVersion 参与签名的字节使用封装自身的版本
+func TestSignedBytesVersion(t *testing.T) {
+	var env = newEnvelope()
+	env.Version = 7
+	if got := env.SignedBytes()[len(Magic)]; got != 7 {
+		t.Errorf("signed version = %d, want 7", got)
+	}
+	data, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[len(Magic)] != 7 {
+		t.Errorf("marshaled version = %d, want 7", data[len(Magic)])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	data, err := newEnvelope().Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 各字段的起始位置
+	var keyID = len(Magic) + 6
+	var payload = keyID + len("gm-0011") + 4
+	var signature = payload + len("payload") + 2
+	var unknown = append([]byte(nil), data...)
+	unknown[len(Magic)] = 0xEE
+
+	for name, c := range map[string]struct {
+		data []byte
+		code I18n.ID
+	}{
+		"magic only":       {Magic, I18n.CodeTruncated},
+		"header":           {data[:len(Magic)+3], I18n.CodeTruncated},
+		"key id":           {data[:keyID+3], I18n.CodeTruncated},
+		"payload length":   {data[:payload-2], I18n.CodeTruncated},
+		"payload":          {data[:payload+3], I18n.CodeTruncated},
+		"signature length": {data[:signature-1], I18n.CodeTruncated},
+		"signature":        {data[:len(data)-1], I18n.CodeTruncated},
+		"trailing data":    {append(append([]byte(nil), data...), 0), I18n.CodeTrailingData},
+		"unknown version":  {unknown, I18n.CodeUnknownVersion},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env, err := Parse(c.data)
+			if I18n.Code(err) != c.code {
+				t.Fatalf("Parse error = %v, want %s", err, c.code)
+			}
+			if env != nil {
+				t.Errorf("Parse returned %+v", env)
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	if _, err := (&Envelope{KeyID: string(make([]byte, 0x100))}).Marshal(); I18n.Code(err) != I18n.CodeKeyIDTooLong {
+		t.Errorf("Marshal error = %v, want %s", err, I18n.CodeKeyIDTooLong)
+	}
+	if _, err := (&Envelope{Signature: make([]byte, 0x10000)}).Marshal(); I18n.Code(err) != I18n.CodeSignatureTooLong {
+		t.Errorf("Marshal error = %v, want %s", err, I18n.CodeSignatureTooLong)
+	}
+}
@@ -0,0 +1,103 @@
+package Utils
+
+import (
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// FingerprintProvider 硬件指纹来源，诊断时逐一采集用于分析主板ID不一致的原因
+type FingerprintProvider struct {
+	Name string
+	GOOS string // 适用的操作系统，为空表示不限
+	Read func() (string, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = []FingerprintProvider{
+		{Name: "motherboard_id", Read: readMotherBoardID},
+		{Name: "dmidecode_system", GOOS: "linux", Read: readDmidecodeSystem},
+		{Name: "dmi_board_serial", GOOS: "linux", Read: readFileFunc("/sys/class/dmi/id/board_serial")},
+		{Name: "dmi_product_serial", GOOS: "linux", Read: readFileFunc("/sys/class/dmi/id/product_serial")},
+		{Name: "dmi_product_uuid", GOOS: "linux", Read: readFileFunc("/sys/class/dmi/id/product_uuid")},
+		{Name: "machine_id", GOOS: "linux", Read: readFileFunc("/etc/machine-id")},
+		{Name: "wmic_baseboard", GOOS: "windows", Read: readCommandFunc("wmic", "baseboard", "get", "SerialNumber")},
+		{Name: "wmic_csproduct_uuid", GOOS: "windows", Read: readCommandFunc("wmic", "csproduct", "get", "UUID")},
+	}
+)
+
+// RegisterFingerprintProvider 注册硬件指纹来源
+func RegisterFingerprintProvider(provider FingerprintProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers = append(providers, provider)
+}
+
+// GetMotherBoardID 按当前操作系统获取主板ID，与License绑定使用的算法一致
+func GetMotherBoardID() string {
+	switch runtime.GOOS {
+	case "linux":
+		return GetLinuxMotherBoardID()
+	case "windows":
+		return GetWinMotherBoardID()
+	}
+	return ""
+}
+
+// Fingerprints 采集适用于当前操作系统的全部硬件指纹
+func Fingerprints() []Entity.Fingerprint {
+	providersMu.RLock()
+	var list = append([]FingerprintProvider{}, providers...)
+	providersMu.RUnlock()
+	var result = make([]Entity.Fingerprint, 0, len(list))
+	for _, provider := range list {
+		if provider.GOOS != "" && provider.GOOS != runtime.GOOS {
+			continue
+		}
+		value, err := provider.Read()
+		var fp = Entity.Fingerprint{Provider: provider.Name, Value: value}
+		if err != nil {
+			fp.Error = err.Error()
+		}
+		result = append(result, fp)
+	}
+	return result
+}
+
+func readMotherBoardID() (string, error) {
+	var id = GetMotherBoardID()
+	if id == "" {
+		return "", I18n.E(I18n.CodeNoMotherBoardID)
+	}
+	return id, nil
+}
+
+func readDmidecodeSystem() (string, error) {
+	result, err := exec.Command("dmidecode", "-t", "system").CombinedOutput()
+	if err != nil {
+		return strings.TrimSpace(string(result)), err
+	}
+	return strings.TrimSpace(string(result)), nil
+}
+
+func readFileFunc(path string) func() (string, error) {
+	return func() (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+}
+
+func readCommandFunc(name string, args ...string) func() (string, error) {
+	return func() (string, error) {
+		result, err := exec.Command(name, args...).CombinedOutput()
+		return strings.TrimSpace(string(result)), err
+	}
+}
@@ -7,36 +7,104 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/KDF"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
 	"github.com/tjfoc/gmsm/sm2"
 	"github.com/tjfoc/gmsm/sm3"
 	"github.com/tjfoc/gmsm/sm4"
 	"github.com/tjfoc/gmsm/x509"
 	"io"
-	"log"
 	"os"
 )
 
 var (
 	PrivateKey *sm2.PrivateKey
 	PublicKey  *sm2.PublicKey
-	Header     = "elst.dev"
+	Header     = "elst.dev" // 旧版私钥文件的固定口令，仅用于读取
 	DefaultKey = []byte("a2IS83Elst01839S")
 	DefaultIV  = []byte("13fd53e24a2779b4")
 )
 
+// logger GM包日志，默认不输出
+var logger = Logger.Nop
+
+// SetLogger 设置GM包日志，为空时不输出，应在调用其它函数前设置
+func SetLogger(l Logger.Logger) {
+	logger = Logger.OrNop(l)
+}
+
+var (
+	ErrInvalidCiphertext error = I18n.E(I18n.CodeInvalidCiphertext) // base64解码失败或长度不合法
+	ErrBadPadding        error = I18n.E(I18n.CodeBadPadding)        // CBC解密后填充不合法，通常意味着密钥错误或数据被篡改
+	ErrAuthFailed        error = I18n.E(I18n.CodeAuthFailed)        // GCM认证标签不匹配
+)
+
 func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
 	padding := blockSize - len(ciphertext)%blockSize
 	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
 	return append(ciphertext, padtext...)
 }
 
-func PKCS5UnPadding(origData []byte) []byte {
+// PKCS5UnPadding 去除并校验PKCS5填充
+func PKCS5UnPadding(origData []byte, blockSize int) ([]byte, error) {
 	length := len(origData)
+	if length == 0 || length%blockSize != 0 {
+		return nil, ErrBadPadding
+	}
 	unpadding := int(origData[length-1])
-	return origData[:(length - unpadding)]
+	if unpadding == 0 || unpadding > blockSize {
+		return nil, ErrBadPadding
+	}
+	for _, b := range origData[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, ErrBadPadding
+		}
+	}
+	return origData[:(length - unpadding)], nil
 }
 
-// SM4Encrypt SM4加密
+// SM4GCMEncrypt SM4-GCM加密，使用随机nonce，输出为 nonce || 密文 || 认证标签
+func SM4GCMEncrypt(origData, key, additionalData []byte) ([]byte, error) {
+	aead, err := newSM4GCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, origData, additionalData), nil
+}
+
+// SM4GCMDecrypt SM4-GCM解密，密文被篡改时返回ErrAuthFailed
+func SM4GCMDecrypt(cryted, key, additionalData []byte) ([]byte, error) {
+	aead, err := newSM4GCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(cryted) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce := cryted[:aead.NonceSize()]
+	origData, err := aead.Open(nil, nonce, cryted[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return origData, nil
+}
+
+func newSM4GCM(key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SM4Encrypt SM4-CBC加密
+//
+// Deprecated: CBC模式不能发现篡改且IV固定，仅用于兼容旧文件，新数据请使用SM4GCMEncrypt。
 func SM4Encrypt(origData, key, IV []byte) ([]byte, error) {
 	if key == nil {
 		key = DefaultKey
@@ -46,7 +114,7 @@ func SM4Encrypt(origData, key, IV []byte) ([]byte, error) {
 	}
 	block, err := sm4.NewCipher(key)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM4Encrypt"), Logger.Err(err))
 		return nil, err
 	}
 	blockSize := block.BlockSize()
@@ -58,7 +126,7 @@ func SM4Encrypt(origData, key, IV []byte) ([]byte, error) {
 	return []byte(result), nil
 }
 
-// SM4Decrypt SM4解密
+// SM4Decrypt SM4-CBC解密
 func SM4Decrypt(cryted, key, IV []byte) ([]byte, error) {
 	if key == nil {
 		key = DefaultKey
@@ -66,95 +134,126 @@ func SM4Decrypt(cryted, key, IV []byte) ([]byte, error) {
 	if IV == nil {
 		IV = DefaultIV
 	}
-	cryted, _ = base64.StdEncoding.DecodeString(string(cryted))
+	cryted, err := base64.StdEncoding.DecodeString(string(cryted))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
 	block, err := sm4.NewCipher(key)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM4Decrypt"), Logger.Err(err))
 		return nil, err
 	}
+	if len(cryted) == 0 || len(cryted)%block.BlockSize() != 0 {
+		return nil, ErrInvalidCiphertext
+	}
 	blockMode := cipher.NewCBCDecrypter(block, IV)
 	origData := make([]byte, len(cryted))
 	blockMode.CryptBlocks(origData, cryted)
-	origData = PKCS5UnPadding(origData)
-	return origData, nil
+	return PKCS5UnPadding(origData, block.BlockSize())
 }
 
-// InitSM2Key 初始化SM2密钥
-func InitSM2Key(privateKeyPath, publicKeyPath string) error {
+// InitSM2Key 初始化SM2密钥。私钥不存在时生成新密钥，并使用经Argon2id派生的口令加密保存，
+// 私钥文件权限为0600；未带KDF参数的旧私钥文件仍按固定口令Header读取。
+func InitSM2Key(privateKeyPath, publicKeyPath string, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return I18n.E(I18n.CodeEmptyPassphrase)
+	}
 	_, statErr := os.Stat(privateKeyPath)
-	file, err := os.OpenFile(privateKeyPath, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	file, err := os.OpenFile(privateKeyPath, os.O_RDWR|os.O_CREATE, 0600)
 	if file != nil {
 		defer file.Close()
 	}
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err), Logger.F(Logger.FieldPath, privateKeyPath))
+		return err
+	}
+	// 旧版本以0777创建的私钥文件收紧为0600
+	err = file.Chmod(0600)
+	if err != nil {
 		return err
 	}
 
-	publicKeyFile, err := os.OpenFile(publicKeyPath, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	publicKeyFile, err := os.OpenFile(publicKeyPath, os.O_RDWR|os.O_CREATE, 0644)
 	if publicKeyFile != nil {
 		defer publicKeyFile.Close()
 	}
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err), Logger.F(Logger.FieldPath, publicKeyPath))
 		return err
 	}
 
 	if statErr != nil {
 		PrivateKey, err = sm2.GenerateKey(rand.Reader)
 		if err != nil {
-			log.Printf("ERROR: %v\n", err)
-			log.Printf("创建密钥异常：%s", err)
+			logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 			return err
 		}
 		PublicKey = &PrivateKey.PublicKey
 	} else {
 		privateByte, err := io.ReadAll(file)
 		if err != nil {
-			log.Printf("ERROR: %v\n", err)
+			logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 			return err
 		}
 		privateBlock, _ := pem.Decode(privateByte)
-		PrivateKey, err = x509.ReadPrivateKeyFromPem(privateBlock.Bytes, []byte(Header))
+		if privateBlock == nil {
+			return I18n.E(I18n.CodeBadPrivateKeyFile)
+		}
+		pwd, err := pemPassword(privateBlock.Headers, passphrase)
 		if err != nil {
-			log.Printf("ERROR: %v\n", err)
+			return err
+		}
+		PrivateKey, err = x509.ReadPrivateKeyFromPem(privateBlock.Bytes, pwd)
+		if err != nil {
+			logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 			return err
 		}
 
 		publicByte, err := io.ReadAll(publicKeyFile)
 		if err != nil {
-			log.Printf("ERROR: %v\n", err)
+			logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 			return err
 		}
 		publicBlock, _ := pem.Decode(publicByte)
+		if publicBlock == nil {
+			return I18n.E(I18n.CodeBadPublicKeyFile)
+		}
 		PublicKey, err = x509.ReadPublicKeyFromPem(publicBlock.Bytes)
 		if err != nil {
-			log.Printf("ERROR: %v\n", err)
+			logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 			return err
 		}
 		return nil
 	}
 
-	sm2PrivateKey, err := x509.WritePrivateKeyToPem(PrivateKey, []byte(Header))
+	params, err := KDF.NewParams()
+	if err != nil {
+		return err
+	}
+	pwd, err := pemPassword(params.Headers(), passphrase)
+	if err != nil {
+		return err
+	}
+	sm2PrivateKey, err := x509.WritePrivateKeyToPem(PrivateKey, pwd)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 		return err
 	}
 
 	block := pem.Block{
 		Type:    "ELST PRIVATE KEY",
-		Headers: nil,
+		Headers: params.Headers(),
 		Bytes:   sm2PrivateKey,
 	}
 	err = pem.Encode(file, &block)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 		return err
 	}
 
 	sm2PublicKey, err := x509.WritePublicKeyToPem(PublicKey)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 		return err
 	}
 	block2 := pem.Block{
@@ -164,17 +263,33 @@ func InitSM2Key(privateKeyPath, publicKeyPath string) error {
 	}
 	err = pem.Encode(publicKeyFile, &block2)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "InitSM2Key"), Logger.Err(err))
 		return err
 	}
 	return nil
 }
 
+// pemPassword 由口令派生私钥PEM密码，没有KDF参数的旧文件使用固定口令Header
+func pemPassword(headers map[string]string, passphrase []byte) ([]byte, error) {
+	params, err := KDF.ParseHeaders(headers)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		return []byte(Header), nil
+	}
+	key, err := params.Derive(passphrase, 32)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(key)), nil
+}
+
 // SM2PublicEncrypt 公钥加密数据
 func SM2PublicEncrypt(origData []byte) ([]byte, error) {
 	asn1, err := PublicKey.EncryptAsn1(origData, rand.Reader)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM2PublicEncrypt"), Logger.Err(err))
 		return nil, err
 	}
 	result := base64.StdEncoding.EncodeToString(asn1)
@@ -185,7 +300,7 @@ func SM2PublicEncrypt(origData []byte) ([]byte, error) {
 func SM2PrivateEncrypt(origData []byte) ([]byte, error) {
 	asn1, err := PrivateKey.EncryptAsn1(origData, rand.Reader)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM2PrivateEncrypt"), Logger.Err(err))
 		return nil, err
 	}
 	result := base64.StdEncoding.EncodeToString(asn1)
@@ -197,7 +312,7 @@ func SM2PublicDecrypt(ciphertext []byte) ([]byte, error) {
 	ciphertext, _ = base64.StdEncoding.DecodeString(string(ciphertext))
 	origData, err := sm2.DecryptAsn1(PrivateKey, ciphertext)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM2PublicDecrypt"), Logger.Err(err))
 		return nil, err
 	}
 	return origData, nil
@@ -208,12 +323,12 @@ func SM2PrivateDecrypt(ciphertext []byte) (sign []byte, origData []byte, err err
 	ciphertext, _ = base64.StdEncoding.DecodeString(string(ciphertext))
 	origData, err = PrivateKey.DecryptAsn1(ciphertext)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM2PrivateDecrypt"), Logger.Err(err))
 		return nil, nil, err
 	}
 	sign, err = PrivateKey.Sign(rand.Reader, origData, nil)
 	if err != nil {
-		log.Printf("ERROR: %v\n", err)
+		logger.Error(I18n.T("", I18n.MsgCallFailed, "SM2PrivateDecrypt"), Logger.Err(err))
 		return nil, nil, err
 	}
 	return sign, origData, nil
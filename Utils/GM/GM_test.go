@@ -0,0 +1,153 @@
+package GM
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef")
+
+func TestPKCS5UnPadding(t *testing.T) {
+	var block = bytes.Repeat([]byte{'a'}, 16)
+	var cases = []struct {
+		name string
+		data []byte
+		want []byte
+		err  error
+	}{
+		{"one byte", append(bytes.Repeat([]byte{'a'}, 15), 1), bytes.Repeat([]byte{'a'}, 15), nil},
+		{"full block", append(append([]byte(nil), block...), bytes.Repeat([]byte{16}, 16)...), block, nil},
+		{"empty", nil, nil, ErrBadPadding},
+		{"not block sized", bytes.Repeat([]byte{1}, 15), nil, ErrBadPadding},
+		{"zero padding", append(bytes.Repeat([]byte{'a'}, 15), 0), nil, ErrBadPadding},
+		{"padding over block size", append(bytes.Repeat([]byte{'a'}, 15), 17), nil, ErrBadPadding},
+		{"inconsistent padding", append(bytes.Repeat([]byte{'a'}, 13), 3, 2, 3), nil, ErrBadPadding},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := PKCS5UnPadding(c.data, 16)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("error = %v, want %v", err, c.err)
+			}
+			if !bytes.Equal(got, c.want) {
+				t.Errorf("PKCS5UnPadding = %q, want %q", got, c.want)
+			}
+		})
+	}
+	for length := 0; length <= 32; length++ {
+		var data = bytes.Repeat([]byte{'x'}, length)
+		got, err := PKCS5UnPadding(PKCS5Padding(append([]byte(nil), data...), 16), 16)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("padding round trip of %d bytes = %q, %v", length, got, err)
+		}
+	}
+}
+
+func TestSM4CBC(t *testing.T) {
+	var plaintext = []byte(`{"allow_nodes":8}`)
+	encrypted, err := SM4Encrypt(plaintext, testKey, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(encrypted))
+	if err != nil || len(raw)%16 != 0 {
+		t.Fatalf("ciphertext = %q, %v", encrypted, err)
+	}
+	decrypted, err := SM4Decrypt(encrypted, testKey, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("SM4Decrypt = %q, want %q", decrypted, plaintext)
+	}
+	// 未指定密钥与IV时使用默认值
+	encrypted, err = SM4Encrypt(plaintext, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err = SM4Decrypt(encrypted, DefaultKey, DefaultIV); err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("default key round trip = %q, %v", decrypted, err)
+	}
+}
+
+func TestSM4DecryptErrors(t *testing.T) {
+	encrypted, err := SM4Encrypt([]byte(`{"allow_nodes":8}`), testKey, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(string(encrypted))
+	var encode = base64.StdEncoding.EncodeToString
+	var cases = []struct {
+		name string
+		data []byte
+		key  []byte
+		err  error
+	}{
+		{"not base64", []byte("not*base64"), testKey, ErrInvalidCiphertext},
+		{"empty", nil, testKey, ErrInvalidCiphertext},
+		{"not block sized", []byte(encode(raw[:len(raw)-1])), testKey, ErrInvalidCiphertext},
+		{"wrong key", encrypted, []byte("fedcba9876543210"), ErrBadPadding},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := SM4Decrypt(c.data, c.key, c.key)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("SM4Decrypt = %q, %v, want %v", got, err, c.err)
+			}
+		})
+	}
+	if _, err = SM4Decrypt(encrypted, []byte("short"), testKey); err == nil {
+		t.Error("SM4Decrypt accepted a 5-byte key")
+	}
+}
+
+func TestSM4GCM(t *testing.T) {
+	var plaintext = []byte(`{"allow_nodes":8}`)
+	encrypted, err := SM4GCMEncrypt(plaintext, testKey, []byte("header"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := SM4GCMEncrypt(plaintext, testKey, []byte("header"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(encrypted, again) {
+		t.Error("nonce reused")
+	}
+	decrypted, err := SM4GCMDecrypt(encrypted, testKey, []byte("header"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("SM4GCMDecrypt = %q, want %q", decrypted, plaintext)
+	}
+
+	var tampered = append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 0x01
+	var cases = []struct {
+		name string
+		data []byte
+		key  []byte
+		ad   []byte
+		err  error
+	}{
+		{"tampered", tampered, testKey, []byte("header"), ErrAuthFailed},
+		{"wrong key", encrypted, []byte("fedcba9876543210"), []byte("header"), ErrAuthFailed},
+		{"wrong additional data", encrypted, testKey, []byte("other"), ErrAuthFailed},
+		{"truncated", encrypted[:12+15], testKey, []byte("header"), ErrInvalidCiphertext},
+		{"empty", nil, testKey, nil, ErrInvalidCiphertext},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := SM4GCMDecrypt(c.data, c.key, c.ad)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("SM4GCMDecrypt = %q, %v, want %v", got, err, c.err)
+			}
+		})
+	}
+	if _, err = SM4GCMEncrypt(plaintext, []byte("short"), nil); err == nil {
+		t.Error("SM4GCMEncrypt accepted a 5-byte key")
+	}
+}
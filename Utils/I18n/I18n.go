@@ -0,0 +1,166 @@
+package I18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Locale 语言
+type Locale string
+
+const (
+	ZH Locale = "zh" // 简体中文，默认语言
+	EN Locale = "en" // 英文
+)
+
+// ID 消息ID，同时作为错误码
+type ID string
+
+var (
+	mu      sync.RWMutex
+	current Locale
+)
+
+// SetLocale 设置默认语言，为空时按环境变量检测
+func SetLocale(locale Locale) {
+	// 先解析再加锁，Parse会读取消息表
+	var parsed Locale
+	if locale != "" {
+		parsed = Parse(string(locale))
+	}
+	mu.Lock()
+	current = parsed
+	mu.Unlock()
+}
+
+// Current 默认语言：SetLocale设置的语言，否则按 LC_ALL、LC_MESSAGES、LANG 检测
+func Current() Locale {
+	mu.RLock()
+	var locale = current
+	mu.RUnlock()
+	if locale != "" {
+		return locale
+	}
+	return Detect()
+}
+
+// Detect 按 LC_ALL、LC_MESSAGES、LANG 检测语言，未设置或无法识别时为中文
+func Detect() Locale {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(key); value != "" && value != "C" && value != "POSIX" {
+			return Parse(value)
+		}
+	}
+	return ZH
+}
+
+// Parse 解析语言标记，如 en_US.UTF-8、zh-CN，无法识别时为中文
+func Parse(tag string) Locale {
+	var lang = strings.ToLower(tag)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if _, ok := catalogue[Locale(lang)]; ok {
+		return Locale(lang)
+	}
+	return ZH
+}
+
+// Or locale为空时返回默认语言
+func Or(locale Locale) Locale {
+	if locale == "" {
+		return Current()
+	}
+	return locale
+}
+
+// Register 注册或覆盖某个语言的消息
+func Register(locale Locale, messages map[ID]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if catalogue[locale] == nil {
+		catalogue[locale] = make(map[ID]string, len(messages))
+	}
+	for id, text := range messages {
+		catalogue[locale][id] = text
+	}
+}
+
+// T 按语言获取消息文本，缺失时依次回退到中文与消息ID，args用于格式化
+func T(locale Locale, id ID, args ...interface{}) string {
+	// 持有读锁时不能再调用加锁的函数，写锁等待时会死锁
+	locale = Or(locale)
+	mu.RLock()
+	text, ok := catalogue[locale][id]
+	if !ok {
+		text, ok = catalogue[ZH][id]
+	}
+	mu.RUnlock()
+	if !ok {
+		text = string(id)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Error 带错误码的错误，错误文本按语言输出。
+// 相同错误码的Error视为同一错误，可用 errors.Is 比较。
+type Error struct {
+	Code   ID
+	Args   []interface{}
+	Locale Locale // 为空时使用默认语言
+}
+
+// E 创建带错误码的错误
+func E(code ID, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+// In 创建指定语言的错误
+func In(locale Locale, code ID, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args, Locale: locale}
+}
+
+func (e *Error) Error() string {
+	return T(e.Locale, e.Code, e.Args...)
+}
+
+// Localize 按指定语言输出错误文本
+func (e *Error) Localize(locale Locale) string {
+	return T(locale, e.Code, e.Args...)
+}
+
+// Is 错误码相同即视为同一错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Code 错误链中第一个带错误码的错误的错误码，没有时为空
+func Code(err error) ID {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// Localize 按指定语言输出错误文本，错误链中带错误码的部分会被翻译
+func Localize(err error, locale Locale) string {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		return err.Error()
+	}
+	// 保留包装时追加的上下文，只替换带错误码部分的文本
+	return strings.Replace(err.Error(), e.Error(), e.Localize(locale), 1)
+}
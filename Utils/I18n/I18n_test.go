@@ -0,0 +1,67 @@
+package I18n
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetLocale(t *testing.T) {
+	defer SetLocale("")
+	SetLocale("en_US.UTF-8")
+	if got := Current(); got != EN {
+		t.Fatalf("Current() = %q, want %q", got, EN)
+	}
+	SetLocale("zh-CN")
+	if got := Current(); got != ZH {
+		t.Fatalf("Current() = %q, want %q", got, ZH)
+	}
+}
+
+// TestSetLocaleConcurrentT SetLocale与T并发调用不能死锁
+func TestSetLocaleConcurrentT(t *testing.T) {
+	defer SetLocale("")
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					if (i+j)%2 == 0 {
+						SetLocale(EN)
+					} else {
+						SetLocale(ZH)
+					}
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					if T("", CodeTampered) == "" {
+						t.Error("T returned empty text")
+						return
+					}
+					_ = E(CodeTampered).Error()
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("SetLocale and T deadlocked")
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T(EN, CodeTampered); got == string(CodeTampered) {
+		t.Fatalf("T(EN, %q) returned the message id", CodeTampered)
+	}
+	if got := T(EN, ID("no-such-id")); got != "no-such-id" {
+		t.Fatalf("T for missing id = %q", got)
+	}
+}
@@ -0,0 +1,837 @@
+package I18n
+
+// 交互提示
+const (
+	PromptSelectNetCard     ID = "prompt.select_net_card"
+	PromptNodeInfoSavePath  ID = "prompt.node_info_save_path"
+	PromptNodeInfoPath      ID = "prompt.node_info_path"
+	PromptLicPath           ID = "prompt.lic_path"
+	PromptLicSavePath       ID = "prompt.lic_save_path"
+	PromptAllowNodes        ID = "prompt.allow_nodes"
+	PromptPermanent         ID = "prompt.permanent"
+	PromptEndTime           ID = "prompt.end_time"
+	PromptCustomerTag       ID = "prompt.customer_tag"
+	PromptSetPassphrase     ID = "prompt.set_passphrase"
+	PromptPassphrase        ID = "prompt.passphrase"
+	PromptCurrentPassphrase ID = "prompt.current_passphrase"
+	PromptNewPassphrase     ID = "prompt.new_passphrase"
+	PromptConfirmPassphrase ID = "prompt.confirm_passphrase"
+	PromptProduct           ID = "prompt.product"
+	PromptEdition           ID = "prompt.edition"
+	PromptOverride          ID = "prompt.override"
+	PromptPlan              ID = "prompt.plan"
+	PromptCustomer          ID = "prompt.customer"
+)
+
+// 提示信息
+const (
+	MsgFlagNodeInfoPath   ID = "msg.flag_node_info_path"
+	MsgLicenseInfo        ID = "msg.license_info"
+	MsgInvalidNumber      ID = "msg.invalid_number"
+	MsgInvalidTime        ID = "msg.invalid_time"
+	MsgClockTampered      ID = "msg.clock_tampered"
+	MsgCheckFailed        ID = "msg.check_failed"
+	MsgCheckFailedExit    ID = "msg.check_failed_exit"
+	MsgSaveStateFailed    ID = "msg.save_state_failed"
+	MsgStartTime          ID = "msg.start_time"
+	MsgEndTime            ID = "msg.end_time"
+	MsgPolicyViolation    ID = "msg.policy_violation"
+	MsgCustomPlan         ID = "msg.custom_plan"
+	MsgCustomerTagPattern ID = "msg.customer_tag_pattern"
+	MsgNoCustomer         ID = "msg.no_customer"
+	MsgReportTitle        ID = "msg.report_title"
+	MsgReportGenerated    ID = "msg.report_generated"
+	MsgReportSerial       ID = "msg.report_serial"
+	MsgReportCustomer     ID = "msg.report_customer"
+	MsgReportProduct      ID = "msg.report_product"
+	MsgReportPlan         ID = "msg.report_plan"
+	MsgReportNodes        ID = "msg.report_nodes"
+	MsgReportEndTime      ID = "msg.report_end_time"
+	MsgReportDaysLeft     ID = "msg.report_days_left"
+	MsgReportEmpty        ID = "msg.report_empty"
+	MsgCustomerSaved      ID = "msg.customer_saved"
+	MsgCustomerID         ID = "msg.customer_id"
+	MsgCustomerName       ID = "msg.customer_name"
+	MsgCustomerContract   ID = "msg.customer_contract"
+	MsgCustomerContacts   ID = "msg.customer_contacts"
+	MsgCustomerNotes      ID = "msg.customer_notes"
+	MsgCustomerNextExpiry ID = "msg.customer_next_expiry"
+	MsgLicenseStatus      ID = "msg.license_status"
+	MsgStatusActive       ID = "msg.status_active"
+	MsgStatusRevoked      ID = "msg.status_revoked"
+	MsgStatusExpired      ID = "msg.status_expired"
+	MsgStatusPermanent    ID = "msg.status_permanent"
+	MsgLicenseTotal       ID = "msg.license_total"
+	MsgInspectFile        ID = "msg.inspect_file"
+	MsgInspectFields      ID = "msg.inspect_fields"
+	MsgInspectTimes       ID = "msg.inspect_times"
+	MsgInspectHardware    ID = "msg.inspect_hardware"
+	MsgInspectChecks      ID = "msg.inspect_checks"
+	MsgInspectChain       ID = "msg.inspect_chain"
+	MsgInspectPass        ID = "msg.inspect_pass"
+	MsgInspectFail        ID = "msg.inspect_fail"
+	MsgInspectWarn        ID = "msg.inspect_warn"
+	MsgInspectSkip        ID = "msg.inspect_skip"
+	MsgInspectEnvelope    ID = "msg.inspect_envelope"
+	MsgInspectLegacy      ID = "msg.inspect_legacy"
+	MsgInspectIntegrity   ID = "msg.inspect_integrity"
+	MsgInspectUnsigned    ID = "msg.inspect_unsigned"
+	MsgInspectNoTrust     ID = "msg.inspect_no_trust"
+	MsgInspectTampered    ID = "msg.inspect_tampered"
+	MsgInspectKeyTrusted  ID = "msg.inspect_key_trusted"
+	MsgInspectResellerKey ID = "msg.inspect_reseller_key"
+	MsgInspectNoChain     ID = "msg.inspect_no_chain"
+	MsgInspectChainOK     ID = "msg.inspect_chain_ok"
+	MsgInspectPermanent   ID = "msg.inspect_permanent"
+	MsgInspectRemaining   ID = "msg.inspect_remaining"
+	MsgInspectExpired     ID = "msg.inspect_expired"
+	MsgInspectNotYet      ID = "msg.inspect_not_yet"
+	MsgInspectNodeInfo    ID = "msg.inspect_node_info"
+	MsgDiagLicenseError   ID = "msg.diag_license_error"
+	MsgDiagLicenseOK      ID = "msg.diag_license_ok"
+	MsgDiagBoardOK        ID = "msg.diag_board_ok"
+	MsgDiagBoardMissing   ID = "msg.diag_board_missing"
+	MsgDiagBoardCollected ID = "msg.diag_board_collected"
+	MsgDiagBoardReplaced  ID = "msg.diag_board_replaced"
+	MsgDiagMACOK          ID = "msg.diag_mac_ok"
+	MsgDiagMACMissing     ID = "msg.diag_mac_missing"
+	MsgDiagZoneSame       ID = "msg.diag_zone_same"
+	MsgDiagZoneUTC        ID = "msg.diag_zone_utc"
+	MsgDiagZoneLegacy     ID = "msg.diag_zone_legacy"
+	MsgDiagClockOK        ID = "msg.diag_clock_ok"
+	MsgDiagClockBehind    ID = "msg.diag_clock_behind"
+	MsgDiagClockRollbacks ID = "msg.diag_clock_rollbacks"
+	MsgDiagBadValidity    ID = "msg.diag_bad_validity"
+	MsgDiagNotYetValid    ID = "msg.diag_not_yet_valid"
+	MsgDiagExpired        ID = "msg.diag_expired"
+	MsgDiagValid          ID = "msg.diag_valid"
+	MsgDiagChecksOK       ID = "msg.diag_checks_ok"
+	MsgDiagChecksFailed   ID = "msg.diag_checks_failed"
+	MsgAuditRestarted     ID = "msg.audit_restarted"
+	MsgAuditNoAnchor      ID = "msg.audit_no_anchor"
+	MsgAuditDecrypt       ID = "msg.audit_decrypt"
+	MsgAuditHash          ID = "msg.audit_hash"
+	MsgAuditGap           ID = "msg.audit_gap"
+	MsgAuditOrder         ID = "msg.audit_order"
+	MsgAuditChain         ID = "msg.audit_chain"
+	MsgAuditClock         ID = "msg.audit_clock"
+	MsgAuditTruncated     ID = "msg.audit_truncated"
+	MsgAuditStaleExport   ID = "msg.audit_stale_export"
+	MsgAuditReplaced      ID = "msg.audit_replaced"
+	MsgCallFailed         ID = "msg.call_failed"
+	MsgLicenseLoaded      ID = "msg.license_loaded"
+	MsgLicenseReloaded    ID = "msg.license_reloaded"
+	MsgLicenseRejected    ID = "msg.license_rejected"
+	MsgAuditWriteFailed   ID = "msg.audit_write_failed"
+	MsgAuditChainReset    ID = "msg.audit_chain_reset"
+	MsgAuditProblems      ID = "msg.audit_problems"
+	MsgKeyGenerated       ID = "msg.key_generated"
+	MsgKeyRotated         ID = "msg.key_rotated"
+	MsgLicenseIssued      ID = "msg.license_issued"
+	MsgLicenseRevoked     ID = "msg.license_revoked"
+	MsgLicenseSaved       ID = "msg.license_saved"
+	MsgLicenseMigrated    ID = "msg.license_migrated"
+	MsgStartTimeFallback  ID = "msg.start_time_fallback"
+	MsgPolicyOverridden   ID = "msg.policy_overridden"
+	MsgLedgerRollback     ID = "msg.ledger_rollback"
+	MsgBatchDone          ID = "msg.batch_done"
+	MsgBatchFailed        ID = "msg.batch_failed"
+	MsgReminderSent       ID = "msg.reminder_sent"
+	MsgReminderSkipped    ID = "msg.reminder_skipped"
+	MsgReminderLicenses   ID = "msg.reminder_licenses"
+	MsgReminderRecipients ID = "msg.reminder_recipients"
+	MsgReminderResult     ID = "msg.reminder_result"
+	MsgReminderDelivered  ID = "msg.reminder_delivered"
+	MsgResellerIssued     ID = "msg.reseller_issued"
+	MsgAPIStarted         ID = "msg.api_started"
+	MsgAPIFailed          ID = "msg.api_failed"
+	MsgAgentStarted       ID = "msg.agent_started"
+)
+
+// 错误码
+const (
+	CodeNoMotherBoardID     ID = "error.no_mother_board_id"
+	CodeTamperedContact     ID = "error.tampered_contact"
+	CodeMotherBoardMismatch ID = "error.mother_board_mismatch"
+	CodeBadTime             ID = "error.bad_time"
+	CodeNotYetValid         ID = "error.not_yet_valid"
+	CodeExpired             ID = "error.expired"
+	CodeNodeLimit           ID = "error.node_limit"
+	CodeStateMismatch       ID = "error.state_mismatch"
+	CodeStateMissing        ID = "error.state_missing"
+	CodeClockRollback       ID = "error.clock_rollback"
+	CodeCheckFailed         ID = "error.check_failed"
+	CodeNoSource            ID = "error.no_source"
+	CodeNodeInfoExpired     ID = "error.node_info_expired"
+	CodeBadNodeInfo         ID = "error.bad_node_info"
+
+	CodeTampered          ID = "error.tampered"
+	CodeUnsigned          ID = "error.unsigned"
+	CodeBadSignature      ID = "error.bad_signature"
+	CodeSuiteMismatch     ID = "error.suite_mismatch"
+	CodeUnknownVersion    ID = "error.unknown_version"
+	CodeTruncated         ID = "error.truncated"
+	CodeInvalidCiphertext ID = "error.invalid_ciphertext"
+	CodeBadPadding        ID = "error.bad_padding"
+	CodeAuthFailed        ID = "error.auth_failed"
+
+	CodeUnknownKey     ID = "error.unknown_key"
+	CodeKeyExpired     ID = "error.key_expired"
+	CodeBadCert        ID = "error.bad_cert"
+	CodeCertExpired    ID = "error.cert_expired"
+	CodeExceedsLimit   ID = "error.exceeds_limit"
+	CodeCertParent     ID = "error.cert_parent"
+	CodeLimitNodes     ID = "error.limit_nodes"
+	CodeLimitFeature   ID = "error.limit_feature"
+	CodeLimitPermanent ID = "error.limit_permanent"
+	CodeLimitDays      ID = "error.limit_days"
+	CodeLimitNotAfter  ID = "error.limit_not_after"
+	CodeLimitIssuedAt  ID = "error.limit_issued_at"
+	CodeCertNodes      ID = "error.cert_nodes"
+	CodeCertDays       ID = "error.cert_days"
+	CodeCertPermanent  ID = "error.cert_permanent"
+	CodeCertFeature    ID = "error.cert_feature"
+	CodeCertValidity   ID = "error.cert_validity"
+
+	CodeWrongPassphrase    ID = "error.wrong_passphrase"
+	CodeEmptyPassphrase    ID = "error.empty_passphrase"
+	CodePassphraseMismatch ID = "error.passphrase_mismatch"
+	CodeKeyRingExists      ID = "error.key_ring_exists"
+	CodeNoActiveKey        ID = "error.no_active_key"
+	CodeNoSigner           ID = "error.no_signer"
+	CodeBundleKey          ID = "error.bundle_key"
+	CodeBundleVersion      ID = "error.bundle_version"
+	CodePolicyViolation    ID = "error.policy_violation"
+	CodePolicyProduct      ID = "error.policy_product"
+	CodePolicyEdition      ID = "error.policy_edition"
+	CodePolicyFeature      ID = "error.policy_feature"
+	CodePolicyMinNodes     ID = "error.policy_min_nodes"
+	CodePolicyMaxNodes     ID = "error.policy_max_nodes"
+	CodePolicyMaxDays      ID = "error.policy_max_days"
+	CodePolicyPermanent    ID = "error.policy_permanent"
+	CodeNoEndTime          ID = "error.no_end_time"
+	CodeEndBeforeStart     ID = "error.end_before_start"
+	CodeNoLedger           ID = "error.no_ledger"
+	CodeUnknownPlan        ID = "error.unknown_plan"
+	CodePlanCustomerTag    ID = "error.plan_customer_tag"
+	CodePlanNoName         ID = "error.plan_no_name"
+	CodePlanDuplicate      ID = "error.plan_duplicate"
+	CodeManifestColumn     ID = "error.manifest_column"
+	CodeManifestNodeInfo   ID = "error.manifest_node_info"
+	CodeManifestOutput     ID = "error.manifest_output"
+	CodeManifestReserved   ID = "error.manifest_reserved"
+	CodeManifestDuplicate  ID = "error.manifest_duplicate"
+	CodeOutputNotDir       ID = "error.output_not_dir"
+	CodeOutputSymlink      ID = "error.output_symlink"
+	CodeNoInventory        ID = "error.no_inventory"
+	CodeLicenseNotFound    ID = "error.license_not_found"
+	CodeLicenseRevoked     ID = "error.license_revoked"
+	CodeUnauthorized       ID = "error.unauthorized"
+	CodeBadRequest         ID = "error.bad_request"
+	CodeProductMismatch    ID = "error.product_mismatch"
+	CodeUnknownProduct     ID = "error.unknown_product"
+	CodeProductSigner      ID = "error.product_signer"
+	CodeNodeInfoProduct    ID = "error.node_info_product"
+	CodeNoCustomerRegistry ID = "error.no_customer_registry"
+	CodeCustomerNotFound   ID = "error.customer_not_found"
+	CodeCustomerExists     ID = "error.customer_exists"
+	CodeMigrateUnknown     ID = "error.migrate_unknown"
+	CodeCustomerID         ID = "error.customer_id"
+	CodeContactName        ID = "error.contact_name"
+	CodeMissingCommand     ID = "error.missing_command"
+	CodeUnknownCommand     ID = "error.unknown_command"
+	CodeUsage              ID = "error.usage"
+	CodeReportFormat       ID = "error.report_format"
+	CodeReminderTemplate   ID = "error.reminder_template"
+	CodeOutputFormat       ID = "error.output_format"
+	CodeEmptyLicense       ID = "error.empty_license"
+	CodeEnvNotSet          ID = "error.env_not_set"
+	CodeNoLicenseFile      ID = "error.no_license_file"
+	CodeNoAuditPath        ID = "error.no_audit_path"
+	CodeNoPublicKey        ID = "error.no_public_key"
+	CodeNoMailer           ID = "error.no_mailer"
+	CodeSignerSuite        ID = "error.signer_suite"
+	CodeUnknownSuite       ID = "error.unknown_suite"
+	CodeSuiteForAlg        ID = "error.suite_for_alg"
+	CodeSuiteForKey        ID = "error.suite_for_key"
+	CodeLegacyEncrypt      ID = "error.legacy_encrypt"
+	CodeNoEncrypter        ID = "error.no_encrypter"
+	CodePublicKeyType      ID = "error.public_key_type"
+	CodePrivateKeyType     ID = "error.private_key_type"
+	CodeSigningKeyType     ID = "error.signing_key_type"
+	CodeBadEd25519Key      ID = "error.bad_ed25519_key"
+	CodeNoSigningKey       ID = "error.no_signing_key"
+	CodeNoSignFunc         ID = "error.no_sign_func"
+	CodeAgentOp            ID = "error.agent_op"
+	CodeNotKeyFile         ID = "error.not_key_file"
+	CodeBadPrivateKeyFile  ID = "error.bad_private_key_file"
+	CodeBadPublicKeyFile   ID = "error.bad_public_key_file"
+	CodeNoKDF              ID = "error.no_kdf"
+	CodeUnknownKDF         ID = "error.unknown_kdf"
+	CodeBadKDFSalt         ID = "error.bad_kdf_salt"
+	CodeKDFParam           ID = "error.kdf_param"
+	CodeEmptyChain         ID = "error.empty_chain"
+	CodeChainNoTrust       ID = "error.chain_no_trust"
+	CodeChainSigner        ID = "error.chain_signer"
+	CodeNoTrustedKey       ID = "error.no_trusted_key"
+	CodeNoCurrentKey       ID = "error.no_current_key"
+	CodeKeyIDTooLong       ID = "error.key_id_too_long"
+	CodeSignatureTooLong   ID = "error.signature_too_long"
+	CodeTrailingData       ID = "error.trailing_data"
+	CodeNotAnchor          ID = "error.not_anchor"
+	CodeAnchorNoSeed       ID = "error.anchor_no_seed"
+	CodeAuditVersion       ID = "error.audit_version"
+)
+
+var catalogue = map[Locale]map[ID]string{
+	ZH: {
+		PromptSelectNetCard:     "请选择需要授权的网卡：",
+		PromptNodeInfoSavePath:  "请输入node.info文件保存路径",
+		PromptNodeInfoPath:      "请输入node.info文件路径",
+		PromptLicPath:           "请输入license.lic文件路径",
+		PromptLicSavePath:       "请输入license.lic文件保存路径",
+		PromptAllowNodes:        "请输入允许接入的最大节点数",
+		PromptPermanent:         "选择是否永久授权",
+		PromptEndTime:           "设置过期时间（未带时区时按客户时区解析）",
+		PromptCustomerTag:       "设置客户标记",
+		PromptSetPassphrase:     "请设置签发私钥口令",
+		PromptPassphrase:        "请输入签发私钥口令",
+		PromptCurrentPassphrase: "请输入当前口令",
+		PromptNewPassphrase:     "请输入新口令",
+		PromptConfirmPassphrase: "请再次输入口令",
+		PromptProduct:           "请选择产品",
+		PromptEdition:           "请选择版本",
+		PromptOverride:          "违反签发策略，请输入放行理由（留空取消签发）",
+		PromptPlan:              "请选择授权方案",
+		PromptCustomer:          "选择客户",
+
+		MsgFlagNodeInfoPath:   "请指定node.info文件路径",
+		MsgLicenseInfo:        "###############授权信息###############",
+		MsgInvalidNumber:      "输入格式异常，请输入数字类型数据！",
+		MsgInvalidTime:        "输入时间格式不正确，请重新输入！",
+		MsgClockTampered:      "请勿随意修改系统时间，否则会影响License授权！",
+		MsgCheckFailed:        "License校验未通过",
+		MsgCheckFailedExit:    "License校验未通过，程序退出",
+		MsgSaveStateFailed:    "保存License运行状态失败",
+		MsgStartTime:          "开始时间: 本地 %s / 客户(%s) %s",
+		MsgEndTime:            "到期时间: 本地 %s / 客户(%s) %s",
+		MsgPolicyViolation:    "违反签发策略: %s",
+		MsgCustomPlan:         "自定义",
+		MsgCustomerTagPattern: "客户标记需符合格式: %s",
+		MsgNoCustomer:         "不关联客户",
+		MsgReportTitle:        "%d天内到期的License",
+		MsgReportGenerated:    "生成时间: %s",
+		MsgReportSerial:       "序列号",
+		MsgReportCustomer:     "客户",
+		MsgReportProduct:      "产品",
+		MsgReportPlan:         "方案",
+		MsgReportNodes:        "节点数",
+		MsgReportEndTime:      "到期时间",
+		MsgReportDaysLeft:     "剩余天数",
+		MsgReportEmpty:        "没有即将到期的License",
+		MsgCustomerSaved:      "客户已保存: %s",
+		MsgCustomerID:         "ID",
+		MsgCustomerName:       "名称",
+		MsgCustomerContract:   "合同编号",
+		MsgCustomerContacts:   "联系人",
+		MsgCustomerNotes:      "备注",
+		MsgCustomerNextExpiry: "最近到期",
+		MsgLicenseStatus:      "状态",
+		MsgStatusActive:       "有效",
+		MsgStatusRevoked:      "已吊销",
+		MsgStatusExpired:      "已到期",
+		MsgStatusPermanent:    "永久",
+		MsgLicenseTotal:       "共%d个License，授权节点总数%d",
+		MsgInspectFile:        "文件",
+		MsgInspectFields:      "字段",
+		MsgInspectTimes:       "时间",
+		MsgInspectHardware:    "硬件绑定",
+		MsgInspectChecks:      "校验结果",
+		MsgInspectChain:       "证书链",
+		MsgInspectPass:        "通过",
+		MsgInspectFail:        "未通过",
+		MsgInspectWarn:        "警告",
+		MsgInspectSkip:        "未校验",
+		MsgInspectEnvelope:    "封装版本%d，算法套件%s",
+		MsgInspectLegacy:      "封装版本%d，算法套件%s，旧格式建议迁移",
+		MsgInspectIntegrity:   "校验码一致",
+		MsgInspectUnsigned:    "文件未签名",
+		MsgInspectNoTrust:     "未提供信任列表",
+		MsgInspectTampered:    "完整性校验未通过",
+		MsgInspectKeyTrusted:  "密钥%s在信任列表中",
+		MsgInspectResellerKey: "经销商%[1]s的密钥%[2]s",
+		MsgInspectNoChain:     "厂商直接签发",
+		MsgInspectChainOK:     "证书链有效，共%d级",
+		MsgInspectPermanent:   "永久授权",
+		MsgInspectRemaining:   "有效，剩余%d天",
+		MsgInspectExpired:     "已于%s到期",
+		MsgInspectNotYet:      "尚未生效，开始时间%s",
+		MsgInspectNodeInfo:    "node.info不含授权期限",
+		MsgDiagLicenseError:   "客户端未能读取License: %s",
+		MsgDiagLicenseOK:      "License读取正常",
+		MsgDiagBoardOK:        "主板ID与License一致",
+		MsgDiagBoardMissing:   "客户端未能获取主板ID，请确认dmidecode/wmic可用且具有root或管理员权限",
+		MsgDiagBoardCollected: "当前主板ID与License不一致，但License中的主板ID仍出现在%s中：硬件未更换，主板ID的采集结果发生了变化（如BIOS更新后序列号变为有效值或被清空）",
+		MsgDiagBoardReplaced:  "全部指纹来源中都没有License绑定的主板ID：License可能属于其它机器，或主板/虚拟机已更换",
+		MsgDiagMACOK:          "授权网卡存在",
+		MsgDiagMACMissing:     "授权网卡不在当前网卡列表中，网卡可能已更换或MAC地址被修改",
+		MsgDiagZoneSame:       "时区与生成node.info时一致",
+		MsgDiagZoneUTC:        "时区与生成node.info时不同，License使用UTC时间，有效期不受影响",
+		MsgDiagZoneLegacy:     "时区与生成node.info时不同，License为不带时区的旧格式时间，有效期按License记录的时区解析，请确认该时区有效",
+		MsgDiagClockOK:        "未发现系统时间回拨",
+		MsgDiagClockBehind:    "最后一次校验时间晚于客户端当前时间，系统时间被回拨",
+		MsgDiagClockRollbacks: "客户端检测到%d次系统时间回拨",
+		MsgDiagBadValidity:    "License有效期格式错误",
+		MsgDiagNotYetValid:    "客户端时间早于License开始时间，请检查系统时间或时区",
+		MsgDiagExpired:        "License已过期",
+		MsgDiagValid:          "在有效期内",
+		MsgDiagChecksOK:       "最近的校验均已通过",
+		MsgDiagChecksFailed:   "最近未通过的校验项: %s",
+		MsgAuditRestarted:     "审计日志在此处重新开始，此前的审计链状态丢失",
+		MsgAuditNoAnchor:      "记录之前没有签发方可验证的锚点",
+		MsgAuditDecrypt:       "记录无法解密，内容被修改或替换",
+		MsgAuditHash:          "记录内容与摘要不一致",
+		MsgAuditGap:           "缺少序号%d至%d的记录",
+		MsgAuditOrder:         "记录序号为%d，应为%d：记录重复或顺序被调整",
+		MsgAuditChain:         "与上一条记录的链接不一致",
+		MsgAuditClock:         "记录时间%s早于上一条%s",
+		MsgAuditTruncated:     "审计日志不以导出记录结尾，末尾的记录可能被删除",
+		MsgAuditStaleExport:   "最后一条导出记录早于日志包生成时间",
+		MsgAuditReplaced:      "%s 已登记始于%s的审计日志%s，当前日志为重新生成",
+		MsgCallFailed:         "%s失败",
+		MsgLicenseLoaded:      "License已加载",
+		MsgLicenseReloaded:    "License已热加载",
+		MsgLicenseRejected:    "License校验未通过",
+		MsgAuditWriteFailed:   "写入审计日志失败",
+		MsgAuditChainReset:    "读取审计链状态失败，审计日志将重新开始",
+		MsgAuditProblems:      "审计日志校验发现问题",
+		MsgKeyGenerated:       "签发密钥已生成",
+		MsgKeyRotated:         "签发密钥已轮换",
+		MsgLicenseIssued:      "License已签发",
+		MsgLicenseRevoked:     "License已吊销",
+		MsgLicenseSaved:       "License已保存",
+		MsgLicenseMigrated:    "License已迁移",
+		MsgStartTimeFallback:  "解析开始时间字段异常，使用当前时间",
+		MsgPolicyOverridden:   "违反签发策略，已放行",
+		MsgLedgerRollback:     "撤回签发记录失败",
+		MsgBatchDone:          "批量签发完成",
+		MsgBatchFailed:        "批量签发失败",
+		MsgReminderSent:       "到期提醒邮件已发送",
+		MsgReminderSkipped:    "到期提醒邮件未发送",
+		MsgReminderLicenses:   "License",
+		MsgReminderRecipients: "收件人",
+		MsgReminderResult:     "结果",
+		MsgReminderDelivered:  "已发送",
+		MsgResellerIssued:     "经销商证书已签发",
+		MsgAPIStarted:         "签发管理接口已启动",
+		MsgAPIFailed:          "签发管理接口请求失败",
+		MsgAgentStarted:       "签名代理已启动",
+
+		CodeNoMotherBoardID:     "未获取到主板ID",
+		CodeTamperedContact:     "数据疑似被篡改，请联系:%s！",
+		CodeMotherBoardMismatch: "主板ID比对异常，请检查是否使用了正确的license文件",
+		CodeBadTime:             "解析时间异常，疑似数据被篡改！",
+		CodeNotYetValid:         "证书不在有效期！",
+		CodeExpired:             "证书已过期，请联系销售人员重新获取授权！",
+		CodeNodeLimit:           "超出允许的节点范围，请联系产品供应商扩容许可",
+		CodeStateMismatch:       "运行状态文件与License不匹配",
+		CodeStateMissing:        "运行状态文件缺失，而License此前已校验通过，请恢复状态文件或联系签发方: %s",
+		CodeClockRollback:       "检测到系统时间被修改",
+		CodeCheckFailed:         "License校验未通过",
+		CodeNoSource:            "未配置License来源",
+		CodeNodeInfoExpired:     "node.info文件已超出48小时有效期！",
+		CodeBadNodeInfo:         "node.info无法解析: %v",
+
+		CodeTampered:          "数据校验失败",
+		CodeUnsigned:          "License未签名",
+		CodeBadSignature:      "License签名验证失败",
+		CodeSuiteMismatch:     "License记录的算法套件与文件头不一致",
+		CodeUnknownVersion:    "不支持的License文件格式版本",
+		CodeTruncated:         "License文件不完整",
+		CodeInvalidCiphertext: "密文格式错误",
+		CodeBadPadding:        "密文填充校验失败",
+		CodeAuthFailed:        "密文认证失败，数据被篡改",
+
+		CodeUnknownKey:     "License签发密钥不在信任列表中",
+		CodeKeyExpired:     "License签发时间不在签发密钥有效期内",
+		CodeBadCert:        "经销商证书签名验证失败",
+		CodeCertExpired:    "License签发时间不在经销商证书有效期内",
+		CodeExceedsLimit:   "License超出经销商授权范围",
+		CodeCertParent:     "%s 的上级证书不匹配",
+		CodeLimitNodes:     "节点数%d超出上限%d",
+		CodeLimitFeature:   "未授权的功能%s",
+		CodeLimitPermanent: "不允许签发永久授权",
+		CodeLimitDays:      "授权时长超出%d天",
+		CodeLimitNotAfter:  "到期时间%s晚于经销商证书失效时间%s",
+		CodeLimitIssuedAt:  "签发时间%s不在License有效期%s至%s内",
+		CodeCertNodes:      "证书%s节点上限超出上级证书",
+		CodeCertDays:       "证书%s授权时长超出上级证书",
+		CodeCertPermanent:  "证书%s不允许永久授权",
+		CodeCertFeature:    "证书%s包含上级未授权的功能%s",
+		CodeCertValidity:   "证书%s的有效期超出上级证书",
+
+		CodeWrongPassphrase:    "私钥口令错误或私钥文件已损坏",
+		CodeEmptyPassphrase:    "口令不能为空",
+		CodePassphraseMismatch: "两次输入的口令不一致",
+		CodeKeyRingExists:      "密钥环已存在，请使用RotateKey轮换密钥",
+		CodeNoActiveKey:        "密钥环中没有可用的签发密钥",
+		CodeNoSigner:           "未配置签发密钥",
+		CodeBundleKey:          "密钥环中没有诊断包使用的密钥: %s",
+		CodeBundleVersion:      "不支持的诊断包版本: %d",
+		CodePolicyViolation:    "违反签发策略，需要填写放行理由",
+		CodePolicyProduct:      "不允许签发产品: %s",
+		CodePolicyEdition:      "未定义的版本: %s",
+		CodePolicyFeature:      "版本%s不包含功能特性: %s",
+		CodePolicyMinNodes:     "节点数不能少于%d",
+		CodePolicyMaxNodes:     "节点数不能超过%d",
+		CodePolicyMaxDays:      "授权天数不能超过%d",
+		CodePolicyPermanent:    "%s无权签发永久授权",
+		CodeNoEndTime:          "未指定到期时间",
+		CodeEndBeforeStart:     "到期时间%s不晚于开始时间%s",
+		CodeNoLedger:           "使用放行理由签发时必须配置签发台账",
+		CodeUnknownPlan:        "未定义的授权方案: %s",
+		CodePlanCustomerTag:    "客户标记%s不符合方案%s的格式: %s",
+		CodePlanNoName:         "第%d个授权方案未填写名称",
+		CodePlanDuplicate:      "授权方案%s重复定义",
+		CodeManifestColumn:     "清单包含未知的列: %s",
+		CodeManifestNodeInfo:   "清单第%d项缺少node.info路径",
+		CodeManifestOutput:     "输出文件名必须是输出目录内的相对路径: %s",
+		CodeManifestReserved:   "输出文件名%s与签发报告重名",
+		CodeManifestDuplicate:  "清单中的输出文件名重复: %s",
+		CodeOutputNotDir:       "输出路径%s不是目录",
+		CodeOutputSymlink:      "输出路径%s是符号链接，不会被跟随",
+		CodeNoInventory:        "未配置签发记录库",
+		CodeLicenseNotFound:    "签发记录不存在: %s",
+		CodeLicenseRevoked:     "License已吊销: %s",
+		CodeUnauthorized:       "访问令牌无效",
+		CodeBadRequest:         "请求格式错误: %s",
+		CodeProductMismatch:    "License不属于当前产品，License产品为: %s",
+		CodeUnknownProduct:     "未配置的产品: %s",
+		CodeProductSigner:      "产品%s未配置签发密钥",
+		CodeNodeInfoProduct:    "node.info属于产品%s，不能签发为产品%s",
+		CodeNoCustomerRegistry: "未配置客户库",
+		CodeCustomerNotFound:   "客户不存在: %s",
+		CodeCustomerExists:     "客户已存在: %s",
+		CodeMigrateUnknown:     "待迁移的License不在签发记录中或内容与记录不一致，需操作人确认后迁移: %s",
+		CodeCustomerID:         "客户ID不能为空",
+		CodeContactName:        "联系人姓名不能为空",
+		CodeMissingCommand:     "缺少子命令: %s",
+		CodeUnknownCommand:     "未知的子命令: %s",
+		CodeUsage:              "用法: %s",
+		CodeReportFormat:       "不支持的报告格式: %s",
+		CodeReminderTemplate:   "提醒邮件模板%s必须定义subject与body",
+		CodeOutputFormat:       "不支持的输出格式: %s",
+		CodeEmptyLicense:       "License数据为空",
+		CodeEnvNotSet:          "环境变量%s未设置",
+		CodeNoLicenseFile:      "未在以下路径找到License文件: %s",
+		CodeNoAuditPath:        "未配置审计日志路径",
+		CodeNoPublicKey:        "未配置签发公钥",
+		CodeNoMailer:           "未配置邮件发送方式或发件人",
+		CodeSignerSuite:        "签名器套件%s与签发套件%s不一致",
+		CodeUnknownSuite:       "未注册的算法套件: %s",
+		CodeSuiteForAlg:        "未找到匹配的算法套件: enc=%d sig=%d",
+		CodeSuiteForKey:        "未找到匹配公钥类型%T的算法套件",
+		CodeLegacyEncrypt:      "旧版算法套件%s只能用于解密旧文件",
+		CodeNoEncrypter:        "算法套件%s不支持公钥加密",
+		CodePublicKeyType:      "公钥不是%s公钥",
+		CodePrivateKeyType:     "私钥不是%s私钥",
+		CodeSigningKeyType:     "签名密钥不是%s密钥",
+		CodeBadEd25519Key:      "无效的Ed25519公钥",
+		CodeNoSigningKey:       "签名私钥不能为空",
+		CodeNoSignFunc:         "未设置签名函数",
+		CodeAgentOp:            "未知的请求类型: %s",
+		CodeNotKeyFile:         "不是加密的签发私钥文件",
+		CodeBadPrivateKeyFile:  "私钥文件格式错误",
+		CodeBadPublicKeyFile:   "公钥文件格式错误",
+		CodeNoKDF:              "私钥文件缺少KDF参数",
+		CodeUnknownKDF:         "不支持的KDF: %s",
+		CodeBadKDFSalt:         "KDF盐值格式错误",
+		CodeKDFParam:           "KDF参数%s=%d超出允许范围",
+		CodeEmptyChain:         "证书链为空",
+		CodeChainNoTrust:       "校验经销商证书链需要配置信任列表",
+		CodeChainSigner:        "证书链与当前签发密钥不匹配",
+		CodeNoTrustedKey:       "未找到签发公钥",
+		CodeNoCurrentKey:       "信任列表中没有当前签发公钥",
+		CodeKeyIDTooLong:       "密钥ID长度超出限制",
+		CodeSignatureTooLong:   "签名长度超出限制",
+		CodeTrailingData:       "License文件末尾存在多余数据",
+		CodeNotAnchor:          "不是审计日志锚点",
+		CodeAnchorNoSeed:       "审计日志锚点缺少初始密钥",
+		CodeAuditVersion:       "不支持的审计日志包版本: %d",
+	},
+	EN: {
+		PromptSelectNetCard:     "Select the network card to license:",
+		PromptNodeInfoSavePath:  "Directory to save node.info",
+		PromptNodeInfoPath:      "Path of node.info",
+		PromptLicPath:           "Path of license.lic",
+		PromptLicSavePath:       "Directory to save license.lic",
+		PromptAllowNodes:        "Maximum number of nodes",
+		PromptPermanent:         "Permanent license?",
+		PromptEndTime:           "Expiry time (customer time zone unless an offset is given)",
+		PromptCustomerTag:       "Customer tag",
+		PromptSetPassphrase:     "Set the issuing key passphrase",
+		PromptPassphrase:        "Issuing key passphrase",
+		PromptCurrentPassphrase: "Current passphrase",
+		PromptNewPassphrase:     "New passphrase",
+		PromptConfirmPassphrase: "Repeat passphrase",
+		PromptProduct:           "Product",
+		PromptEdition:           "Edition",
+		PromptOverride:          "Policy violated, enter an override reason (empty to cancel)",
+		PromptPlan:              "License plan",
+		PromptCustomer:          "Select customer",
+
+		MsgFlagNodeInfoPath:   "path of node.info",
+		MsgLicenseInfo:        "############### License ###############",
+		MsgInvalidNumber:      "Invalid input, please enter a number.",
+		MsgInvalidTime:        "Invalid time format, please try again.",
+		MsgClockTampered:      "The system clock was changed; do not modify the system time or the license will be revoked.",
+		MsgCheckFailed:        "License check failed",
+		MsgCheckFailedExit:    "License check failed, exiting",
+		MsgSaveStateFailed:    "Failed to save the license runtime state",
+		MsgStartTime:          "Start:  local %s / customer (%s) %s",
+		MsgEndTime:            "Expiry: local %s / customer (%s) %s",
+		MsgPolicyViolation:    "Policy violation: %s",
+		MsgCustomPlan:         "Custom",
+		MsgCustomerTagPattern: "The customer tag must match: %s",
+		MsgNoCustomer:         "No customer",
+		MsgReportTitle:        "Licenses expiring within %d days",
+		MsgReportGenerated:    "Generated at %s",
+		MsgReportSerial:       "Serial",
+		MsgReportCustomer:     "Customer",
+		MsgReportProduct:      "Product",
+		MsgReportPlan:         "Plan",
+		MsgReportNodes:        "Nodes",
+		MsgReportEndTime:      "Expires",
+		MsgReportDaysLeft:     "Days left",
+		MsgReportEmpty:        "No licenses are about to expire",
+		MsgCustomerSaved:      "Customer saved: %s",
+		MsgCustomerID:         "ID",
+		MsgCustomerName:       "Name",
+		MsgCustomerContract:   "Contract",
+		MsgCustomerContacts:   "Contacts",
+		MsgCustomerNotes:      "Notes",
+		MsgCustomerNextExpiry: "Next expiry",
+		MsgLicenseStatus:      "Status",
+		MsgStatusActive:       "active",
+		MsgStatusRevoked:      "revoked",
+		MsgStatusExpired:      "expired",
+		MsgStatusPermanent:    "permanent",
+		MsgLicenseTotal:       "%d licenses, %d nodes in total",
+		MsgInspectFile:        "File",
+		MsgInspectFields:      "Fields",
+		MsgInspectTimes:       "Times",
+		MsgInspectHardware:    "Hardware binding",
+		MsgInspectChecks:      "Verification",
+		MsgInspectChain:       "Reseller chain",
+		MsgInspectPass:        "pass",
+		MsgInspectFail:        "FAIL",
+		MsgInspectWarn:        "warn",
+		MsgInspectSkip:        "skipped",
+		MsgInspectEnvelope:    "envelope v%d, suite %s",
+		MsgInspectLegacy:      "envelope v%d, suite %s, legacy format, migration recommended",
+		MsgInspectIntegrity:   "check code matches",
+		MsgInspectUnsigned:    "file is not signed",
+		MsgInspectNoTrust:     "no trust store provided",
+		MsgInspectTampered:    "integrity check failed",
+		MsgInspectKeyTrusted:  "key %s is in the trust store",
+		MsgInspectResellerKey: "key %[2]s of reseller %[1]s",
+		MsgInspectNoChain:     "issued directly by the vendor",
+		MsgInspectChainOK:     "chain valid, %d level(s)",
+		MsgInspectPermanent:   "permanent license",
+		MsgInspectRemaining:   "valid, %d days left",
+		MsgInspectExpired:     "expired at %s",
+		MsgInspectNotYet:      "not valid before %s",
+		MsgInspectNodeInfo:    "node.info carries no license term",
+		MsgDiagLicenseError:   "The client could not read the license: %s",
+		MsgDiagLicenseOK:      "The license was read successfully",
+		MsgDiagBoardOK:        "The motherboard ID matches the license",
+		MsgDiagBoardMissing:   "The client could not read the motherboard ID; make sure dmidecode/wmic is available and runs with root or administrator rights",
+		MsgDiagBoardCollected: "The motherboard ID differs from the license, but the licensed ID still appears in %s: the hardware is unchanged and only the collected ID changed (for example a BIOS update filled in or cleared the serial number)",
+		MsgDiagBoardReplaced:  "No fingerprint source contains the licensed motherboard ID: the license may belong to another machine, or the motherboard/VM was replaced",
+		MsgDiagMACOK:          "The licensed network card is present",
+		MsgDiagMACMissing:     "The licensed network card is not in the current list; the card may have been replaced or its MAC address changed",
+		MsgDiagZoneSame:       "The time zone matches the one used when node.info was generated",
+		MsgDiagZoneUTC:        "The time zone differs from the one used for node.info; the license uses UTC times, so validity is not affected",
+		MsgDiagZoneLegacy:     "The time zone differs from the one used for node.info; the license uses legacy times without a zone, which are parsed in the recorded time zone — make sure that zone is valid",
+		MsgDiagClockOK:        "No system clock rollback found",
+		MsgDiagClockBehind:    "The last check time is later than the client's current time: the system clock was turned back",
+		MsgDiagClockRollbacks: "The client detected %d system clock rollbacks",
+		MsgDiagBadValidity:    "The license validity period is malformed",
+		MsgDiagNotYetValid:    "The client time is before the license start; check the system time or time zone",
+		MsgDiagExpired:        "The license has expired",
+		MsgDiagValid:          "Within the validity period",
+		MsgDiagChecksOK:       "All recent checks passed",
+		MsgDiagChecksFailed:   "Recently failed checks: %s",
+		MsgAuditRestarted:     "The audit log restarts here; the earlier chain state was lost",
+		MsgAuditNoAnchor:      "No anchor verifiable by the vendor precedes this record",
+		MsgAuditDecrypt:       "The record cannot be decrypted; it was modified or replaced",
+		MsgAuditHash:          "The record content does not match its digest",
+		MsgAuditGap:           "Records %d to %d are missing",
+		MsgAuditOrder:         "The record has sequence %d but %d was expected: records were duplicated or reordered",
+		MsgAuditChain:         "The link to the previous record does not match",
+		MsgAuditClock:         "The record time %s is before the previous record at %s",
+		MsgAuditTruncated:     "The audit log does not end with an export record; trailing records may have been deleted",
+		MsgAuditStaleExport:   "The last export record is older than the bundle",
+		MsgAuditReplaced:      "%s has a registered audit log %[3]s starting %[2]s; this log was regenerated",
+		MsgCallFailed:         "%s failed",
+		MsgLicenseLoaded:      "License loaded",
+		MsgLicenseReloaded:    "License reloaded",
+		MsgLicenseRejected:    "License check failed",
+		MsgAuditWriteFailed:   "Failed to write the audit log",
+		MsgAuditChainReset:    "Failed to read the audit chain state; the audit log will restart",
+		MsgAuditProblems:      "Audit log verification found problems",
+		MsgKeyGenerated:       "Signing key generated",
+		MsgKeyRotated:         "Signing key rotated",
+		MsgLicenseIssued:      "License issued",
+		MsgLicenseRevoked:     "License revoked",
+		MsgLicenseSaved:       "License saved",
+		MsgLicenseMigrated:    "License migrated",
+		MsgStartTimeFallback:  "Failed to parse the start time; using the current time",
+		MsgPolicyOverridden:   "Issuance policy violated and overridden",
+		MsgLedgerRollback:     "Failed to roll back the issuance record",
+		MsgBatchDone:          "Batch issuance finished",
+		MsgBatchFailed:        "Batch issuance failed",
+		MsgReminderSent:       "Expiry reminder sent",
+		MsgReminderSkipped:    "Expiry reminder not sent",
+		MsgReminderLicenses:   "Licenses",
+		MsgReminderRecipients: "Recipients",
+		MsgReminderResult:     "Result",
+		MsgReminderDelivered:  "sent",
+		MsgResellerIssued:     "Reseller certificate issued",
+		MsgAPIStarted:         "Issuance API started",
+		MsgAPIFailed:          "Issuance API request failed",
+		MsgAgentStarted:       "Signing agent started",
+
+		CodeNoMotherBoardID:     "unable to read the motherboard ID",
+		CodeTamperedContact:     "the data appears to have been tampered with, please contact %s",
+		CodeMotherBoardMismatch: "motherboard ID mismatch, check that the correct license file is used",
+		CodeBadTime:             "invalid time in license, the data may have been tampered with",
+		CodeNotYetValid:         "the license is not yet valid",
+		CodeExpired:             "the license has expired, please contact sales to renew it",
+		CodeNodeLimit:           "node limit reached, please contact the vendor to extend the license",
+		CodeStateMismatch:       "the runtime state file does not match the license",
+		CodeStateMissing:        "the runtime state file is missing although the license has been checked before; restore it or contact the vendor: %s",
+		CodeClockRollback:       "the system clock was turned back",
+		CodeCheckFailed:         "license check failed",
+		CodeNoSource:            "no license source configured",
+		CodeNodeInfoExpired:     "node.info has passed its 48-hour validity",
+		CodeBadNodeInfo:         "node.info cannot be parsed: %v",
+
+		CodeTampered:          "data integrity check failed",
+		CodeUnsigned:          "the license is not signed",
+		CodeBadSignature:      "license signature verification failed",
+		CodeSuiteMismatch:     "the crypto suite recorded in the license does not match the file header",
+		CodeUnknownVersion:    "unsupported license file format version",
+		CodeTruncated:         "the license file is truncated",
+		CodeInvalidCiphertext: "malformed ciphertext",
+		CodeBadPadding:        "invalid ciphertext padding",
+		CodeAuthFailed:        "ciphertext authentication failed, the data has been tampered with",
+
+		CodeUnknownKey:     "the license issuing key is not trusted",
+		CodeKeyExpired:     "the license was issued outside the issuing key's validity period",
+		CodeBadCert:        "reseller certificate signature verification failed",
+		CodeCertExpired:    "the license was issued outside the reseller certificate's validity period",
+		CodeExceedsLimit:   "the license exceeds the reseller's limits",
+		CodeCertParent:     "issuer certificate of %s does not match",
+		CodeLimitNodes:     "%d nodes exceeds the limit of %d",
+		CodeLimitFeature:   "feature %s is not allowed",
+		CodeLimitPermanent: "permanent licenses are not allowed",
+		CodeLimitDays:      "duration exceeds %d days",
+		CodeLimitNotAfter:  "the end time %s is after the reseller certificate expires at %s",
+		CodeLimitIssuedAt:  "the issue time %s is outside the license validity %s to %s",
+		CodeCertNodes:      "certificate %s node limit exceeds its issuer's",
+		CodeCertDays:       "certificate %s duration limit exceeds its issuer's",
+		CodeCertPermanent:  "certificate %s may not allow permanent licenses",
+		CodeCertFeature:    "certificate %s includes feature %s not allowed by its issuer",
+		CodeCertValidity:   "certificate %s is valid outside its issuer's validity period",
+
+		CodeWrongPassphrase:    "wrong passphrase or corrupted key file",
+		CodeEmptyPassphrase:    "the passphrase must not be empty",
+		CodePassphraseMismatch: "the passphrases do not match",
+		CodeKeyRingExists:      "the key ring already exists, use RotateKey to rotate keys",
+		CodeNoActiveKey:        "no active issuing key in the key ring",
+		CodeNoSigner:           "no issuing key configured",
+		CodeBundleKey:          "the key ring has no key for the support bundle: %s",
+		CodeBundleVersion:      "unsupported support bundle version: %d",
+		CodePolicyViolation:    "the issuance violates the policy and requires an override reason",
+		CodePolicyProduct:      "product %s may not be issued",
+		CodePolicyEdition:      "edition %s is not defined",
+		CodePolicyFeature:      "edition %s does not include feature %s",
+		CodePolicyMinNodes:     "the node count must be at least %d",
+		CodePolicyMaxNodes:     "the node count must not exceed %d",
+		CodePolicyMaxDays:      "the license must not exceed %d days",
+		CodePolicyPermanent:    "%s may not issue permanent licenses",
+		CodeNoEndTime:          "no expiry time given",
+		CodeEndBeforeStart:     "the expiry time %s is not after the start time %s",
+		CodeNoLedger:           "an issuance ledger is required to issue with an override",
+		CodeUnknownPlan:        "license plan %s is not defined",
+		CodePlanCustomerTag:    "customer tag %s does not match the pattern of plan %s: %s",
+		CodePlanNoName:         "license plan #%d has no name",
+		CodePlanDuplicate:      "license plan %s is defined more than once",
+		CodeManifestColumn:     "the manifest has an unknown column: %s",
+		CodeManifestNodeInfo:   "manifest item %d has no node.info path",
+		CodeManifestOutput:     "the output file name must be a relative path inside the output directory: %s",
+		CodeManifestReserved:   "the output file name %s is reserved for the batch report",
+		CodeManifestDuplicate:  "the manifest repeats the output file name %s",
+		CodeOutputNotDir:       "the output path %s is not a directory",
+		CodeOutputSymlink:      "the output path %s is a symbolic link and will not be followed",
+		CodeNoInventory:        "no license inventory configured",
+		CodeLicenseNotFound:    "license %s not found",
+		CodeLicenseRevoked:     "license %s has been revoked",
+		CodeUnauthorized:       "invalid access token",
+		CodeBadRequest:         "malformed request: %s",
+		CodeProductMismatch:    "the license belongs to another product: %s",
+		CodeUnknownProduct:     "product %s is not configured",
+		CodeProductSigner:      "product %s has no signing key configured",
+		CodeNodeInfoProduct:    "node.info belongs to product %s and cannot be issued for product %s",
+		CodeNoCustomerRegistry: "no customer registry configured",
+		CodeCustomerNotFound:   "customer %s not found",
+		CodeCustomerExists:     "customer %s already exists",
+		CodeMigrateUnknown:     "license %s to migrate is not in the issuance records or differs from them; operator confirmation required",
+		CodeCustomerID:         "customer ID must not be empty",
+		CodeContactName:        "the contact name is required",
+		CodeMissingCommand:     "missing subcommand: %s",
+		CodeUnknownCommand:     "unknown subcommand: %s",
+		CodeUsage:              "usage: %s",
+		CodeReportFormat:       "unsupported report format: %s",
+		CodeReminderTemplate:   "reminder template %s must define subject and body",
+		CodeOutputFormat:       "unsupported output format: %s",
+		CodeEmptyLicense:       "the license data is empty",
+		CodeEnvNotSet:          "the environment variable %s is not set",
+		CodeNoLicenseFile:      "no license file found in: %s",
+		CodeNoAuditPath:        "no audit log path is configured",
+		CodeNoPublicKey:        "no vendor public key is configured",
+		CodeNoMailer:           "no mailer or sender is configured",
+		CodeSignerSuite:        "the signer suite %s does not match the issuing suite %s",
+		CodeUnknownSuite:       "unregistered crypto suite: %s",
+		CodeSuiteForAlg:        "no crypto suite matches enc=%d sig=%d",
+		CodeSuiteForKey:        "no crypto suite matches the public key type %T",
+		CodeLegacyEncrypt:      "the legacy crypto suite %s can only decrypt old files",
+		CodeNoEncrypter:        "the crypto suite %s does not support public-key encryption",
+		CodePublicKeyType:      "the public key is not a %s key",
+		CodePrivateKeyType:     "the private key is not a %s key",
+		CodeSigningKeyType:     "the signing key is not a %s key",
+		CodeBadEd25519Key:      "invalid Ed25519 public key",
+		CodeNoSigningKey:       "the signing key is required",
+		CodeNoSignFunc:         "no signing function is set",
+		CodeAgentOp:            "unknown request type: %s",
+		CodeNotKeyFile:         "not an encrypted issuer key file",
+		CodeBadPrivateKeyFile:  "malformed private key file",
+		CodeBadPublicKeyFile:   "malformed public key file",
+		CodeNoKDF:              "the key file has no KDF parameters",
+		CodeUnknownKDF:         "unsupported KDF: %s",
+		CodeBadKDFSalt:         "malformed KDF salt",
+		CodeKDFParam:           "KDF parameter %s=%d is out of range",
+		CodeEmptyChain:         "the certificate chain is empty",
+		CodeChainNoTrust:       "verifying a reseller chain requires a trust store",
+		CodeChainSigner:        "the certificate chain does not match the current signing key",
+		CodeNoTrustedKey:       "no vendor public key found",
+		CodeNoCurrentKey:       "the trust store has no current vendor key",
+		CodeKeyIDTooLong:       "the key ID is too long",
+		CodeSignatureTooLong:   "the signature is too long",
+		CodeTrailingData:       "the license file has trailing data",
+		CodeNotAnchor:          "not an audit log anchor",
+		CodeAnchorNoSeed:       "the audit log anchor has no initial key",
+		CodeAuditVersion:       "unsupported audit bundle version: %d",
+	},
+}
@@ -0,0 +1,360 @@
+package Inspect
+
+import (
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+	"os"
+	"time"
+)
+
+// 文件类型
+const (
+	KindLicense  = "license"
+	KindNodeInfo = "node_info"
+)
+
+// 校验项
+const (
+	CheckEnvelope  = "envelope"  // 文件封装版本与算法套件
+	CheckIntegrity = "integrity" // 校验码
+	CheckSignature = "signature" // 签名
+	CheckKeyID     = "key_id"    // 签发密钥是否受信任且在有效期内
+	CheckChain     = "chain"     // 经销商证书链
+	CheckValidity  = "validity"  // 授权期限
+)
+
+// 校验结果
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusWarn = "warn"
+	StatusSkip = "skip"
+)
+
+// Options 检查配置
+type Options struct {
+	Offset     int
+	Step       int
+	TrustStore *Trust.Store // 受信任的签发公钥列表，为空时不校验签名、密钥与证书链
+	Zones      []string     // 额外显示的时区，UTC、客户时区与本地时区始终显示
+	Locale     I18n.Locale  // 校验结果说明的语言，为空时按LANG检测
+}
+
+// EnvelopeInfo 文件封装信息
+type EnvelopeInfo struct {
+	Version uint8  `json:"version"`
+	Suite   string `json:"suite"`
+	Signed  bool   `json:"signed"`
+	KeyID   string `json:"key_id,omitempty"`
+	Legacy  bool   `json:"legacy"` // 旧格式或旧加密算法，建议迁移
+}
+
+// ZonedTime 某个时区中的时间
+type ZonedTime struct {
+	Zone string `json:"zone"`
+	Time string `json:"time"`
+}
+
+// TimeInfo License中的时间字段及其在各时区的显示
+type TimeInfo struct {
+	Field  string      `json:"field"`
+	Raw    string      `json:"raw"`
+	Legacy bool        `json:"legacy,omitempty"` // 不带时区的旧格式
+	Zones  []ZonedTime `json:"zones,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Hardware 硬件绑定信息
+type Hardware struct {
+	MotherBoardID  string             `json:"mother_board_id"`
+	MacAddr        string             `json:"mac_addr"`
+	ClientTimeZone string             `json:"client_time_zone"`
+	ProductID      string             `json:"product_id,omitempty"`
+	AllowNodes     int                `json:"allow_nodes"`
+	UseNodes       int                `json:"use_nodes"`
+	Nodes          []*Entity.NodeInfo `json:"nodes,omitempty"`
+}
+
+// Check 一项校验的结果
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report 检查结果
+type Report struct {
+	Source   string          `json:"source,omitempty"`
+	Kind     string          `json:"kind"`
+	Serial   string          `json:"serial,omitempty"` // License序列号，与签发台账一致
+	Envelope EnvelopeInfo    `json:"envelope"`
+	License  *Entity.License `json:"license"` // 解密后的License，不含校验码
+	Times    []TimeInfo      `json:"times"`
+	Hardware Hardware        `json:"hardware"`
+	Checks   []Check         `json:"checks"`
+}
+
+// File 检查license.lic或node.info文件
+func File(path string, opts *Options) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Inspect(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.Source = path
+	return report, nil
+}
+
+// Inspect 解密文件内容并逐项校验。只有无法解析或解密时返回错误，
+// 校验码不一致或签名无效的文件仍返回其内容，并在校验结果中标记。
+func Inspect(data []byte, opts *Options) (*Report, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Offset == 0 {
+		o.Offset = 1
+	}
+	if o.Step == 0 {
+		o.Step = 1
+	}
+	lic, env, suite, err := Utils.Decode(data, o.Offset, o.Step)
+	if err != nil {
+		return nil, err
+	}
+	var inspector = &inspector{opts: &o, locale: I18n.Or(o.Locale), lic: lic, env: env}
+	// 校验码前16位是文件的对称密钥，不输出
+	var shown = lic.Clone()
+	shown.CheckCode = ""
+	var report = &Report{
+		Kind:    KindLicense,
+		License: shown,
+		Envelope: EnvelopeInfo{
+			Version: env.Version,
+			Suite:   suite.Name(),
+			Signed:  env.SigAlg != Envelope.SigNone,
+			KeyID:   env.KeyID,
+			Legacy:  Utils.NeedsMigration(env),
+		},
+		Hardware: Hardware{
+			MotherBoardID:  lic.MotherBoardID,
+			MacAddr:        lic.MacAddr,
+			ClientTimeZone: lic.ClientTimeZone,
+			ProductID:      lic.ProductID,
+			AllowNodes:     lic.AllowNodes,
+			UseNodes:       lic.UseNodes,
+			Nodes:          lic.NodeList,
+		},
+	}
+	if !report.Envelope.Signed && lic.EndTime == "" && lic.LicenseCreateTime == "" {
+		report.Kind = KindNodeInfo
+	} else {
+		report.Serial = Logger.Serial(lic)
+	}
+	report.Times = inspector.times()
+
+	var envelope = Check{Name: CheckEnvelope, Status: StatusPass, Detail: inspector.text(I18n.MsgInspectEnvelope, env.Version, suite.Name())}
+	if report.Envelope.Legacy {
+		envelope.Status, envelope.Detail = StatusWarn, inspector.text(I18n.MsgInspectLegacy, env.Version, suite.Name())
+	}
+	var integrity = inspector.integrity(suite)
+	report.Checks = []Check{
+		envelope,
+		integrity,
+		inspector.signature(data, integrity.Status == StatusPass),
+		inspector.keyID(),
+		inspector.chain(),
+		inspector.validity(report.Kind),
+	}
+	return report, nil
+}
+
+type inspector struct {
+	opts   *Options
+	locale I18n.Locale
+	lic    *Entity.License
+	env    *Envelope.Envelope
+}
+
+func (i *inspector) text(id I18n.ID, args ...interface{}) string {
+	return I18n.T(i.locale, id, args...)
+}
+
+func (i *inspector) fail(name string, err error) Check {
+	return Check{Name: name, Status: StatusFail, Detail: I18n.Localize(err, i.locale)}
+}
+
+// zones 显示时间的时区：UTC、客户时区、本地时区与额外指定的时区，按名称去重
+func (i *inspector) zones() []*time.Location {
+	var result = make([]*time.Location, 0)
+	var seen = make(map[string]bool)
+	var names = append([]string{"UTC", i.lic.ClientTimeZone, "Local"}, i.opts.Zones...)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		var loc = Timestamp.Zone(name)
+		if seen[loc.String()] {
+			continue
+		}
+		seen[loc.String()] = true
+		result = append(result, loc)
+	}
+	return result
+}
+
+type timeField struct {
+	name  string
+	value string
+	zone  string
+}
+
+func (i *inspector) times() []TimeInfo {
+	// 开始与到期时间的旧格式按客户时区解析，签发时间的旧格式按签发端本地时间解析
+	var fields = []timeField{
+		{"start_time", i.lic.StartTime, i.lic.ClientTimeZone},
+		{"end_time", i.lic.EndTime, i.lic.ClientTimeZone},
+		{"license_create_time", i.lic.LicenseCreateTime, ""},
+	}
+	if i.lic.LastCheckTime != nil {
+		fields = append(fields, timeField{"last_check_time", Timestamp.Format(*i.lic.LastCheckTime), ""})
+	}
+	var zones = i.zones()
+	var result = make([]TimeInfo, 0, len(fields))
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		var info = TimeInfo{Field: field.name, Raw: field.value, Legacy: Timestamp.IsLegacy(field.value)}
+		t, err := Timestamp.Parse(field.value, field.zone)
+		if err != nil {
+			info.Error = I18n.Localize(fmt.Errorf("%w: %q", I18n.E(I18n.CodeBadTime), field.value), i.locale)
+			result = append(result, info)
+			continue
+		}
+		for _, loc := range zones {
+			info.Zones = append(info.Zones, ZonedTime{Zone: loc.String(), Time: t.In(loc).Format(Timestamp.Layout)})
+		}
+		result = append(result, info)
+	}
+	return result
+}
+
+func (i *inspector) integrity(suite Suite.CryptoSuite) Check {
+	err := Utils.CheckIntegrity(i.lic, suite)
+	if err != nil {
+		return i.fail(CheckIntegrity, err)
+	}
+	return Check{Name: CheckIntegrity, Status: StatusPass, Detail: i.text(I18n.MsgInspectIntegrity)}
+}
+
+// signature 按客户端的规则打开文件，结果与配置同一信任列表的客户端一致
+func (i *inspector) signature(data []byte, intact bool) Check {
+	var check = Check{Name: CheckSignature, Status: StatusSkip}
+	switch {
+	case i.env.SigAlg == Envelope.SigNone && i.opts.TrustStore == nil:
+		check.Detail = i.text(I18n.MsgInspectUnsigned)
+	case i.opts.TrustStore == nil:
+		check.Detail = i.text(I18n.MsgInspectNoTrust)
+	case !intact:
+		check.Detail = i.text(I18n.MsgInspectTampered)
+	default:
+		var opener = &Utils.Opener{Offset: i.opts.Offset, Step: i.opts.Step, TrustStore: i.opts.TrustStore}
+		_, _, err := opener.Open(data)
+		if err != nil {
+			return i.fail(CheckSignature, err)
+		}
+		check.Status = StatusPass
+	}
+	return check
+}
+
+// keyID 经销商签发时密钥ID须与证书链首证书一致，厂商签发时须在信任列表中且签发时间在密钥有效期内
+func (i *inspector) keyID() Check {
+	var check = Check{Name: CheckKeyID, Status: StatusSkip}
+	if i.env.SigAlg == Envelope.SigNone {
+		check.Detail = i.text(I18n.MsgInspectUnsigned)
+		return check
+	}
+	// 没有信任列表时无从判断密钥是否可信，经销商签发的也不例外
+	if i.opts.TrustStore == nil {
+		check.Detail = i.text(I18n.MsgInspectNoTrust)
+		return check
+	}
+	if len(i.lic.ResellerChain) > 0 {
+		var leaf = i.lic.ResellerChain[0]
+		if leaf.KeyID != i.env.KeyID {
+			return i.fail(CheckKeyID, Utils.ErrBadSignature)
+		}
+		check.Status, check.Detail = StatusPass, i.text(I18n.MsgInspectResellerKey, leaf.Reseller, leaf.KeyID)
+		return check
+	}
+	key, err := i.opts.TrustStore.Lookup(i.env.KeyID)
+	if err != nil {
+		return i.fail(CheckKeyID, err)
+	}
+	issuedAt, err := i.lic.IssuedAt()
+	if err != nil || !key.Covers(issuedAt) {
+		return i.fail(CheckKeyID, Trust.ErrKeyExpired)
+	}
+	check.Status, check.Detail = StatusPass, i.text(I18n.MsgInspectKeyTrusted, key.KeyID)
+	return check
+}
+
+func (i *inspector) chain() Check {
+	var check = Check{Name: CheckChain, Status: StatusSkip}
+	if len(i.lic.ResellerChain) == 0 {
+		check.Detail = i.text(I18n.MsgInspectNoChain)
+		return check
+	}
+	if i.opts.TrustStore == nil {
+		check.Detail = i.text(I18n.MsgInspectNoTrust)
+		return check
+	}
+	_, err := Trust.VerifyChain(i.lic.ResellerChain, i.opts.TrustStore, i.lic)
+	if err != nil {
+		return i.fail(CheckChain, err)
+	}
+	check.Status, check.Detail = StatusPass, i.text(I18n.MsgInspectChainOK, len(i.lic.ResellerChain))
+	return check
+}
+
+func (i *inspector) validity(kind string) Check {
+	var check = Check{Name: CheckValidity, Status: StatusSkip}
+	if kind == KindNodeInfo {
+		check.Detail = i.text(I18n.MsgInspectNodeInfo)
+		return check
+	}
+	if i.lic.PermanentAuth {
+		check.Status, check.Detail = StatusPass, i.text(I18n.MsgInspectPermanent)
+		return check
+	}
+	start, err := i.lic.StartAt()
+	if err != nil {
+		return i.fail(CheckValidity, err)
+	}
+	end, err := i.lic.EndAt()
+	if err != nil {
+		return i.fail(CheckValidity, err)
+	}
+	var now = time.Now()
+	switch {
+	case now.Before(start):
+		check.Status, check.Detail = StatusWarn, i.text(I18n.MsgInspectNotYet, Timestamp.Format(start))
+	case !now.Before(end):
+		check.Status, check.Detail = StatusFail, i.text(I18n.MsgInspectExpired, Timestamp.Format(end))
+	default:
+		check.Status, check.Detail = StatusPass, i.text(I18n.MsgInspectRemaining, int(end.Sub(now).Hours()/24))
+	}
+	return check
+}
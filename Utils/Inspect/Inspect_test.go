@@ -0,0 +1,352 @@
+package Inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+const (
+	testOffset = 3
+	testStep   = 3
+)
+
+// newStubSigner 一次性签发密钥与只包含其公钥的信任列表
+func newStubSigner(t *testing.T) (*Signer.StubSigner, *Trust.Store) {
+	t.Helper()
+	signer, err := Signer.NewStubSigner("", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, signer.Public(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.ID = key.KeyID
+	return signer, &Trust.Store{Keys: []*Trust.Key{key}}
+}
+
+func newLicense() *Entity.License {
+	var now = time.Now()
+	return &Entity.License{
+		StartTime:         Timestamp.Format(now),
+		EndTime:           Timestamp.Format(now.AddDate(0, 0, 30)),
+		LicenseCreateTime: Timestamp.Format(now),
+		AllowNodes:        8,
+		MotherBoardID:     "MB-TEST",
+		MacAddr:           "00:11:22:33:44:55",
+		ClientTimeZone:    "UTC+08:00",
+		CustomerTag:       "ACME",
+	}
+}
+
+// seal 以signer签名加密License，signer为空时不签名
+func seal(t *testing.T, lic *Entity.License, signer Signer.Signer) []byte {
+	t.Helper()
+	data, err := (&Utils.Sealer{Offset: testOffset, Step: testStep, Signer: signer}).Seal(lic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// tamper 以文件中的密钥重新加密修改后的License，密文可以解密但校验码不再一致
+func tamper(t *testing.T, data []byte, modify func(lic *Entity.License)) []byte {
+	t.Helper()
+	env, err := Envelope.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.ByAlg(env.EncAlg, env.SigAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, key := Utils.GetGMCipherAndKey(append([]byte(nil), env.Payload...), testOffset, testStep)
+	plain, err := suite.Decrypt(key, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lic = new(Entity.License)
+	if err = json.Unmarshal(plain, lic); err != nil {
+		t.Fatal(err)
+	}
+	modify(lic)
+	plain, err = json.Marshal(lic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err = suite.Encrypt(key, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Payload = Utils.AddKeyToGMCipher(cipher, key, testOffset, testStep)
+	tampered, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tampered
+}
+
+func inspect(t *testing.T, data []byte, store *Trust.Store) *Report {
+	t.Helper()
+	report, err := Inspect(data, &Options{Offset: testOffset, Step: testStep, TrustStore: store, Zones: []string{"UTC+09:00"}, Locale: I18n.EN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// assertChecks 比较各校验项的结果，未列出的校验项不检查
+func assertChecks(t *testing.T, report *Report, want map[string]string) {
+	t.Helper()
+	for _, check := range report.Checks {
+		if status, ok := want[check.Name]; ok && check.Status != status {
+			t.Errorf("%s = %s (%s), want %s", check.Name, check.Status, check.Detail, status)
+		}
+	}
+}
+
+func TestInspectSignedLicense(t *testing.T) {
+	signer, store := newStubSigner(t)
+	var report = inspect(t, seal(t, newLicense(), signer), store)
+	if report.Kind != KindLicense || report.Serial == "" || !report.Envelope.Signed || report.Envelope.KeyID != signer.ID {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.License.CheckCode != "" {
+		t.Error("check code shown")
+	}
+	assertChecks(t, report, map[string]string{
+		CheckEnvelope:  StatusPass,
+		CheckIntegrity: StatusPass,
+		CheckSignature: StatusPass,
+		CheckKeyID:     StatusPass,
+		CheckChain:     StatusSkip,
+		CheckValidity:  StatusPass,
+	})
+}
+
+func TestInspectNodeInfo(t *testing.T) {
+	var info = &Entity.License{MotherBoardID: "MB-TEST", MacAddr: "00:11:22:33:44:55", ClientTimeZone: "UTC+08:00", StartTime: Timestamp.Format(time.Now())}
+	var report = inspect(t, seal(t, info, nil), nil)
+	if report.Kind != KindNodeInfo || report.Serial != "" || report.Hardware.MotherBoardID != "MB-TEST" {
+		t.Fatalf("report = %+v", report)
+	}
+	assertChecks(t, report, map[string]string{
+		CheckIntegrity: StatusPass,
+		CheckSignature: StatusSkip,
+		CheckKeyID:     StatusSkip,
+		CheckValidity:  StatusSkip,
+	})
+}
+
+// TestInspectTampered 校验码不一致的文件仍输出内容，完整性校验失败且不再验签
+func TestInspectTampered(t *testing.T) {
+	signer, store := newStubSigner(t)
+	var data = tamper(t, seal(t, newLicense(), signer), func(lic *Entity.License) {
+		lic.AllowNodes = 1000
+	})
+	var report = inspect(t, data, store)
+	if report.License.AllowNodes != 1000 {
+		t.Fatalf("AllowNodes = %d, want the tampered value", report.License.AllowNodes)
+	}
+	assertChecks(t, report, map[string]string{
+		CheckIntegrity: StatusFail,
+		CheckSignature: StatusSkip,
+	})
+}
+
+func TestInspectUntrustedKey(t *testing.T) {
+	signer, _ := newStubSigner(t)
+	_, other := newStubSigner(t)
+	var report = inspect(t, seal(t, newLicense(), signer), other)
+	assertChecks(t, report, map[string]string{
+		CheckIntegrity: StatusPass,
+		CheckSignature: StatusFail,
+		CheckKeyID:     StatusFail,
+	})
+}
+
+// TestInspectResellerChain 经销商签发的License按证书链校验，证书被改动时证书链校验失败
+func TestInspectResellerChain(t *testing.T) {
+	root, store := newStubSigner(t)
+	reseller, _ := newStubSigner(t)
+	var now = time.Now()
+	var cert = &Entity.ResellerCert{
+		Serial:      "R1",
+		Reseller:    "Partner",
+		KeyID:       reseller.ID,
+		Suite:       reseller.Suite(),
+		NotBefore:   Timestamp.Format(now.Add(-time.Minute)),
+		NotAfter:    Timestamp.Format(now.AddDate(1, 0, 0)),
+		IssuerKeyID: root.ID,
+		IssuerSuite: root.Suite(),
+	}
+	suite, err := Suite.Get(cert.Suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, reseller.Public(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.PublicKey = key.PublicKey
+	signed, err := Trust.CertSignedBytes(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Signature, err = root.Sign(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lic = newLicense()
+	lic.ResellerChain = []*Entity.ResellerCert{cert}
+	var report = inspect(t, seal(t, lic, reseller), store)
+	assertChecks(t, report, map[string]string{
+		CheckSignature: StatusPass,
+		CheckKeyID:     StatusPass,
+		CheckChain:     StatusPass,
+	})
+
+	// 没有信任列表时不判断经销商密钥
+	report = inspect(t, seal(t, lic, reseller), nil)
+	assertChecks(t, report, map[string]string{
+		CheckSignature: StatusSkip,
+		CheckKeyID:     StatusSkip,
+		CheckChain:     StatusSkip,
+	})
+
+	var forged = *cert
+	forged.Reseller = "Someone else"
+	lic = newLicense()
+	lic.ResellerChain = []*Entity.ResellerCert{&forged}
+	report = inspect(t, seal(t, lic, reseller), store)
+	assertChecks(t, report, map[string]string{
+		CheckIntegrity: StatusPass,
+		CheckChain:     StatusFail,
+	})
+}
+
+// TestInspectTimes 时间按UTC、客户时区、本地时区与额外指定的时区显示，旧格式按客户时区解析
+func TestInspectTimes(t *testing.T) {
+	var lic = newLicense()
+	lic.StartTime = "2024-01-02T08:00:00"
+	var report = inspect(t, seal(t, lic, nil), nil)
+	var start *TimeInfo
+	for i := range report.Times {
+		if report.Times[i].Field == "start_time" {
+			start = &report.Times[i]
+		}
+	}
+	if start == nil || !start.Legacy {
+		t.Fatalf("start_time = %+v", start)
+	}
+	var zones = make(map[string]string)
+	for _, zoned := range start.Zones {
+		zones[zoned.Zone] = zoned.Time
+	}
+	for zone, want := range map[string]string{
+		"UTC":       "2024-01-02T00:00:00Z",
+		"UTC+08:00": "2024-01-02T08:00:00+08:00",
+		"UTC+09:00": "2024-01-02T09:00:00+09:00",
+	} {
+		if zones[zone] != want {
+			t.Errorf("start_time in %s = %q, want %q", zone, zones[zone], want)
+		}
+	}
+	if _, ok := zones[time.Local.String()]; !ok {
+		t.Errorf("local time missing: %v", zones)
+	}
+}
+
+// TestInspectBadTime 无法解析的时间按报告语言说明原因
+func TestInspectBadTime(t *testing.T) {
+	var lic = newLicense()
+	lic.EndTime = "someday"
+	var data = seal(t, lic, nil)
+	for _, locale := range []I18n.Locale{I18n.EN, I18n.ZH} {
+		report, err := Inspect(data, &Options{Offset: testOffset, Step: testStep, Locale: locale})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var end *TimeInfo
+		for i := range report.Times {
+			if report.Times[i].Field == "end_time" {
+				end = &report.Times[i]
+			}
+		}
+		if end == nil || len(end.Zones) != 0 || !strings.HasPrefix(end.Error, I18n.T(locale, I18n.CodeBadTime)) || !strings.Contains(end.Error, "someday") {
+			t.Errorf("%s end_time = %+v", locale, end)
+		}
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	signer, store := newStubSigner(t)
+	var data = seal(t, newLicense(), signer)
+	var report = inspect(t, data, store)
+	lic, _, _, err := Utils.Decode(data, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, FormatTable, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[" + I18n.T(I18n.EN, I18n.MsgInspectChecks) + "]", CheckSignature, I18n.T(I18n.EN, I18n.MsgInspectPass), "UTC+09:00", report.Serial} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("table output has no %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, FormatJSON, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Serial != report.Serial || len(decoded.Checks) != len(report.Checks) {
+		t.Errorf("JSON output = %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, FormatYAML, I18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: license\n", "serial: " + report.Serial + "\n", "- name: signature\n  status: pass\n", "  allow_nodes: 8\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("YAML output has no %q:\n%s", want, buf.String())
+		}
+	}
+
+	for _, format := range []string{FormatTable, FormatJSON, FormatYAML} {
+		buf.Reset()
+		if err := report.Write(&buf, format, I18n.EN); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), lic.CheckCode) {
+			t.Errorf("%s output shows the check code", format)
+		}
+	}
+
+	if err = report.Write(&buf, "xml", I18n.EN); I18n.Code(err) != I18n.CodeOutputFormat {
+		t.Fatalf("Write error = %v, want CodeOutputFormat", err)
+	}
+}
@@ -0,0 +1,292 @@
+package Inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// 输出格式
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Write 按格式输出检查结果，表格的标题与校验结果使用locale语言
+func (r *Report) Write(w io.Writer, format string, locale I18n.Locale) error {
+	switch format {
+	case FormatTable, "":
+		return r.writeTable(w, I18n.Or(locale))
+	case FormatJSON:
+		var encoder = json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(r)
+	case FormatYAML:
+		data, err := marshalYAML(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return I18n.In(locale, I18n.CodeOutputFormat, format)
+	}
+}
+
+func (r *Report) writeTable(w io.Writer, locale I18n.Locale) error {
+	var tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	var section = func(id I18n.ID) {
+		fmt.Fprintf(tw, "\n[%s]\n", I18n.T(locale, id))
+	}
+	var row = func(key string, value interface{}) {
+		fmt.Fprintf(tw, "  %s\t%v\n", key, value)
+	}
+
+	section(I18n.MsgInspectFile)
+	if r.Source != "" {
+		row("source", r.Source)
+	}
+	row("kind", r.Kind)
+	if r.Serial != "" {
+		row("serial", r.Serial)
+	}
+	row("version", r.Envelope.Version)
+	row("suite", r.Envelope.Suite)
+	row("signed", r.Envelope.Signed)
+	if r.Envelope.KeyID != "" {
+		row("key_id", r.Envelope.KeyID)
+	}
+
+	section(I18n.MsgInspectChecks)
+	for _, check := range r.Checks {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", check.Name, statusText(check.Status, locale), check.Detail)
+	}
+
+	section(I18n.MsgInspectFields)
+	var lic = r.License
+	row("customer_tag", lic.CustomerTag)
+	row("allow_nodes", lic.AllowNodes)
+	row("permanent_auth", lic.PermanentAuth)
+	if len(lic.Features) > 0 {
+		row("features", strings.Join(lic.Features, ", "))
+	}
+	if lic.ModelRoute != "" {
+		row("model_route", lic.ModelRoute)
+	}
+	row("check_status", lic.CheckStatus)
+
+	section(I18n.MsgInspectTimes)
+	for _, t := range r.Times {
+		if t.Error != "" {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", t.Field, t.Raw, t.Error)
+			continue
+		}
+		for j, zoned := range t.Zones {
+			var field = t.Field
+			if j > 0 {
+				field = ""
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", field, zoned.Zone, zoned.Time)
+		}
+	}
+
+	section(I18n.MsgInspectHardware)
+	var hw = r.Hardware
+	row("mother_board_id", hw.MotherBoardID)
+	row("mac_addr", hw.MacAddr)
+	row("client_time_zone", hw.ClientTimeZone)
+	if hw.ProductID != "" {
+		row("product_id", hw.ProductID)
+	}
+	row("nodes", fmt.Sprintf("%d/%d", hw.UseNodes, hw.AllowNodes))
+	for _, node := range hw.Nodes {
+		fmt.Fprintf(tw, "  \t%s\t%s\t%s\t%s\n", node.NodeName, node.NodeIP, node.NodeMac, node.NodeMotherBoardID)
+	}
+
+	if len(lic.ResellerChain) > 0 {
+		section(I18n.MsgInspectChain)
+		for _, cert := range lic.ResellerChain {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s ~ %s\n", cert.Serial, cert.Reseller, cert.KeyID, cert.NotBefore, cert.NotAfter)
+		}
+	}
+	return tw.Flush()
+}
+
+func statusText(status string, locale I18n.Locale) string {
+	switch status {
+	case StatusPass:
+		return I18n.T(locale, I18n.MsgInspectPass)
+	case StatusFail:
+		return I18n.T(locale, I18n.MsgInspectFail)
+	case StatusWarn:
+		return I18n.T(locale, I18n.MsgInspectWarn)
+	default:
+		return I18n.T(locale, I18n.MsgInspectSkip)
+	}
+}
+
+// yamlNode 按JSON字段顺序保存的值，用于输出YAML
+type yamlNode struct {
+	keys   []string
+	items  []*yamlNode
+	object bool
+	array  bool
+	scalar string
+}
+
+// marshalYAML 按JSON编码结果输出YAML，字段名与顺序与JSON一致
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := decodeYAMLNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if node.object || node.array {
+		node.write(&buf, 0)
+	} else {
+		buf.WriteString(node.scalar + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeYAMLNode(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	var node = new(yamlNode)
+	switch t := token.(type) {
+	case json.Delim:
+		node.object, node.array = t == '{', t == '['
+		for decoder.More() {
+			if node.object {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			item, err := decodeYAMLNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+		}
+		// 读取结束符
+		if _, err = decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		node.scalar = yamlString(t)
+	case json.Number:
+		node.scalar = t.String()
+	case bool:
+		node.scalar = strconv.FormatBool(t)
+	case nil:
+		node.scalar = "null"
+	}
+	return node, nil
+}
+
+// inline 标量与空集合写在同一行
+func (n *yamlNode) inline() (string, bool) {
+	switch {
+	case n.object && len(n.items) == 0:
+		return "{}", true
+	case n.array && len(n.items) == 0:
+		return "[]", true
+	case !n.object && !n.array:
+		return n.scalar, true
+	}
+	return "", false
+}
+
+func (n *yamlNode) write(buf *bytes.Buffer, indent int) {
+	var pad = strings.Repeat(" ", indent)
+	for i, item := range n.items {
+		if n.object {
+			buf.WriteString(pad + yamlString(n.keys[i]) + ":")
+			if value, ok := item.inline(); ok {
+				buf.WriteString(" " + value + "\n")
+				continue
+			}
+			buf.WriteString("\n")
+			if item.array {
+				item.write(buf, indent)
+			} else {
+				item.write(buf, indent+2)
+			}
+			continue
+		}
+		if value, ok := item.inline(); ok {
+			buf.WriteString(pad + "- " + value + "\n")
+			continue
+		}
+		if item.array {
+			buf.WriteString(pad + "-\n")
+			item.write(buf, indent+2)
+			continue
+		}
+		// 对象的第一个字段与"- "写在同一行
+		var nested bytes.Buffer
+		item.write(&nested, indent+2)
+		buf.WriteString(pad + "- ")
+		buf.Write(nested.Bytes()[indent+2:])
+	}
+}
+
+// yamlString 必要时以双引号输出字符串，JSON字符串同时是合法的YAML双引号字符串
+func yamlString(s string) string {
+	if !yamlNeedsQuote(s) {
+		return s
+	}
+	var buf bytes.Buffer
+	var encoder = json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func yamlNeedsQuote(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return true
+	}
+	// 数字及YAML 1.1中的十六进制、八进制与六十进制数字
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return true
+	}
+	if strings.Trim(s, "0123456789:._+-") == "" {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package KDF
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"golang.org/x/crypto/argon2"
+	"strconv"
+)
+
+// Name 写入文件头的KDF名称
+const Name = "argon2id"
+
+// 从文件头读取的参数上限，防止篡改的文件头使派生耗尽内存或CPU
+const (
+	MaxTime    = 16      // 最大迭代次数
+	MaxMemory  = 1 << 20 // 最大内存开销，单位KiB，即1GiB
+	MaxThreads = 16      // 最大并行度
+)
+
+// Params Argon2id参数，随密文一同保存
+type Params struct {
+	Salt    []byte
+	Time    uint32 // 迭代次数
+	Memory  uint32 // 内存开销，单位KiB
+	Threads uint8
+}
+
+// NewParams 使用推荐参数（64MiB、3次迭代）和随机盐创建参数
+func NewParams() (*Params, error) {
+	var salt = make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Params{Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4}, nil
+}
+
+// Derive 由口令派生keyLen字节的密钥
+func (p *Params) Derive(passphrase []byte, keyLen uint32) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, I18n.E(I18n.CodeEmptyPassphrase)
+	}
+	return argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, keyLen), nil
+}
+
+// Headers 转为PEM头
+func (p *Params) Headers() map[string]string {
+	return map[string]string{
+		"KDF":         Name,
+		"KDF-Salt":    hex.EncodeToString(p.Salt),
+		"KDF-Time":    strconv.FormatUint(uint64(p.Time), 10),
+		"KDF-Memory":  strconv.FormatUint(uint64(p.Memory), 10),
+		"KDF-Threads": strconv.FormatUint(uint64(p.Threads), 10),
+	}
+}
+
+// ParseHeaders 从PEM头读取参数，没有KDF头时返回nil，参数超出上限时返回错误
+func ParseHeaders(headers map[string]string) (*Params, error) {
+	kdf, ok := headers["KDF"]
+	if !ok {
+		return nil, nil
+	}
+	if kdf != Name {
+		return nil, I18n.E(I18n.CodeUnknownKDF, kdf)
+	}
+	salt, err := hex.DecodeString(headers["KDF-Salt"])
+	if err != nil || len(salt) == 0 {
+		return nil, I18n.E(I18n.CodeBadKDFSalt)
+	}
+	t, err := strconv.ParseUint(headers["KDF-Time"], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := strconv.ParseUint(headers["KDF-Memory"], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := strconv.ParseUint(headers["KDF-Threads"], 10, 8)
+	if err != nil {
+		return nil, err
+	}
+	// Argon2要求内存不少于8*并行度KiB，否则派生时panic
+	switch {
+	case t < 1 || t > MaxTime:
+		return nil, I18n.E(I18n.CodeKDFParam, "KDF-Time", t)
+	case threads < 1 || threads > MaxThreads:
+		return nil, I18n.E(I18n.CodeKDFParam, "KDF-Threads", threads)
+	case memory < 8*threads || memory > MaxMemory:
+		return nil, I18n.E(I18n.CodeKDFParam, "KDF-Memory", memory)
+	}
+	return &Params{Salt: salt, Time: uint32(t), Memory: uint32(memory), Threads: uint8(threads)}, nil
+}
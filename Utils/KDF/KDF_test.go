@@ -0,0 +1,39 @@
+package KDF
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+// TestParseHeadersLimits 文件头中的参数超出上限时拒绝派生
+func TestParseHeadersLimits(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseHeaders(params.Headers()); err != nil {
+		t.Fatalf("default params rejected: %v", err)
+	}
+	var cases = map[string]uint64{
+		"KDF-Time":    MaxTime + 1,
+		"KDF-Memory":  MaxMemory + 1,
+		"KDF-Threads": 0,
+	}
+	for header, value := range cases {
+		t.Run(header, func(t *testing.T) {
+			var headers = params.Headers()
+			headers[header] = strconv.FormatUint(value, 10)
+			if _, err := ParseHeaders(headers); I18n.Code(err) != I18n.CodeKDFParam {
+				t.Errorf("ParseHeaders error = %v, want %s", err, I18n.CodeKDFParam)
+			}
+		})
+	}
+	// 内存不足以支持并行度时派生会panic
+	var headers = params.Headers()
+	headers["KDF-Memory"] = "8"
+	if _, err = ParseHeaders(headers); I18n.Code(err) != I18n.CodeKDFParam {
+		t.Errorf("memory below 8*threads error = %v", err)
+	}
+}
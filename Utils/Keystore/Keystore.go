@@ -0,0 +1,170 @@
+package Keystore
+
+import (
+	"crypto"
+	"encoding/pem"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/KDF"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/manifoldco/promptui"
+	"os"
+	"path/filepath"
+)
+
+// 私钥文件为PEM格式，类型为 ELST ENCRYPTED PRIVATE KEY。
+// 口令经Argon2id派生出16字节密钥，再用私钥所属套件的AEAD算法加密PKCS#8私钥；
+// KDF参数、套件名与密钥ID保存在PEM头中。
+
+const blockType = "ELST ENCRYPTED PRIVATE KEY"
+
+// ErrWrongPassphrase 口令错误或文件被篡改
+var ErrWrongPassphrase error = I18n.E(I18n.CodeWrongPassphrase)
+
+// Key 解密后的签发私钥
+type Key struct {
+	KeyID   string
+	Suite   string
+	Private crypto.Signer
+}
+
+// Signer 转为进程内签名器
+func (k *Key) Signer() (*Signer.LocalSigner, error) {
+	return Signer.NewLocalSigner(k.KeyID, k.Suite, k.Private)
+}
+
+// Encrypt 使用口令加密私钥，返回PEM
+func Encrypt(key *Key, passphrase []byte) ([]byte, error) {
+	suite, err := Suite.Get(key.Suite)
+	if err != nil {
+		return nil, err
+	}
+	der, err := suite.MarshalPrivateKey(key.Private)
+	if err != nil {
+		return nil, err
+	}
+	params, err := KDF.NewParams()
+	if err != nil {
+		return nil, err
+	}
+	kek, err := params.Derive(passphrase, 16)
+	if err != nil {
+		return nil, err
+	}
+	encrypt, err := suite.Encrypt(kek, der)
+	if err != nil {
+		return nil, err
+	}
+	var headers = params.Headers()
+	headers["Suite"] = suite.Name()
+	headers["Key-ID"] = key.KeyID
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Headers: headers, Bytes: encrypt}), nil
+}
+
+// Decrypt 使用口令解密PEM私钥
+func Decrypt(data, passphrase []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, I18n.E(I18n.CodeNotKeyFile)
+	}
+	params, err := KDF.ParseHeaders(block.Headers)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		return nil, I18n.E(I18n.CodeNoKDF)
+	}
+	suite, err := Suite.Get(block.Headers["Suite"])
+	if err != nil {
+		return nil, err
+	}
+	kek, err := params.Derive(passphrase, 16)
+	if err != nil {
+		return nil, err
+	}
+	der, err := suite.Decrypt(kek, block.Bytes)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	priv, err := suite.ParsePrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{KeyID: block.Headers["Key-ID"], Suite: suite.Name(), Private: priv}, nil
+}
+
+// Save 加密保存私钥，文件权限为0600
+func Save(path string, key *Key, passphrase []byte) error {
+	data, err := Encrypt(key, passphrase)
+	if err != nil {
+		return err
+	}
+	return writePrivate(path, data)
+}
+
+// Load 读取并解密私钥文件
+func Load(path string, passphrase []byte) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, passphrase)
+}
+
+// ChangePassphrase 修改私钥文件口令
+func ChangePassphrase(path string, oldPassphrase, newPassphrase []byte) error {
+	key, err := Load(path, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return Save(path, key, newPassphrase)
+}
+
+// ReadPassphrase 交互式读取口令，confirm为true时要求输入两次
+func ReadPassphrase(label string, confirm bool) ([]byte, error) {
+	prompt := promptui.Prompt{
+		Label: label,
+		Mask:  '*',
+	}
+	result, err := prompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	if result == "" {
+		return nil, I18n.E(I18n.CodeEmptyPassphrase)
+	}
+	if confirm {
+		prompt.Label = I18n.T("", I18n.PromptConfirmPassphrase)
+		again, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		if again != result {
+			return nil, I18n.E(I18n.CodePassphraseMismatch)
+		}
+	}
+	return []byte(result), nil
+}
+
+// writePrivate 写入同目录下的临时文件后替换原文件，写入中断时原文件保持不变；
+// 临时文件以0600权限创建，替换后已存在文件的权限也随之收紧
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package Keystore
+
+import (
+	"bytes"
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+)
+
+func newKey(t *testing.T, name string) *Key {
+	t.Helper()
+	suite, err := Suite.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Key{KeyID: name + "-0001", Suite: name, Private: priv}
+}
+
+// samePublic 比较两个私钥对应的公钥
+func samePublic(t *testing.T, name string, a, b crypto.Signer) bool {
+	t.Helper()
+	suite, err := Suite.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa, err := suite.MarshalPublicKey(a.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := suite.MarshalPublicKey(b.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Equal(pa, pb)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	for _, name := range []string{Suite.NameGM, Suite.NameStd} {
+		t.Run(name, func(t *testing.T) {
+			var key = newKey(t, name)
+			data, err := Encrypt(key, []byte("passphrase"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(data, []byte("-----BEGIN "+blockType+"-----")) {
+				t.Fatalf("PEM = %s", data)
+			}
+			decrypted, err := Decrypt(data, []byte("passphrase"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decrypted.KeyID != key.KeyID || decrypted.Suite != name || !samePublic(t, name, decrypted.Private, key.Private) {
+				t.Errorf("decrypted key = %+v", decrypted)
+			}
+		})
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	data, err := Encrypt(newKey(t, Suite.NameGM), []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decrypt(data, []byte("Passphrase")); err != ErrWrongPassphrase {
+		t.Fatalf("Decrypt error = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	var key = newKey(t, Suite.NameStd)
+	var path = filepath.Join(t.TempDir(), "signing.key")
+	if err := Save(path, key, []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ChangePassphrase(path, []byte("wrong"), []byte("new")); err != ErrWrongPassphrase {
+		t.Fatalf("ChangePassphrase error = %v, want ErrWrongPassphrase", err)
+	}
+	if err := ChangePassphrase(path, []byte("old"), []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, []byte("old")); err != ErrWrongPassphrase {
+		t.Errorf("old passphrase still accepted: %v", err)
+	}
+	loaded, err := Load(path, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.KeyID != key.KeyID || !samePublic(t, Suite.NameStd, loaded.Private, key.Private) {
+		t.Errorf("loaded key = %+v", loaded)
+	}
+}
+
+// TestSaveMode 覆盖已存在的0644文件后权限为0600，且不留下临时文件
+func TestSaveMode(t *testing.T) {
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "signing.key")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, newKey(t, Suite.NameGM), []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory entries = %v, want only the key file", entries)
+	}
+}
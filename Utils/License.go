@@ -1,28 +1,17 @@
 package Utils
 
 import (
-	"encoding/json"
 	"github.com/lizazacn/ElstLic/Entity"
-	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
 	"net"
 	"os/exec"
 	"regexp"
 	"strings"
 )
 
-// CheckData 校验数据
+// CheckData 校验数据（SM3）
 func CheckData(lic *Entity.License) (bool, error) {
-	var oldCheckCode = lic.CheckCode
-	lic.CheckCode = ""
-	licByte, err := json.Marshal(lic)
-	if err != nil {
-		return false, err
-	}
-	checkCode := GM.SM3SUM(string(licByte))
-	if oldCheckCode == checkCode {
-		return true, nil
-	}
-	return false, nil
+	return CheckDataWith(lic, Suite.GMSuite{})
 }
 
 // GetGMCipherAndKey 获取GM密文和SM4Key
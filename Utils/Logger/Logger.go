@@ -0,0 +1,302 @@
+package Logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// 常用字段名
+const (
+	FieldSerial   = "license_serial" // License序列号
+	FieldCustomer = "customer_tag"   // 客户标记
+	FieldCheck    = "check"          // 校验项
+	FieldKeyID    = "key_id"         // 签发密钥ID
+	FieldSource   = "source"         // License来源
+	FieldPath     = "path"           // 文件路径
+	FieldError    = "error"          // 错误信息
+)
+
+// Field 结构化日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F 创建日志字段
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err 错误字段
+func Err(err error) Field {
+	return Field{Key: FieldError, Value: err}
+}
+
+// License License标识字段：序列号与客户标记。
+// CheckCode前16位是对称密钥，不能直接输出，序列号取其摘要。
+func License(lic *Entity.License) []Field {
+	if lic == nil {
+		return nil
+	}
+	return []Field{F(FieldSerial, Serial(lic)), F(FieldCustomer, lic.CustomerTag)}
+}
+
+// Serial License序列号，由校验码摘要生成，不泄露校验码
+func Serial(lic *Entity.License) string {
+	if lic == nil || lic.CheckCode == "" {
+		return ""
+	}
+	var sum = sha256.Sum256([]byte(lic.CheckCode))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Logger 日志接口，库代码通过该接口输出日志，可适配任意日志库
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Nop 丢弃全部日志，库代码默认使用
+var Nop Logger = nop{}
+
+type nop struct{}
+
+func (nop) Debug(string, ...Field) {}
+func (nop) Info(string, ...Field)  {}
+func (nop) Warn(string, ...Field)  {}
+func (nop) Error(string, ...Field) {}
+
+// OrNop l为空时返回Nop
+func OrNop(l Logger) Logger {
+	if l == nil {
+		return Nop
+	}
+	return l
+}
+
+// redactKeys 值会被替换为 [REDACTED] 的字段名关键字
+var redactKeys = []string{"passphrase", "password", "secret", "token", "private", "check_code", "signature"}
+
+// Redacted 脱敏后的字段值
+const Redacted = "[REDACTED]"
+
+// Redact 对口令、私钥、令牌等敏感字段脱敏。字段名包含敏感关键字，
+// 或字段名为key/key_data等（key_id除外）时替换字段值；License值去掉校验码后输出。
+func Redact(fields []Field) []Field {
+	var out = make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = f
+		switch value := f.Value.(type) {
+		case *Entity.License:
+			out[i].Value = redactLicense(value)
+		case Entity.License:
+			out[i].Value = *redactLicense(&value)
+		}
+		if isSecret(f.Key) {
+			out[i].Value = Redacted
+		}
+	}
+	return out
+}
+
+// redactLicense 去掉校验码的License副本，校验码前16位是对称密钥
+func redactLicense(lic *Entity.License) *Entity.License {
+	var clone = lic.Clone()
+	if clone != nil && clone.CheckCode != "" {
+		clone.CheckCode = Redacted
+	}
+	return clone
+}
+
+func isSecret(key string) bool {
+	var k = strings.ToLower(key)
+	for _, s := range redactKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return k == "key" || (strings.HasSuffix(k, "_key") && k != "public_key")
+}
+
+// Format 输出格式
+type Format int
+
+const (
+	FormatText Format = iota // key=value文本
+	FormatJSON               // 每行一个JSON对象
+)
+
+// writer 写入io.Writer的日志实现
+type writer struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	format Format
+	fields []Field
+}
+
+// New 创建写入w的日志，低于level的日志被丢弃，敏感字段自动脱敏
+func New(w io.Writer, level Level, format Format) Logger {
+	return &writer{mu: new(sync.Mutex), out: w, level: level, format: format}
+}
+
+func (w *writer) Debug(msg string, fields ...Field) { w.log(LevelDebug, msg, fields) }
+func (w *writer) Info(msg string, fields ...Field)  { w.log(LevelInfo, msg, fields) }
+func (w *writer) Warn(msg string, fields ...Field)  { w.log(LevelWarn, msg, fields) }
+func (w *writer) Error(msg string, fields ...Field) { w.log(LevelError, msg, fields) }
+
+func (w *writer) log(level Level, msg string, fields []Field) {
+	if level < w.level {
+		return
+	}
+	fields = Redact(append(append([]Field{}, w.fields...), fields...))
+	var line string
+	if w.format == FormatJSON {
+		line = jsonLine(level, msg, fields)
+	} else {
+		line = textLine(level, msg, fields)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.out, line)
+}
+
+func textLine(level Level, msg string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(time.Now().Format(time.RFC3339))
+	b.WriteString(" level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	b.WriteString(strconv.Quote(msg))
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		var v = valueString(f.Value)
+		if strings.ContainsAny(v, " \"=") || v == "" {
+			v = strconv.Quote(v)
+		}
+		b.WriteString(v)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func jsonLine(level Level, msg string, fields []Field) string {
+	var m = make(map[string]interface{}, len(fields)+3)
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			m[f.Key] = err.Error()
+			continue
+		}
+		m[f.Key] = f.Value
+	}
+	m["time"] = time.Now().Format(time.RFC3339)
+	m["level"] = level.String()
+	m["msg"] = msg
+	data, err := json.Marshal(m)
+	if err != nil {
+		return textLine(level, msg, fields)
+	}
+	return string(data) + "\n"
+}
+
+func valueString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case error:
+		return value.Error()
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// With 返回附带固定字段的日志
+func With(l Logger, fields ...Field) Logger {
+	if w, ok := l.(*writer); ok {
+		var clone = *w
+		clone.fields = append(append([]Field{}, w.fields...), fields...)
+		return &clone
+	}
+	return &with{next: OrNop(l), fields: fields}
+}
+
+type with struct {
+	next   Logger
+	fields []Field
+}
+
+func (w *with) Debug(msg string, fields ...Field) { w.next.Debug(msg, w.merge(fields)...) }
+func (w *with) Info(msg string, fields ...Field)  { w.next.Info(msg, w.merge(fields)...) }
+func (w *with) Warn(msg string, fields ...Field)  { w.next.Warn(msg, w.merge(fields)...) }
+func (w *with) Error(msg string, fields ...Field) { w.next.Error(msg, w.merge(fields)...) }
+
+func (w *with) merge(fields []Field) []Field {
+	return append(append([]Field{}, w.fields...), fields...)
+}
+
+// Multi 同时写入多个日志
+func Multi(loggers ...Logger) Logger {
+	return multi(loggers)
+}
+
+type multi []Logger
+
+func (m multi) Debug(msg string, fields ...Field) {
+	for _, l := range m {
+		l.Debug(msg, fields...)
+	}
+}
+func (m multi) Info(msg string, fields ...Field) {
+	for _, l := range m {
+		l.Info(msg, fields...)
+	}
+}
+func (m multi) Warn(msg string, fields ...Field) {
+	for _, l := range m {
+		l.Warn(msg, fields...)
+	}
+}
+func (m multi) Error(msg string, fields ...Field) {
+	for _, l := range m {
+		l.Error(msg, fields...)
+	}
+}
@@ -0,0 +1,253 @@
+package Utils
+
+import (
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+var (
+	ErrTampered      error = I18n.E(I18n.CodeTampered)      // 校验码不一致
+	ErrUnsigned      error = I18n.E(I18n.CodeUnsigned)      // 要求签名但文件未签名
+	ErrBadSignature  error = I18n.E(I18n.CodeBadSignature)  // 签名与公钥不匹配
+	ErrSuiteMismatch error = I18n.E(I18n.CodeSuiteMismatch) // 文件头被替换
+)
+
+// Sealer License加密封装配置
+type Sealer struct {
+	Offset int
+	Step   int
+	Suite  Suite.CryptoSuite // 算法套件，为空时使用签名器的套件或默认套件
+	Signer Signer.Signer     // 签名器，为空时不签名
+}
+
+// Opener License解密校验配置
+type Opener struct {
+	Offset     int
+	Step       int
+	PublicKey  crypto.PublicKey // 签发公钥，设置后License必须带有效签名
+	TrustStore *Trust.Store     // 受信任的签发公钥列表，设置后按文件头的密钥ID选择公钥
+}
+
+// SealLicense 使用默认套件加密License，不签名
+func SealLicense(lic *Entity.License, offset, step int) ([]byte, error) {
+	var sealer = &Sealer{Offset: offset, Step: step}
+	return sealer.Seal(lic)
+}
+
+// OpenLicense 解密并校验License，不校验签名
+func OpenLicense(data []byte, offset, step int) (*Entity.License, *Envelope.Envelope, error) {
+	var opener = &Opener{Offset: offset, Step: step}
+	return opener.Open(data)
+}
+
+// Seal 计算校验码、加密并签名License，返回当前版本封装后的文件内容
+func (s *Sealer) Seal(lic *Entity.License) ([]byte, error) {
+	var suite = s.Suite
+	if suite == nil {
+		var name = Suite.Default
+		if s.Signer != nil {
+			name = s.Signer.Suite()
+		}
+		var err error
+		suite, err = Suite.Get(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Signer != nil && s.Signer.Suite() != suite.Name() {
+		return nil, I18n.E(I18n.CodeSignerSuite, s.Signer.Suite(), suite.Name())
+	}
+	lic.CryptoSuite = suite.Name()
+	lic.CheckCode = ""
+	licByte, err := json.Marshal(lic)
+	if err != nil {
+		return nil, err
+	}
+	lic.CheckCode = hex.EncodeToString(suite.Hash(licByte))
+
+	licByte, err = json.Marshal(lic)
+	if err != nil {
+		return nil, err
+	}
+	var key = lic.CheckCode[:16]
+	encrypt, err := suite.Encrypt([]byte(key), licByte)
+	if err != nil {
+		return nil, err
+	}
+	var env = &Envelope.Envelope{
+		Version: Envelope.CurrentVersion,
+		EncAlg:  suite.EncAlg(),
+		SigAlg:  Envelope.SigNone,
+		HashAlg: suite.HashAlg(),
+		Payload: AddKeyToGMCipher(encrypt, []byte(key), s.Offset, s.Step),
+	}
+	if s.Signer != nil {
+		env.SigAlg = suite.SigAlg()
+		env.KeyID = s.Signer.KeyID()
+		env.Signature, err = s.Signer.Sign(env.SignedBytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return env.Marshal()
+}
+
+// Open 解析任意已注册版本的文件内容，按文件头选择算法套件解密，并校验License完整性与签名
+func (o *Opener) Open(data []byte) (*Entity.License, *Envelope.Envelope, error) {
+	env, err := Envelope.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if (o.PublicKey != nil || o.TrustStore != nil) && env.SigAlg == Envelope.SigNone {
+		return nil, nil, ErrUnsigned
+	}
+	// 签名覆盖文件头与密文，解密前先保留一份用于验签
+	var signed = env.SignedBytes()
+	lic, suite, err := decrypt(env, o.Offset, o.Step)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = CheckIntegrity(lic, suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = o.verify(env, signed, suite, lic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lic, env, nil
+}
+
+// Decode 解析并解密文件内容，不校验完整性与签名，用于检查工具展示可能被篡改的文件
+func Decode(data []byte, offset, step int) (*Entity.License, *Envelope.Envelope, Suite.CryptoSuite, error) {
+	env, err := Envelope.Parse(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lic, suite, err := decrypt(env, offset, step)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return lic, env, suite, nil
+}
+
+// decrypt 按文件头选择算法套件解密License
+func decrypt(env *Envelope.Envelope, offset, step int) (*Entity.License, Suite.CryptoSuite, error) {
+	suite, err := Suite.ByAlg(env.EncAlg, env.SigAlg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(env.Payload) <= 15*step+offset {
+		return nil, nil, Envelope.ErrTruncated
+	}
+	// 取出密钥时会改动密文，复制一份以免修改调用方的数据
+	cipher, key := GetGMCipherAndKey(append([]byte(nil), env.Payload...), offset, step)
+	plain, err := suite.Decrypt(key, cipher)
+	if err != nil {
+		return nil, nil, err
+	}
+	var lic = new(Entity.License)
+	err = json.Unmarshal(plain, lic)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lic, suite, nil
+}
+
+// CheckIntegrity 校验License记录的算法套件与校验码，不一致时返回ErrSuiteMismatch或ErrTampered
+func CheckIntegrity(lic *Entity.License, suite Suite.CryptoSuite) error {
+	if lic.CryptoSuite != "" && lic.CryptoSuite != suite.Name() {
+		return ErrSuiteMismatch
+	}
+	stat, err := CheckDataWith(lic, suite)
+	if err != nil {
+		return err
+	}
+	if !stat {
+		return ErrTampered
+	}
+	return nil
+}
+
+// verify 校验签名。经销商签发的License按证书链校验并检查授权范围，
+// 厂商直接签发的License按信任列表中的密钥及其有效期校验。
+func (o *Opener) verify(env *Envelope.Envelope, signed []byte, suite Suite.CryptoSuite, lic *Entity.License) error {
+	if o.PublicKey == nil && o.TrustStore == nil {
+		return nil
+	}
+	var pub = o.PublicKey
+	var trustKey *Trust.Key
+	issuedAt, timeErr := lic.IssuedAt()
+	switch {
+	case len(lic.ResellerChain) > 0:
+		if timeErr != nil {
+			return Trust.ErrCertExpired
+		}
+		var leaf = lic.ResellerChain[0]
+		if leaf.KeyID != env.KeyID || leaf.Suite != suite.Name() {
+			return ErrBadSignature
+		}
+		chainPub, err := Trust.VerifyChain(lic.ResellerChain, o.TrustStore, lic)
+		if err != nil {
+			return err
+		}
+		pub = chainPub
+	case o.TrustStore != nil:
+		key, err := o.TrustStore.Lookup(env.KeyID)
+		if err != nil {
+			return err
+		}
+		if key.Suite != suite.Name() {
+			return ErrSuiteMismatch
+		}
+		pub, err = key.Public()
+		if err != nil {
+			return err
+		}
+		trustKey = key
+	}
+	if !suite.Verify(pub, signed, env.Signature) {
+		return ErrBadSignature
+	}
+	if trustKey != nil && (timeErr != nil || !trustKey.Covers(issuedAt)) {
+		return Trust.ErrKeyExpired
+	}
+	return nil
+}
+
+// CheckDataWith 使用指定套件的摘要算法校验数据
+func CheckDataWith(lic *Entity.License, suite Suite.CryptoSuite) (bool, error) {
+	var oldCheckCode = lic.CheckCode
+	lic.CheckCode = ""
+	licByte, err := json.Marshal(lic)
+	// 恢复校验码，打开后的License仍可计算序列号
+	lic.CheckCode = oldCheckCode
+	if err != nil {
+		return false, err
+	}
+	checkCode := hex.EncodeToString(suite.Hash(licByte))
+	if oldCheckCode == checkCode {
+		return true, nil
+	}
+	return false, nil
+}
+
+// NeedsMigration 判断文件是否为旧格式或仍使用旧加密算法
+func NeedsMigration(env *Envelope.Envelope) bool {
+	return env.Version != Envelope.CurrentVersion || Suite.IsLegacy(env.EncAlg)
+}
+
+// IsTampered 判断错误是否表示数据被篡改
+func IsTampered(err error) bool {
+	return errors.Is(err, ErrTampered) || errors.Is(err, ErrBadSignature) ||
+		errors.Is(err, ErrSuiteMismatch) || errors.Is(err, GM.ErrAuthFailed) || errors.Is(err, GM.ErrBadPadding)
+}
@@ -0,0 +1,226 @@
+package Utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+const (
+	testOffset = 3
+	testStep   = 3
+)
+
+// newStubSigner 一次性签发密钥与只包含其公钥的信任列表
+func newStubSigner(t *testing.T, suiteName string) (*Signer.StubSigner, *Trust.Store) {
+	t.Helper()
+	signer, err := Signer.NewStubSigner("", suiteName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := Suite.Get(suiteName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Trust.NewKey(suite, signer.Public(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.ID = key.KeyID
+	return signer, &Trust.Store{Keys: []*Trust.Key{key}}
+}
+
+func newLicense() *Entity.License {
+	var now = time.Now()
+	return &Entity.License{
+		StartTime:         Timestamp.Format(now),
+		EndTime:           Timestamp.Format(now.AddDate(1, 0, 0)),
+		LicenseCreateTime: Timestamp.Format(now),
+		AllowNodes:        8,
+		MotherBoardID:     "MB-TEST",
+		MacAddr:           "00:11:22:33:44:55",
+		CustomerTag:       "ACME",
+	}
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	for _, name := range []string{Suite.NameGM, Suite.NameStd} {
+		t.Run(name, func(t *testing.T) {
+			signer, store := newStubSigner(t, name)
+			var sealer = &Sealer{Offset: testOffset, Step: testStep, Signer: signer}
+			data, err := sealer.Seal(newLicense())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.Calls != 1 {
+				t.Fatalf("Sign called %d times", signer.Calls)
+			}
+			lic, env, err := (&Opener{Offset: testOffset, Step: testStep, TrustStore: store}).Open(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.KeyID != signer.ID || lic.CryptoSuite != name {
+				t.Errorf("KeyID = %q, suite = %q", env.KeyID, lic.CryptoSuite)
+			}
+			if lic.AllowNodes != 8 || lic.MotherBoardID != "MB-TEST" || lic.CheckCode == "" {
+				t.Errorf("opened license = %+v", lic)
+			}
+			_, _, err = (&Opener{Offset: testOffset, Step: testStep, PublicKey: signer.Public()}).Open(data)
+			if err != nil {
+				t.Fatalf("open with public key: %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenRejectsBadSignature(t *testing.T) {
+	signer, store := newStubSigner(t, Suite.NameGM)
+	data, err := (&Sealer{Offset: testOffset, Step: testStep, Signer: signer}).Seal(newLicense())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Envelope.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Signature[len(env.Signature)-1] ^= 1
+	forged, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = (&Opener{Offset: testOffset, Step: testStep, TrustStore: store}).Open(forged)
+	if !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Open error = %v, want ErrBadSignature", err)
+	}
+
+	// 其他密钥签名的License
+	other, _ := newStubSigner(t, Suite.NameGM)
+	_, _, err = (&Opener{Offset: testOffset, Step: testStep, PublicKey: other.Public()}).Open(data)
+	if !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Open with another key error = %v, want ErrBadSignature", err)
+	}
+	_, otherStore := newStubSigner(t, Suite.NameGM)
+	_, _, err = (&Opener{Offset: testOffset, Step: testStep, TrustStore: otherStore}).Open(data)
+	if !errors.Is(err, Trust.ErrUnknownKey) {
+		t.Fatalf("Open with another store error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestOpenRequiresSignature(t *testing.T) {
+	signer, store := newStubSigner(t, Suite.NameGM)
+	data, err := SealLicense(newLicense(), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = OpenLicense(data, testOffset, testStep); err != nil {
+		t.Fatalf("open unsigned license without key: %v", err)
+	}
+	_, _, err = (&Opener{Offset: testOffset, Step: testStep, TrustStore: store}).Open(data)
+	if !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("Open error = %v, want ErrUnsigned", err)
+	}
+	if signer.Calls != 0 {
+		t.Fatal("unsigned seal called the signer")
+	}
+}
+
+func TestSealSignerError(t *testing.T) {
+	signer, _ := newStubSigner(t, Suite.NameGM)
+	signer.Err = errors.New("hsm offline")
+	_, err := (&Sealer{Offset: testOffset, Step: testStep, Signer: signer}).Seal(newLicense())
+	if !errors.Is(err, signer.Err) {
+		t.Fatalf("Seal error = %v, want signer error", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	data, err := SealLicense(newLicense(), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Envelope.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Payload[len(env.Payload)-1] ^= 1
+	tampered, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = OpenLicense(tampered, testOffset, testStep); err == nil {
+		t.Fatal("tampered license opened")
+	}
+}
+
+// TestDecryptTampered 两个套件对被篡改或截断的密文返回相同的错误，IsTampered均能识别
+func TestDecryptTampered(t *testing.T) {
+	for _, name := range []string{Suite.NameGM, Suite.NameStd} {
+		t.Run(name, func(t *testing.T) {
+			suite, err := Suite.Get(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var key = make([]byte, 16)
+			ciphertext, err := suite.Encrypt(key, []byte("license payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext[len(ciphertext)-1] ^= 1
+			if _, err = suite.Decrypt(key, ciphertext); !errors.Is(err, GM.ErrAuthFailed) || !IsTampered(err) {
+				t.Errorf("tampered ciphertext error = %v, want ErrAuthFailed", err)
+			}
+			if _, err = suite.Decrypt(key, ciphertext[:12]); !errors.Is(err, GM.ErrInvalidCiphertext) {
+				t.Errorf("short ciphertext error = %v, want ErrInvalidCiphertext", err)
+			}
+		})
+	}
+}
+
+// TestOpenLegacyFixture 迁移前签发的SM4-CBC旧格式文件仍能打开，并被标记为需要迁移
+func TestOpenLegacyFixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "legacy-sm4cbc.lic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lic, env, err := OpenLicense(data, testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Version != Envelope.VersionLegacy || env.EncAlg != Envelope.EncSM4CBC || env.SigAlg != Envelope.SigNone {
+		t.Errorf("envelope = %+v", env)
+	}
+	if !NeedsMigration(env) {
+		t.Error("legacy file not flagged for migration")
+	}
+	if lic.MotherBoardID != "MB-LEGACY" || lic.AllowNodes != 8 || lic.CryptoSuite != "" {
+		t.Errorf("license = %+v", lic)
+	}
+	end, err := lic.EndAt()
+	if err != nil || !end.Equal(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("EndAt = %v, %v, want the legacy time in the client zone", end, err)
+	}
+
+	// 旧文件要求签名时按未签名拒绝
+	_, store := newStubSigner(t, Suite.NameGM)
+	if _, _, err = (&Opener{Offset: testOffset, Step: testStep, TrustStore: store}).Open(data); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("open with a trust store error = %v, want ErrUnsigned", err)
+	}
+
+	current, err := SealLicense(newLicense(), testOffset, testStep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, env, err = OpenLicense(current, testOffset, testStep); err != nil || NeedsMigration(env) {
+		t.Errorf("current file flagged for migration: %v", err)
+	}
+}
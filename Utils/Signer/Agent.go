@@ -0,0 +1,192 @@
+package Signer
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 签名代理协议：每个连接发送一个JSON请求并读取一个JSON响应。
+//
+//	{"op":"info"}                 -> {"key_id":"...","suite":"gm","public_key":"<DER>"}
+//	{"op":"sign","data":"<字节>"} -> {"signature":"<字节>"}
+//
+// 出错时响应中只有error字段。[]byte字段按encoding/json规则以base64编码。
+
+const (
+	opInfo = "info"
+	opSign = "sign"
+)
+
+type agentRequest struct {
+	Op   string `json:"op"`
+	Data []byte `json:"data,omitempty"`
+}
+
+type agentResponse struct {
+	KeyID     string `json:"key_id,omitempty"`
+	Suite     string `json:"suite,omitempty"`
+	PublicKey []byte `json:"public_key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AgentSigner 通过Unix Socket调用签名代理进程的签名器，私钥不进入当前进程
+type AgentSigner struct {
+	Network string // 默认为unix
+	Address string
+	Timeout time.Duration
+
+	keyID     string
+	suite     string
+	publicKey crypto.PublicKey
+}
+
+// DialAgent 连接签名代理并读取密钥信息
+func DialAgent(socketPath string) (*AgentSigner, error) {
+	var agent = &AgentSigner{Network: "unix", Address: socketPath, Timeout: 10 * time.Second}
+	resp, err := agent.call(&agentRequest{Op: opInfo})
+	if err != nil {
+		return nil, err
+	}
+	suite, err := Suite.Get(resp.Suite)
+	if err != nil {
+		return nil, err
+	}
+	agent.publicKey, err = suite.ParsePublicKey(resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	agent.keyID = resp.KeyID
+	agent.suite = suite.Name()
+	return agent, nil
+}
+
+func (a *AgentSigner) KeyID() string            { return a.keyID }
+func (a *AgentSigner) Suite() string            { return a.suite }
+func (a *AgentSigner) Public() crypto.PublicKey { return a.publicKey }
+
+// Sign 请求签名代理签名
+func (a *AgentSigner) Sign(data []byte) ([]byte, error) {
+	resp, err := a.call(&agentRequest{Op: opSign, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+func (a *AgentSigner) call(req *agentRequest) (*agentResponse, error) {
+	conn, err := net.DialTimeout(a.Network, a.Address, a.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if a.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(a.Timeout))
+	}
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp = new(agentResponse)
+	err = json.NewDecoder(conn).Decode(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// ListenAgent 在path上监听签名代理的Unix Socket，只允许当前用户连接。
+// Socket先在权限为0700的临时目录中创建并设置为0600，再移动到path，
+// 设置权限之前其他用户无法连接。
+func ListenAgent(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".signagent-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	var tmp = filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(tmp, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return &agentListener{Listener: listener, path: path}, nil
+}
+
+// agentListener 关闭时删除移动后的Socket文件
+type agentListener struct {
+	net.Listener
+	path string
+}
+
+func (l *agentListener) Close() error {
+	err := l.Listener.Close()
+	_ = os.Remove(l.path)
+	return err
+}
+
+// ServeAgent 在listener上提供签名代理服务，直到listener关闭
+func ServeAgent(listener net.Listener, signer Signer) error {
+	suite, err := Suite.Get(signer.Suite())
+	if err != nil {
+		return err
+	}
+	publicKey, err := suite.MarshalPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go serveAgentConn(conn, signer, publicKey)
+	}
+}
+
+func serveAgentConn(conn net.Conn, signer Signer, publicKey []byte) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	var req = new(agentRequest)
+	var resp = new(agentResponse)
+	err := json.NewDecoder(conn).Decode(req)
+	switch {
+	case err != nil:
+		resp.Error = err.Error()
+	case req.Op == opInfo:
+		resp.KeyID = signer.KeyID()
+		resp.Suite = signer.Suite()
+		resp.PublicKey = publicKey
+	case req.Op == opSign:
+		resp.Signature, err = signer.Sign(req.Data)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	default:
+		resp.Error = I18n.T("", I18n.CodeAgentOp, req.Op)
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
@@ -0,0 +1,58 @@
+package Signer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+)
+
+// TestListenAgent Socket移动到目标路径时权限已为0600，签名请求经代理完成
+func TestListenAgent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not checked on " + runtime.GOOS)
+	}
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "agent.sock")
+	listener, err := ListenAgent(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary directory left behind: %v", entries)
+	}
+
+	stub, err := NewStubSigner("agent-key", Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = ServeAgent(listener, stub)
+	}()
+	agent, err := DialAgent(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.KeyID() != "agent-key" {
+		t.Errorf("KeyID = %q", agent.KeyID())
+	}
+	if _, err = agent.Sign([]byte("data")); err != nil || stub.Calls != 1 {
+		t.Errorf("Sign error = %v, calls = %d", err, stub.Calls)
+	}
+
+	if err = listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed on close: %v", err)
+	}
+}
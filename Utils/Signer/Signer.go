@@ -0,0 +1,100 @@
+package Signer
+
+import (
+	"crypto"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+)
+
+// Signer 签发License使用的签名器，私钥可以在进程内、签名代理进程或硬件模块中
+type Signer interface {
+	// KeyID 签发密钥ID，写入License文件头
+	KeyID() string
+	// Suite 签名所属的算法套件名称
+	Suite() string
+	// Public 签发公钥
+	Public() crypto.PublicKey
+	// Sign 对数据签名
+	Sign(data []byte) ([]byte, error)
+}
+
+// LocalSigner 进程内持有私钥的签名器
+type LocalSigner struct {
+	ID        string
+	SuiteName string
+	Key       crypto.Signer
+}
+
+// NewLocalSigner 创建进程内签名器，套件名为空时使用默认套件
+func NewLocalSigner(keyID, suiteName string, key crypto.Signer) (*LocalSigner, error) {
+	if key == nil {
+		return nil, I18n.E(I18n.CodeNoSigningKey)
+	}
+	suite, err := Suite.Get(suiteName)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalSigner{ID: keyID, SuiteName: suite.Name(), Key: key}, nil
+}
+
+func (l *LocalSigner) KeyID() string            { return l.ID }
+func (l *LocalSigner) Suite() string            { return l.SuiteName }
+func (l *LocalSigner) Public() crypto.PublicKey { return l.Key.Public() }
+
+// Sign 使用套件的签名算法签名
+func (l *LocalSigner) Sign(data []byte) ([]byte, error) {
+	suite, err := Suite.Get(l.SuiteName)
+	if err != nil {
+		return nil, err
+	}
+	return suite.Sign(l.Key, data)
+}
+
+// FuncSigner 将外部签名函数适配为Signer，用于接入PKCS#11等不暴露私钥的模块
+type FuncSigner struct {
+	ID        string
+	SuiteName string
+	PublicKey crypto.PublicKey
+	SignFunc  func(data []byte) ([]byte, error)
+}
+
+func (f *FuncSigner) KeyID() string            { return f.ID }
+func (f *FuncSigner) Suite() string            { return f.SuiteName }
+func (f *FuncSigner) Public() crypto.PublicKey { return f.PublicKey }
+
+// Sign 调用外部签名函数
+func (f *FuncSigner) Sign(data []byte) ([]byte, error) {
+	if f.SignFunc == nil {
+		return nil, I18n.E(I18n.CodeNoSignFunc)
+	}
+	return f.SignFunc(data)
+}
+
+// StubSigner 测试用签名器，生成一次性密钥并记录签名次数
+type StubSigner struct {
+	LocalSigner
+	Calls int   // Sign调用次数
+	Err   error // 非空时Sign直接返回该错误
+}
+
+// NewStubSigner 使用指定套件生成一次性密钥
+func NewStubSigner(keyID, suiteName string) (*StubSigner, error) {
+	suite, err := Suite.Get(suiteName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := suite.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &StubSigner{LocalSigner: LocalSigner{ID: keyID, SuiteName: suite.Name(), Key: key}}, nil
+}
+
+// Sign 记录调用次数后签名
+func (s *StubSigner) Sign(data []byte) ([]byte, error) {
+	s.Calls++
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.LocalSigner.Sign(data)
+}
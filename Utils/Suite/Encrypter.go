@@ -0,0 +1,168 @@
+package Suite
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/tjfoc/gmsm/sm2"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+)
+
+// Encrypter 支持使用签发公钥加密的套件，用于只有签发方能解密的数据（如诊断包）
+type Encrypter interface {
+	// EncryptTo 使用公钥加密
+	EncryptTo(pub crypto.PublicKey, plaintext []byte) ([]byte, error)
+	// DecryptWith 使用私钥解密
+	DecryptWith(priv crypto.Signer, ciphertext []byte) ([]byte, error)
+}
+
+// EncrypterOf 获取套件的公钥加密实现
+func EncrypterOf(suite CryptoSuite) (Encrypter, error) {
+	encrypter, ok := suite.(Encrypter)
+	if !ok {
+		return nil, I18n.E(I18n.CodeNoEncrypter, suite.Name())
+	}
+	return encrypter, nil
+}
+
+// EncryptTo SM2公钥加密
+func (GMSuite) EncryptTo(pub crypto.PublicKey, plaintext []byte) ([]byte, error) {
+	key, ok := pub.(*sm2.PublicKey)
+	if !ok {
+		return nil, I18n.E(I18n.CodePublicKeyType, "SM2")
+	}
+	return sm2.EncryptAsn1(key, plaintext, rand.Reader)
+}
+
+// DecryptWith SM2私钥解密
+func (GMSuite) DecryptWith(priv crypto.Signer, ciphertext []byte) ([]byte, error) {
+	key, ok := priv.(*sm2.PrivateKey)
+	if !ok {
+		return nil, I18n.E(I18n.CodePrivateKeyType, "SM2")
+	}
+	return sm2.DecryptAsn1(key, ciphertext)
+}
+
+// EncryptTo 将Ed25519公钥转换为X25519公钥后进行ECIES加密：
+// 输出为 临时公钥(32字节) || AES-GCM密文，对称密钥由共享密钥经HKDF-SHA256派生，
+// salt为 临时公钥 || 接收方公钥，info为 eciesInfo
+func (s StdSuite) EncryptTo(pub crypto.PublicKey, plaintext []byte) ([]byte, error) {
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return nil, I18n.E(I18n.CodePublicKeyType, "Ed25519")
+	}
+	peer, err := x25519Public(key)
+	if err != nil {
+		return nil, err
+	}
+	var ephemeral = make([]byte, curve25519.ScalarSize)
+	if _, err = io.ReadFull(rand.Reader, ephemeral); err != nil {
+		return nil, err
+	}
+	ephemeralPub, err := curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := curve25519.X25519(ephemeral, peer)
+	if err != nil || isZero(shared) {
+		return nil, I18n.E(I18n.CodeBadEd25519Key)
+	}
+	kek, err := eciesKey(ephemeralPub, peer, shared)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.Encrypt(kek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeralPub, ciphertext...), nil
+}
+
+// DecryptWith 使用Ed25519私钥解密 EncryptTo 的输出
+func (s StdSuite) DecryptWith(priv crypto.Signer, ciphertext []byte) ([]byte, error) {
+	key, ok := priv.(ed25519.PrivateKey)
+	if !ok || len(key) != ed25519.PrivateKeySize {
+		return nil, I18n.E(I18n.CodePrivateKeyType, "Ed25519")
+	}
+	if len(ciphertext) <= curve25519.PointSize {
+		return nil, GM.ErrInvalidCiphertext
+	}
+	var ephemeralPub = ciphertext[:curve25519.PointSize]
+	var digest = sha512.Sum512(key.Seed())
+	var scalar = digest[:32]
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+	self, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	// 低阶点作为临时公钥时共享密钥为全零，与密钥无关，视为无效密文
+	shared, err := curve25519.X25519(scalar, ephemeralPub)
+	if err != nil || isZero(shared) {
+		return nil, GM.ErrInvalidCiphertext
+	}
+	kek, err := eciesKey(ephemeralPub, self, shared)
+	if err != nil {
+		return nil, err
+	}
+	return s.Decrypt(kek, ciphertext[curve25519.PointSize:])
+}
+
+// eciesInfo HKDF的info标签，更换密钥派生方式时需同时更换
+const eciesInfo = "elstlic-ecies-v1"
+
+// eciesKey 由共享密钥派生16字节对称密钥
+func eciesKey(ephemeralPub, peer, shared []byte) ([]byte, error) {
+	var salt = make([]byte, 0, len(ephemeralPub)+len(peer))
+	salt = append(append(salt, ephemeralPub...), peer...)
+	var key = make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(eciesInfo)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// isZero 常量时间判断是否全为零字节
+func isZero(b []byte) bool {
+	return subtle.ConstantTimeCompare(b, make([]byte, len(b))) == 1
+}
+
+// fieldPrime 2^255-19
+var fieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// x25519Public Ed25519公钥转换为X25519公钥：u = (1+y)/(1-y) mod p
+func x25519Public(pub ed25519.PublicKey) ([]byte, error) {
+	var le = make([]byte, len(pub))
+	copy(le, pub)
+	le[31] &= 0x7f
+	var y = new(big.Int).SetBytes(reverse(le))
+	var num = new(big.Int).Add(big.NewInt(1), y)
+	var den = new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, fieldPrime)
+	if den.Sign() == 0 {
+		return nil, I18n.E(I18n.CodeBadEd25519Key)
+	}
+	var u = num.Mul(num, den.ModInverse(den, fieldPrime))
+	u.Mod(u, fieldPrime)
+	var out = make([]byte, 32)
+	u.FillBytes(out)
+	return reverse(out), nil
+}
+
+// reverse 字节序翻转（小端与大端互转）
+func reverse(b []byte) []byte {
+	var out = make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package Suite
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"golang.org/x/crypto/curve25519"
+)
+
+func newEncrypter(t *testing.T, name string) (CryptoSuite, Encrypter) {
+	t.Helper()
+	suite, err := Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypter, err := EncrypterOf(suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return suite, encrypter
+}
+
+func TestEncryptToRoundTrip(t *testing.T) {
+	var plaintext = []byte("diagnostic bundle")
+	for _, name := range []string{NameGM, NameStd} {
+		t.Run(name, func(t *testing.T) {
+			suite, encrypter := newEncrypter(t, name)
+			priv, err := suite.GenerateKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext, err := encrypter.EncryptTo(priv.Public(), plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := encrypter.EncryptTo(priv.Public(), plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(ciphertext, again) {
+				t.Error("encryption is deterministic")
+			}
+			decrypted, err := encrypter.DecryptWith(priv, ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("DecryptWith = %q, want %q", decrypted, plaintext)
+			}
+
+			other, err := suite.GenerateKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = encrypter.DecryptWith(other, ciphertext); err == nil {
+				t.Error("decrypted with the wrong private key")
+			}
+		})
+	}
+}
+
+// TestDecryptWithTampered 临时公钥或密文被改动时解密失败
+func TestDecryptWithTampered(t *testing.T) {
+	for _, name := range []string{NameGM, NameStd} {
+		t.Run(name, func(t *testing.T) {
+			suite, encrypter := newEncrypter(t, name)
+			priv, err := suite.GenerateKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext, err := encrypter.EncryptTo(priv.Public(), []byte("diagnostic bundle"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, index := range []int{curve25519.PointSize - 1, len(ciphertext) - 1} {
+				var tampered = append([]byte(nil), ciphertext...)
+				tampered[index] ^= 0x01
+				if plaintext, err := encrypter.DecryptWith(priv, tampered); err == nil {
+					t.Errorf("byte %d tampered, decrypted %q", index, plaintext)
+				}
+			}
+		})
+	}
+}
+
+// TestDecryptWithLowOrderPoint 低阶点作为临时公钥时共享密钥为全零，按无效密文拒绝
+func TestDecryptWithLowOrderPoint(t *testing.T) {
+	suite, encrypter := newEncrypter(t, NameStd)
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ciphertext = append(make([]byte, curve25519.PointSize), bytes.Repeat([]byte{1}, 32)...)
+	if _, err = encrypter.DecryptWith(priv, ciphertext); !errors.Is(err, GM.ErrInvalidCiphertext) {
+		t.Fatalf("DecryptWith error = %v, want ErrInvalidCiphertext", err)
+	}
+	if _, err = encrypter.DecryptWith(priv, make([]byte, curve25519.PointSize)); !errors.Is(err, GM.ErrInvalidCiphertext) {
+		t.Fatalf("DecryptWith short input error = %v, want ErrInvalidCiphertext", err)
+	}
+}
+
+// TestX25519Public RFC 8032 测试向量1的Ed25519公钥转换为X25519公钥，
+// 结果与按RFC 7748对私钥派生的标量乘基点一致
+func TestX25519Public(t *testing.T) {
+	seed, _ := hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+	var priv = ed25519.NewKeyFromSeed(seed)
+	var pub = priv.Public().(ed25519.PublicKey)
+	if hex.EncodeToString(pub) != "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a" {
+		t.Fatalf("Ed25519 public key = %x", pub)
+	}
+	u, err := x25519Public(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "d85e07ec22b0ad881537c2f44d662d1a143cf830c57aca4305d85c7a90f6b62e"
+	if hex.EncodeToString(u) != want {
+		t.Errorf("x25519Public = %x, want %s", u, want)
+	}
+}
@@ -0,0 +1,99 @@
+package Suite
+
+import (
+	"crypto"
+	"crypto/rand"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/tjfoc/gmsm/x509"
+)
+
+// GMSuite 国密算法套件：SM2签名、SM3摘要、SM4加密
+type GMSuite struct{}
+
+func (GMSuite) Name() string   { return NameGM }
+func (GMSuite) EncAlg() uint8  { return Envelope.EncSM4GCM }
+func (GMSuite) SigAlg() uint8  { return Envelope.SigSM2 }
+func (GMSuite) HashAlg() uint8 { return Envelope.HashSM3 }
+
+// GenerateKey 生成SM2密钥对
+func (GMSuite) GenerateKey() (crypto.Signer, error) {
+	return sm2.GenerateKey(rand.Reader)
+}
+
+// Sign SM2签名
+func (GMSuite) Sign(priv crypto.Signer, data []byte) ([]byte, error) {
+	if _, ok := priv.Public().(*sm2.PublicKey); !ok {
+		return nil, I18n.E(I18n.CodeSigningKeyType, "SM2")
+	}
+	return priv.Sign(rand.Reader, data, nil)
+}
+
+// Verify SM2验签
+func (GMSuite) Verify(pub crypto.PublicKey, data, sig []byte) bool {
+	key, ok := pub.(*sm2.PublicKey)
+	if !ok {
+		return false
+	}
+	return key.Verify(data, sig)
+}
+
+// Encrypt SM4-GCM加密
+func (GMSuite) Encrypt(key, plaintext []byte) ([]byte, error) {
+	return GM.SM4GCMEncrypt(plaintext, key, nil)
+}
+
+// Decrypt SM4-GCM解密
+func (GMSuite) Decrypt(key, ciphertext []byte) ([]byte, error) {
+	return GM.SM4GCMDecrypt(ciphertext, key, nil)
+}
+
+// Hash SM3摘要
+func (GMSuite) Hash(data []byte) []byte {
+	return sm3.Sm3Sum(data)
+}
+
+func (GMSuite) MarshalPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	key, ok := pub.(*sm2.PublicKey)
+	if !ok {
+		return nil, I18n.E(I18n.CodePublicKeyType, "SM2")
+	}
+	return x509.MarshalSm2PublicKey(key)
+}
+
+func (GMSuite) ParsePublicKey(der []byte) (crypto.PublicKey, error) {
+	return x509.ParseSm2PublicKey(der)
+}
+
+func (GMSuite) MarshalPrivateKey(priv crypto.Signer) ([]byte, error) {
+	key, ok := priv.(*sm2.PrivateKey)
+	if !ok {
+		return nil, I18n.E(I18n.CodePrivateKeyType, "SM2")
+	}
+	return x509.MarshalSm2UnecryptedPrivateKey(key)
+}
+
+func (GMSuite) ParsePrivateKey(der []byte) (crypto.Signer, error) {
+	return x509.ParsePKCS8UnecryptedPrivateKey(der)
+}
+
+// GMCBCSuite 旧版国密套件，对称加密为以密钥作IV的SM4-CBC。
+// 只注册为读取用的旧套件，用于解密迁移前签发的文件，不会被用于新签发。
+type GMCBCSuite struct {
+	GMSuite
+}
+
+func (GMCBCSuite) EncAlg() uint8 { return Envelope.EncSM4CBC }
+
+// Encrypt 旧套件只读，拒绝加密
+func (GMCBCSuite) Encrypt(key, plaintext []byte) ([]byte, error) {
+	return nil, I18n.E(I18n.CodeLegacyEncrypt, "SM4-CBC")
+}
+
+// Decrypt SM4-CBC解密
+func (GMCBCSuite) Decrypt(key, ciphertext []byte) ([]byte, error) {
+	return GM.SM4Decrypt(ciphertext, key, key)
+}
@@ -0,0 +1,130 @@
+package Suite
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"io"
+)
+
+// StdSuite 国际算法套件：Ed25519签名、SHA-256摘要、AES-GCM加密，除公钥加密（见Encrypter）外仅依赖标准库
+type StdSuite struct{}
+
+func (StdSuite) Name() string   { return NameStd }
+func (StdSuite) EncAlg() uint8  { return Envelope.EncAESGCM }
+func (StdSuite) SigAlg() uint8  { return Envelope.SigEd25519 }
+func (StdSuite) HashAlg() uint8 { return Envelope.HashSHA256 }
+
+// GenerateKey 生成Ed25519密钥对
+func (StdSuite) GenerateKey() (crypto.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// Sign Ed25519签名
+func (StdSuite) Sign(priv crypto.Signer, data []byte) ([]byte, error) {
+	if _, ok := priv.Public().(ed25519.PublicKey); !ok {
+		return nil, I18n.E(I18n.CodeSigningKeyType, "Ed25519")
+	}
+	return priv.Sign(rand.Reader, data, crypto.Hash(0))
+}
+
+// Verify Ed25519验签
+func (StdSuite) Verify(pub crypto.PublicKey, data, sig []byte) bool {
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(key, data, sig)
+}
+
+// Encrypt AES-GCM加密，输出为 nonce || 密文
+func (StdSuite) Encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	var nonce = make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt AES-GCM解密，错误与SM4-GCM一致：长度不合法返回GM.ErrInvalidCiphertext，密文被篡改时返回GM.ErrAuthFailed
+func (StdSuite) Decrypt(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, GM.ErrInvalidCiphertext
+	}
+	var nonce = ciphertext[:aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, GM.ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// Hash SHA-256摘要
+func (StdSuite) Hash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func (StdSuite) MarshalPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	if _, ok := pub.(ed25519.PublicKey); !ok {
+		return nil, I18n.E(I18n.CodePublicKeyType, "Ed25519")
+	}
+	return x509.MarshalPKIXPublicKey(pub)
+}
+
+func (StdSuite) ParsePublicKey(der []byte) (crypto.PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pub.(ed25519.PublicKey); !ok {
+		return nil, I18n.E(I18n.CodePublicKeyType, "Ed25519")
+	}
+	return pub, nil
+}
+
+func (StdSuite) MarshalPrivateKey(priv crypto.Signer) ([]byte, error) {
+	if _, ok := priv.(ed25519.PrivateKey); !ok {
+		return nil, I18n.E(I18n.CodePrivateKeyType, "Ed25519")
+	}
+	return x509.MarshalPKCS8PrivateKey(priv)
+}
+
+func (StdSuite) ParsePrivateKey(der []byte) (crypto.Signer, error) {
+	priv, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := priv.(ed25519.PrivateKey)
+	if !ok {
+		return nil, I18n.E(I18n.CodePrivateKeyType, "Ed25519")
+	}
+	return key, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,140 @@
+package Suite
+
+import (
+	"crypto"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"sort"
+	"sync"
+)
+
+// CryptoSuite 一组配套使用的签名、对称加密与摘要算法
+type CryptoSuite interface {
+	// Name 套件名称，记录在License中
+	Name() string
+	// EncAlg/SigAlg/HashAlg 写入文件头的算法ID
+	EncAlg() uint8
+	SigAlg() uint8
+	HashAlg() uint8
+
+	// GenerateKey 生成签发密钥对
+	GenerateKey() (crypto.Signer, error)
+	// Sign 使用私钥对数据签名
+	Sign(priv crypto.Signer, data []byte) ([]byte, error)
+	// Verify 使用公钥验证签名
+	Verify(pub crypto.PublicKey, data, sig []byte) bool
+
+	// Encrypt/Decrypt 使用16字节密钥进行对称加解密
+	Encrypt(key, plaintext []byte) ([]byte, error)
+	Decrypt(key, ciphertext []byte) ([]byte, error)
+
+	// Hash 计算摘要
+	Hash(data []byte) []byte
+
+	// MarshalPublicKey/ParsePublicKey 公钥与DER编码互转
+	MarshalPublicKey(pub crypto.PublicKey) ([]byte, error)
+	ParsePublicKey(der []byte) (crypto.PublicKey, error)
+	// MarshalPrivateKey/ParsePrivateKey 私钥与未加密的PKCS#8 DER编码互转
+	MarshalPrivateKey(priv crypto.Signer) ([]byte, error)
+	ParsePrivateKey(der []byte) (crypto.Signer, error)
+}
+
+const (
+	NameGM  = "gm"  // 国密：SM2/SM3/SM4
+	NameStd = "std" // 国际：Ed25519/SHA-256/AES-GCM
+)
+
+// Default 未指定套件时使用的套件
+const Default = NameGM
+
+var (
+	suitesMu sync.RWMutex
+	suites   = make(map[string]CryptoSuite)
+	legacy   = make([]CryptoSuite, 0)
+)
+
+func init() {
+	Register(GMSuite{})
+	Register(StdSuite{})
+	RegisterLegacy(GMCBCSuite{})
+}
+
+// Register 注册算法套件，同名套件会被覆盖
+func Register(suite CryptoSuite) {
+	suitesMu.Lock()
+	defer suitesMu.Unlock()
+	suites[suite.Name()] = suite
+}
+
+// RegisterLegacy 注册仅用于读取旧文件的套件，Get不会返回旧套件
+func RegisterLegacy(suite CryptoSuite) {
+	suitesMu.Lock()
+	defer suitesMu.Unlock()
+	legacy = append(legacy, suite)
+}
+
+// IsLegacy 判断加密算法是否属于只读的旧套件，使用旧套件的文件应迁移
+func IsLegacy(encAlg uint8) bool {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	for _, suite := range legacy {
+		if suite.EncAlg() == encAlg {
+			return true
+		}
+	}
+	return false
+}
+
+// Get 按名称获取算法套件，名称为空时返回默认套件
+func Get(name string) (CryptoSuite, error) {
+	if name == "" {
+		name = Default
+	}
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	suite, ok := suites[name]
+	if !ok {
+		return nil, I18n.E(I18n.CodeUnknownSuite, name)
+	}
+	return suite, nil
+}
+
+// ByAlg 按文件头中的加密与签名算法ID查找套件
+func ByAlg(encAlg, sigAlg uint8) (CryptoSuite, error) {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	for _, suite := range suites {
+		if suite.EncAlg() == encAlg && (sigAlg == 0 || suite.SigAlg() == sigAlg) {
+			return suite, nil
+		}
+	}
+	for _, suite := range legacy {
+		if suite.EncAlg() == encAlg && (sigAlg == 0 || suite.SigAlg() == sigAlg) {
+			return suite, nil
+		}
+	}
+	return nil, I18n.E(I18n.CodeSuiteForAlg, encAlg, sigAlg)
+}
+
+// ForPublicKey 按公钥类型查找算法套件，能够编码该公钥的套件即为匹配
+func ForPublicKey(pub crypto.PublicKey) (CryptoSuite, error) {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	for _, suite := range suites {
+		if _, err := suite.MarshalPublicKey(pub); err == nil {
+			return suite, nil
+		}
+	}
+	return nil, I18n.E(I18n.CodeSuiteForKey, pub)
+}
+
+// Names 已注册的套件名称
+func Names() []string {
+	suitesMu.RLock()
+	defer suitesMu.RUnlock()
+	var names = make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
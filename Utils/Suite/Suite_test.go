@@ -0,0 +1,132 @@
+package Suite
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lizazacn/ElstLic/Utils/Envelope"
+	"github.com/lizazacn/ElstLic/Utils/GM"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+)
+
+func TestGet(t *testing.T) {
+	for name, want := range map[string]string{"": Default, NameGM: NameGM, NameStd: NameStd} {
+		suite, err := Get(name)
+		if err != nil || suite.Name() != want {
+			t.Errorf("Get(%q) = %v, %v, want %s", name, suite, err, want)
+		}
+	}
+	if _, err := Get("rsa"); I18n.Code(err) != I18n.CodeUnknownSuite {
+		t.Errorf("Get(rsa) error = %v, want %s", err, I18n.CodeUnknownSuite)
+	}
+	if names := Names(); len(names) != 2 || names[0] != NameGM || names[1] != NameStd {
+		t.Errorf("Names = %v, want only the writable suites", names)
+	}
+}
+
+func TestByAlg(t *testing.T) {
+	var cases = []struct {
+		enc, sig uint8
+		name     string
+		legacy   bool
+	}{
+		{Envelope.EncSM4GCM, Envelope.SigSM2, NameGM, false},
+		{Envelope.EncSM4GCM, Envelope.SigNone, NameGM, false},
+		{Envelope.EncAESGCM, Envelope.SigEd25519, NameStd, false},
+		{Envelope.EncAESGCM, Envelope.SigNone, NameStd, false},
+		{Envelope.EncSM4CBC, Envelope.SigNone, NameGM, true},
+	}
+	for _, c := range cases {
+		suite, err := ByAlg(c.enc, c.sig)
+		if err != nil {
+			t.Errorf("ByAlg(%d, %d) error = %v", c.enc, c.sig, err)
+			continue
+		}
+		if suite.Name() != c.name || suite.EncAlg() != c.enc || IsLegacy(suite.EncAlg()) != c.legacy {
+			t.Errorf("ByAlg(%d, %d) = %T %s", c.enc, c.sig, suite, suite.Name())
+		}
+	}
+	for _, alg := range [][2]uint8{{Envelope.EncSM4GCM, Envelope.SigEd25519}, {Envelope.EncAESGCM, Envelope.SigSM2}, {0xEE, Envelope.SigNone}} {
+		if _, err := ByAlg(alg[0], alg[1]); I18n.Code(err) != I18n.CodeSuiteForAlg {
+			t.Errorf("ByAlg(%d, %d) error = %v, want %s", alg[0], alg[1], err, I18n.CodeSuiteForAlg)
+		}
+	}
+}
+
+func TestForPublicKey(t *testing.T) {
+	for _, name := range []string{NameGM, NameStd} {
+		suite, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, err := suite.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := ForPublicKey(priv.Public())
+		if err != nil || found.Name() != name {
+			t.Errorf("ForPublicKey(%s key) = %v, %v", name, found, err)
+		}
+	}
+	if _, err := ForPublicKey("not a key"); I18n.Code(err) != I18n.CodeSuiteForKey {
+		t.Errorf("ForPublicKey error = %v, want %s", err, I18n.CodeSuiteForKey)
+	}
+}
+
+// TestLegacySuite 旧套件只能解密迁移前的文件，不能用于加密
+func TestLegacySuite(t *testing.T) {
+	suite, err := ByAlg(Envelope.EncSM4CBC, Envelope.SigNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key = []byte("0123456789abcdef")
+	if data, err := suite.Encrypt(key, []byte("plaintext")); I18n.Code(err) != I18n.CodeLegacyEncrypt || data != nil {
+		t.Fatalf("Encrypt = %q, %v, want %s", data, err, I18n.CodeLegacyEncrypt)
+	}
+	encrypted, err := GM.SM4Encrypt([]byte("plaintext"), key, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := suite.Decrypt(key, encrypted)
+	if err != nil || string(decrypted) != "plaintext" {
+		t.Errorf("Decrypt = %q, %v", decrypted, err)
+	}
+}
+
+// TestStdDecryptErrors AES-GCM解密错误与SM4-GCM一致
+func TestStdDecryptErrors(t *testing.T) {
+	var suite = StdSuite{}
+	var key = []byte("0123456789abcdef")
+	encrypted, err := suite.Encrypt(key, []byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := suite.Decrypt(key, encrypted)
+	if err != nil || !bytes.Equal(decrypted, []byte("plaintext")) {
+		t.Fatalf("Decrypt = %q, %v", decrypted, err)
+	}
+	var tampered = append([]byte(nil), encrypted...)
+	tampered[len(tampered)-1] ^= 0x01
+	var cases = []struct {
+		name string
+		key  []byte
+		data []byte
+		err  error
+	}{
+		{"empty", key, nil, GM.ErrInvalidCiphertext},
+		{"shorter than nonce and tag", key, encrypted[:27], GM.ErrInvalidCiphertext},
+		{"tampered", key, tampered, GM.ErrAuthFailed},
+		{"wrong key", []byte("fedcba9876543210"), encrypted, GM.ErrAuthFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := suite.Decrypt(c.key, c.data); !errors.Is(err, c.err) {
+				t.Errorf("Decrypt error = %v, want %v", err, c.err)
+			}
+		})
+	}
+	if _, err = suite.Decrypt([]byte("short"), encrypted); err == nil {
+		t.Error("Decrypt accepted a 5-byte key")
+	}
+}
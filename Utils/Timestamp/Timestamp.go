@@ -0,0 +1,95 @@
+package Timestamp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layout License时间格式，统一保存为UTC时间
+const Layout = time.RFC3339
+
+// LegacyLayout 旧版本不带时区的时间格式
+const LegacyLayout = "2006-01-02T15:04:05"
+
+// Format 格式化为RFC 3339格式的UTC时间
+func Format(t time.Time) string {
+	return t.UTC().Format(Layout)
+}
+
+// Parse 解析License时间。RFC 3339时间按其自带的时区解析；
+// 不带时区的旧格式按zone解析，zone为空或无法识别时按本地时区解析。
+func Parse(value, zone string) (time.Time, error) {
+	t, err := time.Parse(Layout, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(LegacyLayout, value, Zone(zone))
+}
+
+// IsLegacy 是否为不带时区的旧格式时间
+func IsLegacy(value string) bool {
+	_, err := time.Parse(Layout, value)
+	return err != nil
+}
+
+// Zone 根据时区名称获取时区，支持IANA名称与UTC+08:00形式的固定偏移；
+// 为空、Local或无法识别时返回本地时区
+func Zone(name string) *time.Location {
+	switch name {
+	case "", "Local":
+		return time.Local
+	case "UTC":
+		return time.UTC
+	}
+	if offset, ok := parseOffset(name); ok {
+		return time.FixedZone(name, offset)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// parseOffset 解析UTC+08:00形式的固定偏移
+func parseOffset(name string) (int, bool) {
+	if !strings.HasPrefix(name, "UTC") || len(name) != len("UTC+08:00") {
+		return 0, false
+	}
+	t, err := time.Parse("-07:00", name[3:])
+	if err != nil {
+		return 0, false
+	}
+	_, offset := t.Zone()
+	return offset, true
+}
+
+// LocalZone 本地时区名称。依次读取TZ环境变量、/etc/timezone与/etc/localtime链接，
+// 均无法确定时返回UTC+08:00形式的当前偏移
+func LocalZone() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !filepath.IsAbs(tz) {
+		if _, err := time.LoadLocation(tz); err == nil {
+			return tz
+		}
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		if tz := strings.TrimSpace(string(data)); tz != "" {
+			return tz
+		}
+	}
+	if link, err := os.Readlink("/etc/localtime"); err == nil {
+		if i := strings.Index(link, "zoneinfo/"); i >= 0 {
+			return link[i+len("zoneinfo/"):]
+		}
+	}
+	_, offset := time.Now().Zone()
+	var sign = "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("UTC%s%02d:%02d", sign, offset/3600, offset%3600/60)
+}
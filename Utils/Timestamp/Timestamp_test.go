@@ -0,0 +1,89 @@
+package Timestamp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	var local = func(value string) time.Time {
+		t, _ := time.ParseInLocation(LegacyLayout, value, time.Local)
+		return t
+	}
+	var cases = []struct {
+		name   string
+		value  string
+		zone   string
+		want   time.Time
+		legacy bool
+		err    bool
+	}{
+		{"rfc3339 utc ignores zone", "2024-01-02T03:04:05Z", "Asia/Shanghai", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false, false},
+		{"rfc3339 offset", "2024-01-02T11:04:05+08:00", "", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), false, false},
+		{"legacy iana zone", "2024-01-02T11:04:05", "Asia/Shanghai", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true, false},
+		{"legacy fixed offset", "2024-01-02T11:04:05", "UTC+08:00", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true, false},
+		{"legacy negative offset", "2024-01-02T11:04:05", "UTC-05:00", time.Date(2024, 1, 2, 16, 4, 5, 0, time.UTC), true, false},
+		{"legacy utc", "2024-01-02T11:04:05", "UTC", time.Date(2024, 1, 2, 11, 4, 5, 0, time.UTC), true, false},
+		{"legacy no zone", "2024-01-02T11:04:05", "", local("2024-01-02T11:04:05"), true, false},
+		{"legacy bad zone", "2024-01-02T11:04:05", "Mars/Olympus", local("2024-01-02T11:04:05"), true, false},
+		{"legacy bad offset", "2024-01-02T11:04:05", "UTC+8", local("2024-01-02T11:04:05"), true, false},
+		{"malformed", "2024/01/02 11:04:05", "UTC", time.Time{}, true, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Parse(c.value, c.zone)
+			if c.err {
+				if err == nil {
+					t.Fatalf("Parse(%q, %q) = %v, want error", c.value, c.zone, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("Parse(%q, %q) = %v, want %v", c.value, c.zone, got, c.want)
+			}
+			if IsLegacy(c.value) != c.legacy {
+				t.Errorf("IsLegacy(%q) = %v, want %v", c.value, !c.legacy, c.legacy)
+			}
+		})
+	}
+}
+
+// TestFormatRoundTrip 任意时区的时间都以UTC保存，解析后时刻不变
+func TestFormatRoundTrip(t *testing.T) {
+	var shanghai = Zone("Asia/Shanghai")
+	for _, value := range []time.Time{
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		time.Date(2024, 1, 2, 11, 4, 5, 0, shanghai),
+		time.Date(2024, 6, 30, 23, 59, 59, 0, time.FixedZone("UTC-05:00", -5*3600)),
+	} {
+		var formatted = Format(value)
+		if formatted[len(formatted)-1] != 'Z' || IsLegacy(formatted) {
+			t.Errorf("Format(%v) = %q, want RFC 3339 UTC", value, formatted)
+		}
+		parsed, err := Parse(formatted, "UTC+09:00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !parsed.Equal(value) || parsed.Location() != time.UTC {
+			t.Errorf("Parse(Format(%v)) = %v", value, parsed)
+		}
+	}
+}
+
+func TestZone(t *testing.T) {
+	for name, want := range map[string]string{
+		"":              time.Local.String(),
+		"Local":         time.Local.String(),
+		"UTC":           "UTC",
+		"UTC+08:00":     "UTC+08:00",
+		"Asia/Shanghai": "Asia/Shanghai",
+		"Mars/Olympus":  time.Local.String(),
+	} {
+		if got := Zone(name).String(); got != want {
+			t.Errorf("Zone(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package Trust
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+	"time"
+)
+
+var (
+	ErrBadCert      error = I18n.E(I18n.CodeBadCert)
+	ErrCertExpired  error = I18n.E(I18n.CodeCertExpired)
+	ErrExceedsLimit error = I18n.E(I18n.CodeExceedsLimit)
+)
+
+// CertSignedBytes 证书参与签名的内容（不含签名的JSON）
+func CertSignedBytes(cert *Entity.ResellerCert) ([]byte, error) {
+	var unsigned = *cert
+	unsigned.Signature = nil
+	return json.Marshal(&unsigned)
+}
+
+// CertKey 证书中的经销商公钥
+func CertKey(cert *Entity.ResellerCert) (crypto.PublicKey, Suite.CryptoSuite, error) {
+	suite, err := Suite.Get(cert.Suite)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, err := suite.ParsePublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, suite, nil
+}
+
+// VerifyChain 校验证书链：每个证书由下一个证书签名，最后一个证书由信任列表中的根密钥签名，
+// 下级证书的限额与有效期不得超出上级，License的签发时间需在每个证书的有效期内，
+// License本身需在链首证书的授权范围内（见CheckLicenseLimits）。返回链首证书的公钥。
+func VerifyChain(chain []*Entity.ResellerCert, roots *Store, lic *Entity.License) (crypto.PublicKey, error) {
+	if len(chain) == 0 {
+		return nil, I18n.E(I18n.CodeEmptyChain)
+	}
+	if roots == nil {
+		return nil, I18n.E(I18n.CodeChainNoTrust)
+	}
+	issuedAt, err := lic.IssuedAt()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCertExpired, err)
+	}
+	for i, cert := range chain {
+		signed, err := CertSignedBytes(cert)
+		if err != nil {
+			return nil, err
+		}
+		var issuerPub crypto.PublicKey
+		if i == len(chain)-1 {
+			root, err := roots.Lookup(cert.IssuerKeyID)
+			if err != nil {
+				return nil, err
+			}
+			if root.Suite != cert.IssuerSuite {
+				return nil, ErrBadCert
+			}
+			notBefore, err := Timestamp.Parse(cert.NotBefore, "")
+			if err != nil || !root.Covers(notBefore) {
+				return nil, ErrKeyExpired
+			}
+			issuerPub, err = root.Public()
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			var parent = chain[i+1]
+			if parent.KeyID != cert.IssuerKeyID || parent.Suite != cert.IssuerSuite {
+				return nil, fmt.Errorf("%w: %s", ErrBadCert, I18n.T("", I18n.CodeCertParent, cert.Serial))
+			}
+			issuerPub, _, err = CertKey(parent)
+			if err != nil {
+				return nil, err
+			}
+			err = CheckCertWithin(cert, parent)
+			if err != nil {
+				return nil, err
+			}
+		}
+		issuerSuite, err := Suite.Get(cert.IssuerSuite)
+		if err != nil {
+			return nil, err
+		}
+		if !issuerSuite.Verify(issuerPub, signed, cert.Signature) {
+			return nil, fmt.Errorf("%w: %s", ErrBadCert, cert.Serial)
+		}
+		if !certCovers(cert, issuedAt) {
+			return nil, fmt.Errorf("%w: %s", ErrCertExpired, cert.Serial)
+		}
+	}
+	err = CheckLicenseLimits(lic, chain[0])
+	if err != nil {
+		return nil, err
+	}
+	pub, _, err := CertKey(chain[0])
+	return pub, err
+}
+
+// CheckLicenseLimits 校验License是否在经销商证书授权范围内：签发时间需在License有效期内，
+// 非永久License的到期时间不得晚于证书失效时间
+func CheckLicenseLimits(lic *Entity.License, cert *Entity.ResellerCert) error {
+	start, err := lic.StartAt()
+	if err != nil {
+		return err
+	}
+	end, err := lic.EndAt()
+	if err != nil {
+		return err
+	}
+	issuedAt, err := lic.IssuedAt()
+	if err != nil {
+		return err
+	}
+	if issuedAt.Before(start) || issuedAt.After(end) {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitIssuedAt,
+			Timestamp.Format(issuedAt), Timestamp.Format(start), Timestamp.Format(end)))
+	}
+	if cert.MaxNodes > 0 && lic.AllowNodes > cert.MaxNodes {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitNodes, lic.AllowNodes, cert.MaxNodes))
+	}
+	for _, feature := range lic.Features {
+		if !contains(cert.Features, feature) {
+			return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitFeature, feature))
+		}
+	}
+	if lic.PermanentAuth {
+		if !cert.AllowPermanent {
+			return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitPermanent))
+		}
+		return nil
+	}
+	notAfter, err := Timestamp.Parse(cert.NotAfter, "")
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBadCert, cert.Serial)
+	}
+	if end.After(notAfter) {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitNotAfter,
+			Timestamp.Format(end), Timestamp.Format(notAfter)))
+	}
+	if cert.MaxDays > 0 {
+		term, err := lic.Term()
+		if err != nil {
+			return err
+		}
+		if term > time.Duration(cert.MaxDays)*24*time.Hour {
+			return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeLimitDays, cert.MaxDays))
+		}
+	}
+	return nil
+}
+
+// CheckCertWithin 校验下级证书的限额与有效期不超出上级证书
+func CheckCertWithin(cert, parent *Entity.ResellerCert) error {
+	notBefore, notAfter, err := certValidity(cert)
+	if err != nil {
+		return err
+	}
+	parentBefore, parentAfter, err := certValidity(parent)
+	if err != nil {
+		return err
+	}
+	if notBefore.Before(parentBefore) || notAfter.After(parentAfter) {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeCertValidity, cert.Serial))
+	}
+	if parent.MaxNodes > 0 && (cert.MaxNodes == 0 || cert.MaxNodes > parent.MaxNodes) {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeCertNodes, cert.Serial))
+	}
+	if parent.MaxDays > 0 && (cert.MaxDays == 0 || cert.MaxDays > parent.MaxDays) {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeCertDays, cert.Serial))
+	}
+	if cert.AllowPermanent && !parent.AllowPermanent {
+		return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeCertPermanent, cert.Serial))
+	}
+	for _, feature := range cert.Features {
+		if !contains(parent.Features, feature) {
+			return fmt.Errorf("%w: %s", ErrExceedsLimit, I18n.T("", I18n.CodeCertFeature, cert.Serial, feature))
+		}
+	}
+	return nil
+}
+
+// certValidity 证书的有效期，时间格式错误时返回ErrBadCert
+func certValidity(cert *Entity.ResellerCert) (time.Time, time.Time, error) {
+	notBefore, err := Timestamp.Parse(cert.NotBefore, "")
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s", ErrBadCert, cert.Serial)
+	}
+	notAfter, err := Timestamp.Parse(cert.NotAfter, "")
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s", ErrBadCert, cert.Serial)
+	}
+	return notBefore, notAfter, nil
+}
+
+func certCovers(cert *Entity.ResellerCert, t time.Time) bool {
+	notBefore, err := Timestamp.Parse(cert.NotBefore, "")
+	if err != nil || t.Before(notBefore) {
+		return false
+	}
+	notAfter, err := Timestamp.Parse(cert.NotAfter, "")
+	if err != nil || t.After(notAfter) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
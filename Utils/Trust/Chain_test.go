@@ -0,0 +1,143 @@
+package Trust
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Entity"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Timestamp"
+)
+
+// TestCheckLicenseLimitsRenewal 续期的License保留原开始时间，最长天数自续期时起算
+func TestCheckLicenseLimitsRenewal(t *testing.T) {
+	var now = time.Now()
+	var cert = &Entity.ResellerCert{Serial: "R1", MaxDays: 365, NotAfter: Timestamp.Format(now.AddDate(2, 0, 0))}
+	var lic = &Entity.License{
+		StartTime:         Timestamp.Format(now.AddDate(-3, 0, 0)),
+		EndTime:           Timestamp.Format(now.AddDate(0, 0, 300)),
+		LicenseCreateTime: Timestamp.Format(now),
+	}
+	if err := CheckLicenseLimits(lic, cert); err != nil {
+		t.Fatalf("renewed license rejected: %v", err)
+	}
+	lic.EndTime = Timestamp.Format(now.AddDate(0, 0, 366))
+	if err := CheckLicenseLimits(lic, cert); !errors.Is(err, ErrExceedsLimit) {
+		t.Fatalf("error = %v, want ErrExceedsLimit", err)
+	}
+}
+
+// newChain 由一次性根密钥签发的单级证书链，证书有效期自一小时前起一年
+func newChain(t *testing.T, cert *Entity.ResellerCert) ([]*Entity.ResellerCert, *Store) {
+	t.Helper()
+	suite, err := Suite.Get(Suite.NameGM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var now = time.Now()
+	rootPriv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewKey(suite, rootPriv.Public(), now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resellerPriv, err := suite.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reseller, err := NewKey(suite, resellerPriv.Public(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.KeyID, cert.Suite, cert.PublicKey = reseller.KeyID, reseller.Suite, reseller.PublicKey
+	cert.IssuerKeyID, cert.IssuerSuite = root.KeyID, root.Suite
+	cert.NotBefore = Timestamp.Format(now.Add(-time.Hour))
+	cert.NotAfter = Timestamp.Format(now.AddDate(1, 0, 0))
+	signed, err := CertSignedBytes(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Signature, err = suite.Sign(rootPriv, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*Entity.ResellerCert{cert}, &Store{Keys: []*Key{root}}
+}
+
+func TestVerifyChainLicenseWindow(t *testing.T) {
+	chain, roots := newChain(t, &Entity.ResellerCert{Serial: "R1", AllowPermanent: true})
+	var now = time.Now()
+	var cases = []struct {
+		name      string
+		start     time.Time
+		end       time.Time
+		issued    time.Time
+		permanent bool
+		err       error
+	}{
+		{"within certificate", now, now.AddDate(0, 6, 0), now, false, nil},
+		{"ends after certificate", now, now.AddDate(2, 0, 0), now, false, ErrExceedsLimit},
+		{"permanent", now, now.AddDate(100, 0, 0), now, true, nil},
+		{"issued before start", now.Add(time.Minute), now.AddDate(0, 6, 0), now, false, ErrExceedsLimit},
+		{"issued after end", now.Add(-2 * time.Hour), now.Add(-time.Hour), now, false, ErrExceedsLimit},
+		{"issued outside certificate", now.Add(-3 * time.Hour), now.AddDate(0, 6, 0), now.Add(-2 * time.Hour), false, ErrCertExpired},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var lic = &Entity.License{
+				StartTime:         Timestamp.Format(c.start),
+				EndTime:           Timestamp.Format(c.end),
+				LicenseCreateTime: Timestamp.Format(c.issued),
+				PermanentAuth:     c.permanent,
+			}
+			_, err := VerifyChain(chain, roots, lic)
+			if c.err == nil && err != nil {
+				t.Fatalf("VerifyChain error = %v", err)
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("VerifyChain error = %v, want %v", err, c.err)
+			}
+		})
+	}
+
+	// 证书不允许永久授权时永久License不因到期时间豁免
+	chain, roots = newChain(t, &Entity.ResellerCert{Serial: "R2"})
+	var lic = &Entity.License{
+		StartTime:         Timestamp.Format(now),
+		EndTime:           Timestamp.Format(now.AddDate(100, 0, 0)),
+		LicenseCreateTime: Timestamp.Format(now),
+		PermanentAuth:     true,
+	}
+	if _, err := VerifyChain(chain, roots, lic); !errors.Is(err, ErrExceedsLimit) {
+		t.Fatalf("VerifyChain error = %v, want ErrExceedsLimit", err)
+	}
+}
+
+// TestCheckCertWithinValidity 下级证书的有效期需在上级证书的有效期内
+func TestCheckCertWithinValidity(t *testing.T) {
+	var now = time.Now()
+	var parent = &Entity.ResellerCert{Serial: "P", NotBefore: Timestamp.Format(now.Add(-time.Hour)), NotAfter: Timestamp.Format(now.AddDate(1, 0, 0))}
+	var cases = []struct {
+		name      string
+		notBefore string
+		notAfter  string
+		err       error
+	}{
+		{"within parent", Timestamp.Format(now), Timestamp.Format(now.AddDate(0, 6, 0)), nil},
+		{"same as parent", parent.NotBefore, parent.NotAfter, nil},
+		{"starts before parent", Timestamp.Format(now.Add(-2 * time.Hour)), Timestamp.Format(now.AddDate(0, 6, 0)), ErrExceedsLimit},
+		{"ends after parent", Timestamp.Format(now), Timestamp.Format(now.AddDate(2, 0, 0)), ErrExceedsLimit},
+		{"no end", Timestamp.Format(now), "", ErrBadCert},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var cert = &Entity.ResellerCert{Serial: "C", NotBefore: c.notBefore, NotAfter: c.notAfter}
+			if err := CheckCertWithin(cert, parent); !errors.Is(err, c.err) {
+				t.Errorf("CheckCertWithin = %v, want %v", err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package Trust
+
+import (
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"os"
+	"time"
+)
+
+var (
+	ErrUnknownKey error = I18n.E(I18n.CodeUnknownKey)
+	ErrKeyExpired error = I18n.E(I18n.CodeKeyExpired)
+)
+
+// Key 受信任的签发公钥
+type Key struct {
+	KeyID     string     `json:"key_id"`
+	Suite     string     `json:"suite"`
+	PublicKey []byte     `json:"public_key"`          // DER编码公钥
+	NotBefore time.Time  `json:"not_before"`          // 开始签发时间
+	NotAfter  *time.Time `json:"not_after,omitempty"` // 停止签发时间（轮换退役），为空表示仍在使用
+}
+
+// Store 客户端信任的签发公钥列表。
+// 密钥轮换后旧密钥保留在列表中并设置NotAfter，旧密钥签发的License在到期前仍然有效。
+type Store struct {
+	Keys []*Key `json:"keys"`
+}
+
+// KeyID 根据公钥计算密钥ID
+func KeyID(suite Suite.CryptoSuite, pub crypto.PublicKey) (string, error) {
+	der, err := suite.MarshalPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return suite.Name() + "-" + hex.EncodeToString(suite.Hash(der)[:8]), nil
+}
+
+// NewKey 根据公钥创建信任条目
+func NewKey(suite Suite.CryptoSuite, pub crypto.PublicKey, notBefore time.Time) (*Key, error) {
+	der, err := suite.MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := KeyID(suite, pub)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{KeyID: keyID, Suite: suite.Name(), PublicKey: der, NotBefore: notBefore}, nil
+}
+
+// Public 解析公钥
+func (k *Key) Public() (crypto.PublicKey, error) {
+	suite, err := Suite.Get(k.Suite)
+	if err != nil {
+		return nil, err
+	}
+	return suite.ParsePublicKey(k.PublicKey)
+}
+
+// Covers 判断签发时间是否在密钥有效期内
+func (k *Key) Covers(issuedAt time.Time) bool {
+	if issuedAt.Before(k.NotBefore) {
+		return false
+	}
+	if k.NotAfter != nil && issuedAt.After(*k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// publicBlockType 公钥PEM类型，PEM头中保存密钥ID、套件与有效期
+const publicBlockType = "ELST ISSUER PUBLIC KEY"
+
+// MarshalPEM 导出为PEM，可直接用 //go:embed 编译进客户端
+func (k *Key) MarshalPEM() []byte {
+	var headers = map[string]string{
+		"Key-ID":     k.KeyID,
+		"Suite":      k.Suite,
+		"Not-Before": k.NotBefore.Format(time.RFC3339),
+	}
+	if k.NotAfter != nil {
+		headers["Not-After"] = k.NotAfter.Format(time.RFC3339)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: publicBlockType, Headers: headers, Bytes: k.PublicKey})
+}
+
+// LoadPEM 读取一个或多个公钥PEM组成信任列表
+func LoadPEM(data []byte) (*Store, error) {
+	var store = new(Store)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != publicBlockType {
+			continue
+		}
+		var key = &Key{KeyID: block.Headers["Key-ID"], Suite: block.Headers["Suite"], PublicKey: block.Bytes}
+		notBefore, err := time.Parse(time.RFC3339, block.Headers["Not-Before"])
+		if err != nil {
+			return nil, err
+		}
+		key.NotBefore = notBefore
+		if v, ok := block.Headers["Not-After"]; ok {
+			notAfter, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, err
+			}
+			key.NotAfter = &notAfter
+		}
+		if _, err = key.Public(); err != nil {
+			return nil, err
+		}
+		store.Add(key)
+	}
+	if len(store.Keys) == 0 {
+		return nil, I18n.E(I18n.CodeNoTrustedKey)
+	}
+	return store, nil
+}
+
+// Load 从JSON读取信任列表，可配合 //go:embed 将信任列表编译进客户端
+func Load(data []byte) (*Store, error) {
+	var store = new(Store)
+	err := json.Unmarshal(data, store)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// LoadFile 从文件读取信任列表
+func LoadFile(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// Marshal 序列化为JSON
+func (s *Store) Marshal() ([]byte, error) {
+	return json.MarshalIndent(s, "", "    ")
+}
+
+// Add 添加或替换同ID的公钥
+func (s *Store) Add(key *Key) {
+	for i, k := range s.Keys {
+		if k.KeyID == key.KeyID {
+			s.Keys[i] = key
+			return
+		}
+	}
+	s.Keys = append(s.Keys, key)
+}
+
+// Latest 当前签发公钥：最后一个未退役的公钥
+func (s *Store) Latest() (*Key, error) {
+	for i := len(s.Keys) - 1; i >= 0; i-- {
+		if s.Keys[i].NotAfter == nil {
+			return s.Keys[i], nil
+		}
+	}
+	return nil, I18n.E(I18n.CodeNoCurrentKey)
+}
+
+// Lookup 按密钥ID查找公钥
+func (s *Store) Lookup(keyID string) (*Key, error) {
+	for _, k := range s.Keys {
+		if k.KeyID == keyID {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+}
@@ -0,0 +1,398 @@
+// elstctl 签发端管理工具：维护客户库、查询签发记录库并检查License与node.info文件。
+//
+//	elstctl [-inventory 路径] [-customers 路径] <命令> [参数]
+//
+// 命令：
+//
+//	customer add -id ID -name 名称 [-contract 合同编号] [-contact "姓名,邮箱,电话,职责"]... [-notes 备注]
+//	customer list [-q 文本] [-json]
+//	customer show [-json] ID
+//	customer remove ID
+//	licenses [-customer ID] [-active] [-expiring 天数] [-json]
+//	report [-within 天数] [-format csv|json|html] [-o 文件]
+//	remind [-within 天数] -smtp host:port -from 发件人 [-user 用户名] [-cc 抄送,...] [-templates 模板目录] [-dry-run]
+//	inspect [-format table|json|yaml] [-trust 信任列表] [-offset N] [-step N] [-zone 时区,...] 文件...
+//
+// SMTP口令从环境变量ELST_SMTP_PASSWORD读取。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lizazacn/ElstLic/Server"
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Inspect"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+const smtpPasswordEnv = "ELST_SMTP_PASSWORD"
+
+func main() {
+	inventoryPath := flag.String("inventory", "./inventory.json", "签发记录库路径")
+	customersPath := flag.String("customers", "./customers.json", "客户库路径")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	var args = flag.Args()
+	// 检查文件不需要签发记录库与客户库
+	if args[0] == "inspect" {
+		err := inspect(args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	server, err := open(*inventoryPath, *customersPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch args[0] {
+	case "customer":
+		err = customer(server, args[1:])
+	case "licenses":
+		err = licenses(server, args[1:])
+	case "report":
+		err = report(server, args[1:])
+	case "remind":
+		err = remind(server, args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(flag.CommandLine.Output(), "用法: elstctl [-inventory 路径] [-customers 路径] customer|licenses|report|remind|inspect [参数]")
+	flag.PrintDefaults()
+}
+
+// open 打开签发记录库与客户库
+func open(inventoryPath, customersPath string) (*Server.Server, error) {
+	inventory, err := Server.OpenInventory(inventoryPath)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := Server.OpenCustomerRegistry(customersPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Server.Server{Inventory: inventory, Customers: customers}, nil
+}
+
+func customer(server *Server.Server, args []string) error {
+	if len(args) == 0 {
+		return I18n.E(I18n.CodeMissingCommand, "add, list, show, remove")
+	}
+	var set = flag.NewFlagSet("customer "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "add":
+		var c = new(Server.Customer)
+		set.StringVar(&c.ID, "id", "", "客户ID")
+		set.StringVar(&c.Name, "name", "", "客户名称")
+		set.StringVar(&c.ContractNo, "contract", "", "合同编号")
+		set.StringVar(&c.Notes, "notes", "", "备注")
+		set.Var((*contacts)(&c.Contacts), "contact", "联系人，格式为\"姓名,邮箱,电话,职责\"，可重复")
+		_ = set.Parse(args[1:])
+		err := server.Customers.Put(c)
+		if err != nil {
+			return err
+		}
+		fmt.Println(I18n.T(server.Locale, I18n.MsgCustomerSaved, c.ID))
+		return nil
+	case "list":
+		text := set.String("q", "", "匹配ID、名称、合同编号或联系人")
+		asJSON := set.Bool("json", false, "以JSON输出")
+		_ = set.Parse(args[1:])
+		var list = server.Customers.List(*text)
+		if *asJSON {
+			return printJSON(list)
+		}
+		var w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, columns(server.Locale, I18n.MsgCustomerID, I18n.MsgCustomerName, I18n.MsgCustomerContract, I18n.MsgCustomerContacts))
+		for _, c := range list {
+			var names = make([]string, 0, len(c.Contacts))
+			for _, contact := range c.Contacts {
+				names = append(names, contact.Name)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.ID, c.Name, c.ContractNo, strings.Join(names, ", "))
+		}
+		return w.Flush()
+	case "show":
+		asJSON := set.Bool("json", false, "以JSON输出")
+		_ = set.Parse(args[1:])
+		if set.NArg() != 1 {
+			return I18n.E(I18n.CodeUsage, "customer show [-json] ID")
+		}
+		summary, err := server.CustomerSummary(set.Arg(0))
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(summary)
+		}
+		printSummary(summary, server.Locale)
+		return nil
+	case "remove":
+		_ = set.Parse(args[1:])
+		if set.NArg() != 1 {
+			return I18n.E(I18n.CodeUsage, "customer remove ID")
+		}
+		return server.Customers.Remove(set.Arg(0))
+	default:
+		return I18n.E(I18n.CodeUnknownCommand, args[0])
+	}
+}
+
+func licenses(server *Server.Server, args []string) error {
+	var set = flag.NewFlagSet("licenses", flag.ExitOnError)
+	customerID := set.String("customer", "", "客户ID")
+	active := set.Bool("active", false, "只显示未吊销、未到期且未被续期替代的License")
+	expiring := set.Int("expiring", 0, "只显示在此天数内到期的License")
+	asJSON := set.Bool("json", false, "以JSON输出")
+	_ = set.Parse(args)
+
+	var query = &Server.InventoryQuery{Active: *active}
+	if *expiring > 0 {
+		query.ExpiresBefore = time.Now().AddDate(0, 0, *expiring)
+	}
+	var records []*Server.LicenseRecord
+	var err error
+	if *customerID != "" {
+		records, err = server.CustomerLicenses(*customerID, query)
+		if err != nil {
+			return err
+		}
+	} else {
+		records = server.Inventory.Search(query)
+	}
+	if *asJSON {
+		return printJSON(records)
+	}
+	printRecords(records, server.Locale)
+	return nil
+}
+
+func report(server *Server.Server, args []string) error {
+	var set = flag.NewFlagSet("report", flag.ExitOnError)
+	within := set.Int("within", 30, "报告在此天数内到期的License")
+	format := set.String("format", Server.ReportCSV, "报告格式：csv、json、html")
+	output := set.String("o", "", "输出文件，为空时输出到标准输出")
+	_ = set.Parse(args)
+
+	expiry, err := server.ExpiryReport(*within)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return expiry.Write(os.Stdout, *format, server.Locale)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	err = expiry.Write(file, *format, server.Locale)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func remind(server *Server.Server, args []string) error {
+	var set = flag.NewFlagSet("remind", flag.ExitOnError)
+	within := set.Int("within", 30, "提醒在此天数内到期的License")
+	addr := set.String("smtp", "localhost:25", "SMTP中继地址")
+	from := set.String("from", "", "发件人")
+	user := set.String("user", "", "SMTP用户名，为空时不认证")
+	cc := set.String("cc", "", "抄送地址，以逗号分隔")
+	dir := set.String("templates", "", "自定义模板目录，<客户ID>.tmpl优先，其次default.tmpl")
+	dryRun := set.Bool("dry-run", false, "只输出邮件内容，不发送")
+	_ = set.Parse(args)
+
+	expiry, err := server.ExpiryReport(*within)
+	if err != nil {
+		return err
+	}
+	var reminder = &Server.Reminder{
+		Mailer:      &Server.SMTPMailer{Addr: *addr, Username: *user, Password: os.Getenv(smtpPasswordEnv)},
+		From:        *from,
+		TemplateDir: *dir,
+	}
+	if *cc != "" {
+		reminder.Cc = strings.Split(*cc, ",")
+	}
+	if *dryRun {
+		reminder.Mailer = printMailer{}
+	}
+	results, err := server.SendReminders(expiry, reminder)
+	if err != nil {
+		return err
+	}
+	var w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, columns(server.Locale, I18n.MsgReportCustomer, I18n.MsgReminderLicenses, I18n.MsgReminderRecipients, I18n.MsgReminderResult))
+	for _, result := range results {
+		var outcome = I18n.T(server.Locale, I18n.MsgReminderDelivered)
+		if !result.Sent {
+			outcome = strings.TrimSpace(result.Reason + " " + result.Error)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.CustomerID, strings.Join(result.Serials, ","), strings.Join(result.To, ","), outcome)
+	}
+	return w.Flush()
+}
+
+// printMailer 输出邮件内容而不发送
+type printMailer struct{}
+
+func (printMailer) Send(from string, to []string, msg []byte) error {
+	fmt.Printf("MAIL FROM: %s\nRCPT TO: %s\n%s\n", from, strings.Join(to, ", "), msg)
+	return nil
+}
+
+func inspect(args []string) error {
+	var set = flag.NewFlagSet("inspect", flag.ExitOnError)
+	format := set.String("format", Inspect.FormatTable, "输出格式：table、json、yaml")
+	trustPath := set.String("trust", "", "信任列表文件（JSON或PEM），为空时不校验签名")
+	zones := set.String("zone", "", "额外显示的时区，以逗号分隔，如Asia/Shanghai,UTC+09:00")
+	var opts = new(Inspect.Options)
+	set.IntVar(&opts.Offset, "offset", 1, "密钥偏移量，与签发端一致")
+	set.IntVar(&opts.Step, "step", 1, "密钥步长，与签发端一致")
+	_ = set.Parse(args)
+	if set.NArg() == 0 {
+		return I18n.E(I18n.CodeUsage, "inspect [-format table|json|yaml] [-trust FILE] [-offset N] [-step N] [-zone ZONE,...] FILE...")
+	}
+	if *zones != "" {
+		opts.Zones = strings.Split(*zones, ",")
+	}
+	if *trustPath != "" {
+		store, err := loadTrustStore(*trustPath)
+		if err != nil {
+			return err
+		}
+		opts.TrustStore = store
+	}
+	for _, path := range set.Args() {
+		report, err := Inspect.File(path, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		err = report.Write(os.Stdout, *format, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTrustStore 读取JSON格式的信任列表或PEM格式的签发公钥
+func loadTrustStore(path string) (*Trust.Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+		return Trust.LoadPEM(data)
+	}
+	return Trust.Load(data)
+}
+
+func printSummary(summary *Server.CustomerSummary, locale I18n.Locale) {
+	var c = summary.Customer
+	var w = tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', 0)
+	var field = func(label I18n.ID, value string) {
+		fmt.Fprintf(w, "%s:\t%s\n", I18n.T(locale, label), value)
+	}
+	field(I18n.MsgCustomerID, c.ID)
+	field(I18n.MsgCustomerName, c.Name)
+	field(I18n.MsgCustomerContract, c.ContractNo)
+	for _, contact := range c.Contacts {
+		field(I18n.MsgCustomerContacts, strings.Join([]string{contact.Name, contact.Email, contact.Phone, contact.Role}, " "))
+	}
+	if c.Notes != "" {
+		field(I18n.MsgCustomerNotes, c.Notes)
+	}
+	if summary.NextExpiry != nil {
+		field(I18n.MsgCustomerNextExpiry, summary.NextExpiry.Local().Format(time.RFC3339))
+	}
+	_ = w.Flush()
+	fmt.Println()
+	printRecords(summary.ActiveLicenses, locale)
+}
+
+// printRecords 以表格输出签发记录及授权节点总数
+func printRecords(records []*Server.LicenseRecord, locale I18n.Locale) {
+	var now = time.Now()
+	var w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, columns(locale, I18n.MsgReportSerial, I18n.MsgReportCustomer, I18n.MsgReportProduct, I18n.MsgReportNodes, I18n.MsgReportEndTime, I18n.MsgLicenseStatus))
+	for _, record := range records {
+		var end = record.EndTime
+		if record.PermanentAuth {
+			end = I18n.T(locale, I18n.MsgStatusPermanent)
+		}
+		var status = I18n.T(locale, I18n.MsgStatusActive)
+		switch {
+		case record.Revoked:
+			status = I18n.T(locale, I18n.MsgStatusRevoked)
+		case record.Expired(now):
+			status = I18n.T(locale, I18n.MsgStatusExpired)
+		}
+		var customer = record.CustomerID
+		if customer == "" {
+			customer = record.CustomerTag
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", record.Serial, customer, record.Product, record.AllowNodes, end, status)
+	}
+	_ = w.Flush()
+	fmt.Println(I18n.T(locale, I18n.MsgLicenseTotal, len(records), Server.TotalNodes(records)))
+}
+
+// columns 按语言输出以制表符分隔的表头
+func columns(locale I18n.Locale, ids ...I18n.ID) string {
+	var labels = make([]string, 0, len(ids))
+	for _, id := range ids {
+		labels = append(labels, I18n.T(locale, id))
+	}
+	return strings.Join(labels, "\t")
+}
+
+func printJSON(v interface{}) error {
+	var encoder = json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(v)
+}
+
+// contacts 可重复的联系人参数
+type contacts []Server.Contact
+
+func (c *contacts) String() string {
+	return fmt.Sprint(*c)
+}
+
+func (c *contacts) Set(value string) error {
+	var fields = strings.Split(value, ",")
+	for len(fields) < 4 {
+		fields = append(fields, "")
+	}
+	if strings.TrimSpace(fields[0]) == "" {
+		return I18n.E(I18n.CodeContactName)
+	}
+	*c = append(*c, Server.Contact{
+		Name:  strings.TrimSpace(fields[0]),
+		Email: strings.TrimSpace(fields[1]),
+		Phone: strings.TrimSpace(fields[2]),
+		Role:  strings.TrimSpace(fields[3]),
+	})
+	return nil
+}
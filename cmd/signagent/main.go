@@ -0,0 +1,102 @@
+// signagent 参考签名代理：持有签发私钥，通过Unix Socket为Server提供签名服务，
+// 签发端只需 Signer.DialAgent(socket) 即可签发License而不接触私钥。
+//
+// 私钥以口令加密保存（见Keystore），口令从环境变量ELST_SIGNAGENT_PASSPHRASE读取，未设置时交互式输入。
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lizazacn/ElstLic/Utils/I18n"
+	"github.com/lizazacn/ElstLic/Utils/Keystore"
+	"github.com/lizazacn/ElstLic/Utils/Logger"
+	"github.com/lizazacn/ElstLic/Utils/Signer"
+	"github.com/lizazacn/ElstLic/Utils/Suite"
+	"github.com/lizazacn/ElstLic/Utils/Trust"
+)
+
+const passphraseEnv = "ELST_SIGNAGENT_PASSPHRASE"
+
+func main() {
+	socketPath := flag.String("socket", "/tmp/elst-signagent.sock", "Unix Socket路径")
+	keyPath := flag.String("key", "./issuer.key", "加密私钥文件路径")
+	suiteName := flag.String("suite", Suite.Default, "生成私钥时使用的算法套件")
+	generate := flag.Bool("gen", false, "私钥文件不存在时生成新私钥")
+	flag.Parse()
+
+	// 提示与日志按 LC_ALL、LC_MESSAGES、LANG 选择语言
+	var locale = I18n.Current()
+	key, err := loadKey(*keyPath, *suiteName, *generate, locale)
+	if err != nil {
+		log.Fatal(err)
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 仅允许当前用户访问
+	listener, err := Signer.ListenAgent(*socketPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		_ = listener.Close()
+	}()
+	var logger = Logger.New(os.Stderr, Logger.LevelInfo, Logger.FormatText)
+	logger.Info(I18n.T(locale, I18n.MsgAgentStarted), Logger.F("socket", *socketPath), Logger.F(Logger.FieldKeyID, key.KeyID), Logger.F("suite", key.Suite))
+	err = Signer.ServeAgent(listener, signer)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Fatal(err)
+	}
+}
+
+// loadKey 读取加密私钥，文件不存在且允许生成时生成新私钥
+func loadKey(path, suiteName string, generate bool, locale I18n.Locale) (*Keystore.Key, error) {
+	_, statErr := os.Stat(path)
+	var confirm = errors.Is(statErr, os.ErrNotExist)
+	passphrase, err := readPassphrase(confirm, locale)
+	if err != nil {
+		return nil, err
+	}
+	if !confirm {
+		return Keystore.Load(path, passphrase)
+	}
+	if !generate {
+		return nil, statErr
+	}
+	suite, err := Suite.Get(suiteName)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := suite.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := Trust.KeyID(suite, priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	var key = &Keystore.Key{KeyID: keyID, Suite: suite.Name(), Private: priv}
+	return key, Keystore.Save(path, key, passphrase)
+}
+
+func readPassphrase(confirm bool, locale I18n.Locale) ([]byte, error) {
+	if v := os.Getenv(passphraseEnv); v != "" {
+		return []byte(v), nil
+	}
+	var label = I18n.PromptPassphrase
+	if confirm {
+		label = I18n.PromptSetPassphrase
+	}
+	return Keystore.ReadPassphrase(I18n.T(locale, label), confirm)
+}
@@ -46,7 +46,7 @@ func main() {
 		}
 		fmt.Println("############生成授权数据完成############")
 		fmt.Println("###############授权信息###############")
-		license, err := client.DecryptDataFromFile()
+		license, err := client.LoadFrom(&Client.PromptSource{})
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -2,10 +2,13 @@ module github.com/lizazacn/ElstLic
 
 go 1.19
 
+require (
+	github.com/manifoldco/promptui v0.9.0
+	github.com/tjfoc/gmsm v1.4.1
+	golang.org/x/crypto v0.0.0-20201012173705-84dcc777aaee
+)
+
 require (
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
-	github.com/tjfoc/gmsm v1.4.1 // indirect
-	golang.org/x/crypto v0.0.0-20201012173705-84dcc777aaee // indirect
 	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f // indirect
 )